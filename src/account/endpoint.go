@@ -15,7 +15,48 @@ func (r listAccountsResponse) error() error { return r.Error }
 
 func makeListAccountsEndpoint(svc AccountService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		accounts, err := svc.ListAccounts()
+		accounts, err := svc.ListAccounts(ctx)
 		return listAccountsResponse{accounts, err}, nil
 	}
 }
+
+type setCreditLimitRequest struct {
+	AccountNumber uint64 `json:"-"`
+	CreditLimit   int64  `json:"creditLimit"`
+	Reason        string `json:"reason"`
+}
+
+type setCreditLimitResponse struct {
+	Account *Account `json:"account,omitempty"`
+	Error   error    `json:"error,omitempty"`
+}
+
+func (r setCreditLimitResponse) error() error { return r.Error }
+
+func makeSetCreditLimitEndpoint(svc AccountService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setCreditLimitRequest)
+		account, err := svc.SetCreditLimit(ctx, AccountNumber(req.AccountNumber), req.CreditLimit, req.Reason)
+		return setCreditLimitResponse{account, err}, nil
+	}
+}
+
+type setHotRequest struct {
+	AccountNumber uint64 `json:"-"`
+	Hot           bool   `json:"hot"`
+}
+
+type setHotResponse struct {
+	Account *Account `json:"account,omitempty"`
+	Error   error    `json:"error,omitempty"`
+}
+
+func (r setHotResponse) error() error { return r.Error }
+
+func makeSetHotEndpoint(svc AccountService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setHotRequest)
+		account, err := svc.SetHot(ctx, AccountNumber(req.AccountNumber), req.Hot)
+		return setHotResponse{account, err}, nil
+	}
+}
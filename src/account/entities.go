@@ -1,11 +1,66 @@
 package account
 
+import "math"
+
 type AccountNumber uint64
 
+// Regular customer account
+const AccountTypeCustomer = "customer"
+
+// System account that represents money outside of the system.
+// Deposits are booked from it and withdrawals are booked to it
+const AccountTypeSettlement = "settlement"
+
 type Account struct {
 	// Account number
 	Number AccountNumber `json:"number"`
 
-	// Current account balance
+	// Current account balance. Can be negative if account has credit line
 	Balance int64 `json:"balance"`
+
+	// Max amount account balance can go below zero
+	CreditLimit int64 `json:"creditLimit"`
+
+	// Amount of credit that is still available for account
+	AvailableCredit int64 `json:"availableCredit"`
+
+	// Account type, customer or settlement. Not returned to clients
+	Type string `json:"-"`
+
+	// Hot account receives credits as pending credits that are rolled up into balance in background,
+	// so incoming transfers don't wait for account lock. Balance includes pending credits
+	Hot bool `json:"hot,omitempty"`
+}
+
+// Creates new account object
+//	number      - account number
+//	balance     - current account balance
+//	creditLimit - account credit limit
+// Returns created account with calculated available credit
+func NewAccount(number AccountNumber, balance, creditLimit int64) Account {
+	var availableCredit = creditLimit
+	if balance < 0 {
+		availableCredit = creditLimit + balance
+	}
+
+	if availableCredit < 0 {
+		availableCredit = 0
+	}
+
+	return Account{
+		Number:          number,
+		Balance:         balance,
+		CreditLimit:     creditLimit,
+		AvailableCredit: availableCredit,
+	}
+}
+
+// Returns amount of money that can be spent from account, including credit line.
+// Sum is limited by max int64, so large credit limit of account with positive balance doesn't overflow it
+func (acc Account) AvailableFunds() int64 {
+	if acc.Balance > 0 && acc.CreditLimit > math.MaxInt64-acc.Balance {
+		return math.MaxInt64
+	}
+
+	return acc.Balance + acc.CreditLimit
 }
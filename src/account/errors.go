@@ -2,13 +2,21 @@ package account
 
 import (
 	"fmt"
+	"net/http"
 	servErr "test/coins/errors"
 )
 
 const (
 	errCodeInvalidAccount int = 20 + iota
+	errCodeInvalidCreditLimit
 )
 
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(errCodeInvalidAccount, servErr.ErrorDefinition{Code: "account_not_found", Status: http.StatusNotFound, Title: "Account not found"})
+	servErr.RegisterKind(errCodeInvalidCreditLimit, servErr.ErrorDefinition{Code: "invalid_credit_limit", Status: http.StatusUnprocessableEntity, Title: "Invalid credit limit"})
+}
+
 // Creates new "Invalid account number" error
 // 	accountNum - account number
 // Returns created error
@@ -16,3 +24,10 @@ func ErrInvalidAccount(accountNum AccountNumber) error {
 	var msg = fmt.Sprintf("account with number [%d] not found", uint64(accountNum))
 	return servErr.NewServiceError(msg, nil, errCodeInvalidAccount)
 }
+
+// Creates new "Invalid credit limit" error
+//	reason - reason why credit limit is invalid
+// Returns created error
+func ErrInvalidCreditLimit(reason string) error {
+	return servErr.NewServiceError("invalid credit limit: "+reason, nil, errCodeInvalidCreditLimit)
+}
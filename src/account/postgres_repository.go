@@ -0,0 +1,264 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"test/coins/db"
+
+	servErr "test/coins/errors"
+)
+
+var errQueryReturnedNoData = errors.New("no data returned from database request")
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+// Columns of account read by repository. Balance includes pending credits, so money credited to hot account
+// is visible right after transfer is committed
+const accountColumns = "account_number, " +
+	"balance + (SELECT CAST(COALESCE(SUM(amount), 0) AS bigint) FROM public.pending_credits WHERE pending_credits.account_number = accounts.account_number) AS balance, " +
+	"credit_limit, account_type, hot"
+
+// Postgres storage of accounts
+type postgresStorage struct {
+	dbContextFactory func() (db.DbContext, error)
+}
+
+// Creates new Postgres storage of accounts
+//	dbContextFactory - factory function used to create new db context
+func NewPostgresStorage(dbContextFactory func() (db.DbContext, error)) Storage {
+	return postgresStorage{dbContextFactory}
+}
+
+func (storage postgresStorage) Begin(ctx context.Context) (db.UnitOfWork, error) {
+	return db.JoinOrBegin(ctx, func() (db.UnitOfWork, error) {
+		return storage.dbContextFactory()
+	})
+}
+
+func (storage postgresStorage) Accounts(uow db.UnitOfWork) AccountRepository {
+	return NewPostgresRepository(uow.(db.DbContext))
+}
+
+// Postgres repository of accounts
+type postgresRepository struct {
+	dbContext db.DbContext
+}
+
+// Creates new Postgres repository of accounts
+//	dbContext - db context repository works in
+func NewPostgresRepository(dbContext db.DbContext) AccountRepository {
+	return postgresRepository{dbContext}
+}
+
+func (repo postgresRepository) List(filter ListAccountsFilter) ([]Account, error) {
+	var sql = "SELECT " + accountColumns + " FROM public.accounts"
+	var params = sqlParams{}
+	if filter.Type != "" {
+		sql += " WHERE account_type = $1"
+		params = append(params, filter.Type)
+	}
+	sql += " ORDER BY account_number"
+
+	return repo.query(sql, params)
+}
+
+func (repo postgresRepository) Get(accountNum AccountNumber) (*Account, error) {
+	accounts, err := repo.query(
+		"SELECT "+accountColumns+" FROM public.accounts WHERE account_number = $1",
+		sqlParams{int64(uint64(accountNum))},
+	)
+
+	if err != nil || len(accounts) == 0 {
+		return nil, err
+	}
+
+	return &accounts[0], nil
+}
+
+func (repo postgresRepository) LockForUpdate(accountNums ...AccountNumber) ([]Account, error) {
+	var placeholders = make([]string, len(accountNums))
+	var params = make(sqlParams, len(accountNums))
+	for i, accountNum := range accountNums {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		params[i] = int64(uint64(accountNum))
+	}
+
+	return repo.query(
+		"SELECT "+accountColumns+" FROM public.accounts WHERE account_number IN ("+strings.Join(placeholders, ", ")+") "+
+			"ORDER BY account_number FOR UPDATE",
+		params,
+	)
+}
+
+func (repo postgresRepository) Insert(account Account) (AccountNumber, error) {
+	var accountNum int64 = -1
+	err := repo.dbContext.Query(
+		"INSERT INTO public.accounts (balance, credit_limit, account_type) VALUES ($1, $2, $3) RETURNING account_number",
+		sqlParams{account.Balance, account.CreditLimit, account.Type},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&accountNum)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return AccountNumber(uint64(accountNum)), nil
+}
+
+func (repo postgresRepository) AdjustBalance(accountNum AccountNumber, amount int64) error {
+	rowsAffected, err := repo.dbContext.Execute(
+		"UPDATE public.accounts SET balance = balance + $1 WHERE account_number = $2",
+		amount, int64(uint64(accountNum)),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidAccount(accountNum)
+	}
+
+	return nil
+}
+
+func (repo postgresRepository) SetCreditLimit(accountNum AccountNumber, oldLimit, newLimit int64, reason string) error {
+	_, err := repo.dbContext.Execute(
+		"UPDATE public.accounts SET credit_limit = $1 WHERE account_number = $2",
+		newLimit, int64(uint64(accountNum)),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.dbContext.Execute(
+		"INSERT INTO public.credit_limit_changes (account_number, old_limit, new_limit, reason) VALUES ($1, $2, $3, $4)",
+		int64(uint64(accountNum)), oldLimit, newLimit, reason,
+	)
+
+	return err
+}
+
+func (repo postgresRepository) SetHot(accountNum AccountNumber, hot bool) error {
+	_, err := repo.dbContext.Execute(
+		"UPDATE public.accounts SET hot = $1 WHERE account_number = $2",
+		hot, int64(uint64(accountNum)),
+	)
+
+	return err
+}
+
+func (repo postgresRepository) AddPendingCredit(accountNum AccountNumber, amount int64) error {
+	_, err := repo.dbContext.Execute(
+		"INSERT INTO public.pending_credits (account_number, amount) VALUES ($1, $2)",
+		int64(uint64(accountNum)), amount,
+	)
+
+	return err
+}
+
+func (repo postgresRepository) ApplyPendingCredits(accountNum AccountNumber) (int64, error) {
+	// Credits inserted after delete has started are kept for next roll-up
+	var applied int64 = 0
+	err := repo.dbContext.Query(
+		"DELETE FROM public.pending_credits WHERE account_number = $1 RETURNING amount",
+		sqlParams{int64(uint64(accountNum))},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var amount int64
+				err := rows.Scan(&amount)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				applied += amount
+			}
+			return nil
+		},
+	)
+
+	if err != nil || applied == 0 {
+		return 0, err
+	}
+
+	return applied, repo.AdjustBalance(accountNum, applied)
+}
+
+func (repo postgresRepository) ListWithPendingCredits(limit int) ([]AccountNumber, error) {
+	var result = []AccountNumber{}
+	err := repo.dbContext.Query(
+		"SELECT DISTINCT account_number FROM public.pending_credits ORDER BY account_number LIMIT $1",
+		sqlParams{limit},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var accountNumber int64
+				err := rows.Scan(&accountNumber)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				result = append(result, AccountNumber(uint64(accountNumber)))
+			}
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Reads accounts returned by query
+//	sql    - query that selects account columns
+//	params - query parameters
+func (repo postgresRepository) query(sql string, params sqlParams) ([]Account, error) {
+	var result = []Account{}
+	err := repo.dbContext.Query(
+		sql,
+		params,
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					accountNumber int64
+					balance       int64
+					creditLimit   int64
+					accountType   string
+					hot           bool
+				)
+				err := rows.Scan(&accountNumber, &balance, &creditLimit, &accountType, &hot)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				var account = NewAccount(AccountNumber(uint64(accountNumber)), balance, creditLimit)
+				account.Type = accountType
+				account.Hot = hot
+
+				result = append(result, account)
+			}
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
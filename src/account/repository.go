@@ -0,0 +1,78 @@
+package account
+
+import (
+	"context"
+	"test/coins/db"
+)
+
+// Filter applied to list of accounts
+type ListAccountsFilter struct {
+	// If not empty, only accounts of this type are returned
+	Type string
+}
+
+// Repository of accounts. Repository works inside single unit of work
+type AccountRepository interface {
+	// Returns accounts ordered by number
+	//	filter - filter applied to list of accounts
+	List(filter ListAccountsFilter) ([]Account, error)
+
+	// Reads account
+	//	accountNum - account number
+	// Returns account, nil if account does not exist
+	Get(accountNum AccountNumber) (*Account, error)
+
+	// Reads accounts and locks them until unit of work ends. Accounts are locked in order of their numbers,
+	// so units of work that lock the same accounts don't deadlock
+	//	accountNums - account numbers
+	// Returns existing accounts ordered by number
+	LockForUpdate(accountNums ...AccountNumber) ([]Account, error)
+
+	// Adds new account
+	//	account - account type, balance and credit limit of new account
+	// Returns number of added account
+	Insert(account Account) (AccountNumber, error)
+
+	// Adds amount to account balance
+	//	accountNum - account number
+	//	amount     - amount to add, negative to subtract
+	AdjustBalance(accountNum AccountNumber, amount int64) error
+
+	// Sets credit limit of account and records the change in audit trail
+	//	accountNum - account number
+	//	oldLimit   - current credit limit
+	//	newLimit   - new credit limit
+	//	reason     - reason of the change
+	SetCreditLimit(accountNum AccountNumber, oldLimit, newLimit int64, reason string) error
+
+	// Marks account as hot or regular
+	//	accountNum - account number
+	//	hot        - true if account is hot
+	SetHot(accountNum AccountNumber, hot bool) error
+
+	// Adds pending credit to account without locking it. Credit is included in account balance
+	// right after unit of work is saved and is moved to balance column by ApplyPendingCredits
+	//	accountNum - account number
+	//	amount     - credited amount
+	AddPendingCredit(accountNum AccountNumber, amount int64) error
+
+	// Moves pending credits into account balance. Account should be locked by LockForUpdate
+	//	accountNum - account number
+	// Returns applied amount
+	ApplyPendingCredits(accountNum AccountNumber) (int64, error)
+
+	// Returns numbers of accounts that have pending credits, ordered by number
+	//	limit - max number of returned accounts
+	ListWithPendingCredits(limit int) ([]AccountNumber, error)
+}
+
+// Storage backend of accounts
+type Storage interface {
+	// Begins unit of work. If context carries unit of work, nested unit of work is begun in it
+	//	ctx - context
+	Begin(ctx context.Context) (db.UnitOfWork, error)
+
+	// Returns account repository that works inside unit of work
+	//	uow - unit of work created by Begin
+	Accounts(uow db.UnitOfWork) AccountRepository
+}
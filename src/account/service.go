@@ -1,66 +1,235 @@
 package account
 
 import (
-	"test/coins/db"
-	servErr "test/coins/errors"
+	"context"
+	"math"
 )
 
-// Type alias for sql parameters array
-type sqlParams = []interface{}
-
 // Account service. Incapsulates operations with accounts
 type AccountService interface {
 	// Returns list of accounts that is existing in database
-	ListAccounts() ([]Account, error)
+	//	ctx - context, service joins unit of work carried by it
+	ListAccounts(ctx context.Context) ([]Account, error)
+
+	// Opens new customer account with zero balance and no credit limit
+	//	ctx - context, service joins unit of work carried by it
+	// Returns created account
+	CreateAccount(ctx context.Context) (*Account, error)
+
+	// Changes credit limit of account. Every change is recorded in audit trail
+	//	ctx         - context, service joins unit of work carried by it
+	//	accountNum  - account number
+	//	creditLimit - new credit limit, should not be negative
+	//	reason      - reason of the change
+	// Returns account with updated credit limit
+	SetCreditLimit(ctx context.Context, accountNum AccountNumber, creditLimit int64, reason string) (*Account, error)
+
+	// Marks customer account as hot or regular. Transfers to hot account don't lock it,
+	// credits are kept as pending credits until they are rolled up
+	//	ctx        - context, service joins unit of work carried by it
+	//	accountNum - account number
+	//	hot        - true if account is hot
+	// Returns updated account
+	SetHot(ctx context.Context, accountNum AccountNumber, hot bool) (*Account, error)
+
+	// Moves pending credits of accounts into their balances
+	//	ctx       - context, service joins unit of work carried by it
+	//	batchSize - max number of accounts rolled up in single unit of work
+	// Returns number of rolled up accounts
+	RollUpPendingCredits(ctx context.Context, batchSize int) (int, error)
 }
 
 // Account service implementation
 type accountService struct {
-	dbContextFactory func() (db.DbContext, error)
+	storage Storage
 }
 
 // Creates new account service
-//	dbContextFactory - factory function used to create new db context
-func NewAccountService(dbContextFactory func() (db.DbContext, error)) AccountService {
-	return accountService{dbContextFactory}
+//	storage - storage backend of accounts
+func NewAccountService(storage Storage) AccountService {
+	return accountService{storage}
+}
+
+func (svc accountService) ListAccounts(ctx context.Context) ([]Account, error) {
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer uow.Release()
+
+	return svc.storage.Accounts(uow).List(ListAccountsFilter{Type: AccountTypeCustomer})
+}
+
+func (svc accountService) CreateAccount(ctx context.Context) (*Account, error) {
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer uow.Release()
+
+	var account = NewAccount(0, 0, 0)
+	account.Type = AccountTypeCustomer
+	account.Number, err = svc.storage.Accounts(uow).Insert(account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uow.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (svc accountService) SetCreditLimit(ctx context.Context, accountNum AccountNumber, creditLimit int64, reason string) (*Account, error) {
+	if creditLimit < 0 {
+		return nil, ErrInvalidCreditLimit("credit limit should not be negative")
+	}
+
+	if reason == "" {
+		return nil, ErrInvalidCreditLimit("reason of the change should be provided")
+	}
+
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer uow.Release()
+
+	// Account is locked until unit of work ends, so balance can't change during update
+	var repo = svc.storage.Accounts(uow)
+	accounts, err := repo.LockForUpdate(accountNum)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 || accounts[0].Type != AccountTypeCustomer {
+		return nil, ErrInvalidAccount(accountNum)
+	}
+
+	var account = accounts[0]
+	if account.Hot {
+		refreshed, err := ApplyPendingCredits(repo, accountNum)
+		if err != nil {
+			return nil, err
+		}
+
+		account = *refreshed
+	}
+
+	// Compared without sum, which overflows when balance is positive and limit is large
+	if account.Balance == math.MinInt64 || creditLimit < -account.Balance {
+		return nil, ErrInvalidCreditLimit("account balance is below new credit limit")
+	}
+
+	err = repo.SetCreditLimit(accountNum, account.CreditLimit, creditLimit, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uow.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	var result = NewAccount(account.Number, account.Balance, creditLimit)
+	result.Type = account.Type
+	result.Hot = account.Hot
+	return &result, nil
+}
+
+func (svc accountService) SetHot(ctx context.Context, accountNum AccountNumber, hot bool) (*Account, error) {
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer uow.Release()
+
+	var repo = svc.storage.Accounts(uow)
+	accounts, err := repo.LockForUpdate(accountNum)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 || accounts[0].Type != AccountTypeCustomer {
+		return nil, ErrInvalidAccount(accountNum)
+	}
+
+	// Pending credits left after account is made regular are rolled up by background worker
+	err = repo.SetHot(accountNum, hot)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uow.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	var result = accounts[0]
+	result.Hot = hot
+	return &result, nil
 }
 
-func (svc accountService) ListAccounts() ([]Account, error) {
-	dbContext, err := svc.dbContextFactory()
+func (svc accountService) RollUpPendingCredits(ctx context.Context, batchSize int) (int, error) {
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer uow.Release()
+
+	var repo = svc.storage.Accounts(uow)
+	numbers, err := repo.ListWithPendingCredits(batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Accounts are listed in order of numbers, so locking them one by one doesn't deadlock
+	for _, accountNum := range numbers {
+		_, err = repo.LockForUpdate(accountNum)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = repo.ApplyPendingCredits(accountNum)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(numbers) == 0 {
+		return 0, nil
+	}
+
+	err = uow.Save()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(numbers), nil
+}
+
+// Moves pending credits of locked hot account into its balance and reads account again.
+// Balance read while waiting for lock may count credits that were rolled up by other unit of work
+// in the meantime, so checks of hot account balance use account read after pending credits are applied
+//	repo       - account repository
+//	accountNum - number of locked account
+// Returns account with actual balance
+func ApplyPendingCredits(repo AccountRepository, accountNum AccountNumber) (*Account, error) {
+	_, err := repo.ApplyPendingCredits(accountNum)
 	if err != nil {
 		return nil, err
 	}
-	defer dbContext.Release()
-
-	var result = []Account{}
-	err = dbContext.Query(
-		"SELECT account_number, balance FROM public.accounts",
-		sqlParams{},
-		func(rows db.QueryResultRows) error {
-			for rows.Next() {
-				var (
-					accountNumber int64
-					balance       int64
-				)
-				err := rows.Scan(&accountNumber, &balance)
-				if err != nil {
-					return servErr.ErrDatabaseError(err)
-				}
-
-				var account = Account{
-					Number:  AccountNumber(uint64(accountNumber)),
-					Balance: balance,
-				}
-
-				result = append(result, account)
-			}
-			return nil
-		},
-	)
 
+	account, err := repo.Get(accountNum)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	if account == nil {
+		return nil, ErrInvalidAccount(accountNum)
+	}
+
+	return account, nil
 }
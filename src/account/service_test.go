@@ -1,8 +1,10 @@
 package account_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"test/coins/account"
 	"test/coins/db"
 	"testing"
@@ -13,9 +15,9 @@ import (
 )
 
 func setupService(setupMock func(mock sqlmock.Sqlmock)) account.AccountService {
-	return account.NewAccountService(func() (db.DbContext, error) {
+	return account.NewAccountService(account.NewPostgresStorage(func() (db.DbContext, error) {
 		return db.CreateMockDbContext(setupMock)
-	})
+	}))
 }
 
 func valdiateServiceError(expectedKind int, expectedInnerErr error, actual error, method string) (bool, string) {
@@ -47,13 +49,13 @@ func TestP_ListAccounts_SqlErrorHandled(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnError(expectedErr)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	accounts, err := service.ListAccounts()
+	accounts, err := service.ListAccounts(context.Background())
 
 	// Assert
 	isValid, msg := valdiateServiceError(servErr.ErrorKindDB, expectedErr, err, "ListAccounts()")
@@ -78,14 +80,14 @@ func Test_ListAccounts_NoAccounts(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		var rows = sqlmock.NewRows([]string{"account_number", "balance"})
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(rows)
+		var rows = sqlmock.NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"})
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(rows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	accounts, err := service.ListAccounts()
+	accounts, err := service.ListAccounts(context.Background())
 
 	// Assert
 	if err != nil {
@@ -117,16 +119,16 @@ func Test_ListAccounts_AccountsRetrievedSuccessfully(t *testing.T) {
 		mock.ExpectBegin()
 
 		var rows = sqlmock.
-			NewRows([]string{"account_number", "balance"}).
-			AddRow(an1, b1).
-			AddRow(an2, b2)
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(rows)
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an1, b1, 0, account.AccountTypeCustomer, false).
+			AddRow(an2, b2, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(rows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	accounts, err := service.ListAccounts()
+	accounts, err := service.ListAccounts(context.Background())
 
 	// Assert
 	if err != nil {
@@ -171,3 +173,258 @@ func Test_ListAccounts_AccountsRetrievedSuccessfully(t *testing.T) {
 		t.Fatalf("invalid account 2 balance")
 	}
 }
+
+func Test_SetCreditLimit_ChangeRecordedInAuditTrail(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var (
+		an       int64 = 1
+		balance  int64 = -200
+		oldLimit int64 = 500
+		newLimit int64 = 1000
+		reason         = "limit increase agreed with client"
+	)
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an, balance, oldLimit, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WithArgs(an).WillReturnRows(rows)
+
+		mock.ExpectExec("UPDATE public.accounts SET credit_limit").
+			WithArgs(newLimit, an).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("INSERT INTO public.credit_limit_changes").
+			WithArgs(an, oldLimit, newLimit, reason).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	acc, err := service.SetCreditLimit(context.Background(), account.AccountNumber(uint64(an)), newLimit, reason)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetCreditLimit() was called: %s", err.Error())
+	}
+
+	if acc == nil || acc.CreditLimit != newLimit {
+		t.Fatalf("expected account with updated credit limit to be returned")
+	}
+
+	if acc.AvailableCredit != newLimit+balance {
+		t.Fatalf("invalid available credit: %d, expected: %d", acc.AvailableCredit, newLimit+balance)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SetCreditLimit_BalanceShouldBeCoveredByNewLimit(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var (
+		an      int64 = 1
+		balance int64 = -700
+	)
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an, balance, 1000, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WithArgs(an).WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	acc, err := service.SetCreditLimit(context.Background(), account.AccountNumber(uint64(an)), 500, "limit decrease")
+
+	// Assert
+	if !errors.Is(err, account.ErrInvalidCreditLimit("")) {
+		t.Fatalf("expected invalid credit limit error, got: %v", err)
+	}
+
+	if acc != nil {
+		t.Fatalf("in case of any error, SetCreditLimit() should return (nil, error) as result")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SetCreditLimit_MaxLimitOfAccountWithPositiveBalance_LimitIsSet(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var (
+		an       int64 = 1
+		balance  int64 = 700
+		newLimit int64 = math.MaxInt64
+		reason         = "unlimited credit line"
+	)
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an, balance, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WithArgs(an).WillReturnRows(rows)
+
+		mock.ExpectExec("UPDATE public.accounts SET credit_limit").
+			WithArgs(newLimit, an).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("INSERT INTO public.credit_limit_changes").
+			WithArgs(an, int64(0), newLimit, reason).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	acc, err := service.SetCreditLimit(context.Background(), account.AccountNumber(uint64(an)), newLimit, reason)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetCreditLimit() was called: %s", err.Error())
+	}
+
+	if funds := acc.AvailableFunds(); funds != math.MaxInt64 {
+		t.Fatalf("available funds should not overflow, expected %d, got %d", int64(math.MaxInt64), funds)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_CreateAccount_JoinsUnitOfWorkFromContext(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var an int64 = 3
+	dbContext, err := db.CreateMockDbContext(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		var rows = sqlmock.NewRows([]string{"account_number"}).AddRow(an)
+		mock.ExpectQuery("INSERT INTO public.accounts").
+			WithArgs(int64(0), int64(0), account.AccountTypeCustomer).
+			WillReturnRows(rows)
+
+		mock.ExpectExec("RELEASE SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	})
+	if err != nil {
+		t.Fatalf("unable to create mock db context: %s", err.Error())
+	}
+
+	// Service should not begin its own transaction
+	var service = account.NewAccountService(account.NewPostgresStorage(nil))
+	var ctx = db.WithUnitOfWork(context.Background(), dbContext)
+
+	// Act
+	acc, err := service.CreateAccount(ctx)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when CreateAccount() was called: %s", err.Error())
+	}
+
+	if acc == nil || acc.Number != account.AccountNumber(uint64(an)) || acc.Balance != 0 {
+		t.Fatalf("expected created account with zero balance to be returned, got %+v", acc)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_RollUpPendingCredits_CreditsAppliedToBalance(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var an int64 = 1
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT DISTINCT account_number FROM public.pending_credits").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"account_number"}).AddRow(an))
+
+		var rows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an, 250, 0, account.AccountTypeCustomer, true)
+		mock.ExpectQuery("SELECT account_number, balance .+ FOR UPDATE").WithArgs(an).WillReturnRows(rows)
+
+		mock.ExpectQuery("DELETE FROM public.pending_credits").
+			WithArgs(an).
+			WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(100).AddRow(50))
+
+		mock.ExpectExec("UPDATE public.accounts SET balance = balance").
+			WithArgs(int64(150), an).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	rolledUp, err := service.RollUpPendingCredits(context.Background(), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when RollUpPendingCredits() was called: %s", err.Error())
+	}
+
+	if rolledUp != 1 {
+		t.Fatalf("expected 1 rolled up account, got %d", rolledUp)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SetHot_SettlementAccountCanNotBeHot(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var an int64 = 3
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(an, -1000, 0, account.AccountTypeSettlement, false)
+		mock.ExpectQuery("SELECT account_number, balance .+ FOR UPDATE").WithArgs(an).WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	acc, err := service.SetHot(context.Background(), account.AccountNumber(uint64(an)), true)
+
+	// Assert
+	if acc != nil || err == nil || err.Error() != account.ErrInvalidAccount(account.AccountNumber(uint64(an))).Error() {
+		t.Fatalf("expected account not found error, got %v", err)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
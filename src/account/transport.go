@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 
@@ -21,7 +22,7 @@ import (
 func RegisterHandlers(mr *mux.Router, svc AccountService, logger kitlog.Logger) {
 	var opts = []kithttp.ServerOption{
 		kithttp.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
-		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerErrorEncoder(servErr.EncodeError),
 	}
 
 	var listAccountsHandler = kithttp.NewServer(
@@ -32,6 +33,24 @@ func RegisterHandlers(mr *mux.Router, svc AccountService, logger kitlog.Logger)
 	)
 
 	mr.Handle("/api/v1/accounts", listAccountsHandler).Methods("GET")
+
+	var setCreditLimitHandler = kithttp.NewServer(
+		makeSetCreditLimitEndpoint(svc),
+		decodeSetCreditLimitRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/accounts/{account}/credit-limit", setCreditLimitHandler).Methods("PUT")
+
+	var setHotHandler = kithttp.NewServer(
+		makeSetHotEndpoint(svc),
+		decodeSetHotRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/accounts/{account}/hot", setHotHandler).Methods("PUT")
 }
 
 type errorer interface {
@@ -42,29 +61,58 @@ func decodeListAccontsRequest(_ context.Context, r *http.Request) (interface{},
 	return nil, nil
 }
 
+func decodeSetCreditLimitRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	accNum, err := decodeAccountNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body setCreditLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid request body", err)
+	}
+
+	body.AccountNumber = accNum
+	return body, nil
+}
+
+func decodeSetHotRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	accNum, err := decodeAccountNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body setHotRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid request body", err)
+	}
+
+	body.AccountNumber = accNum
+	return body, nil
+}
+
+// Reads account number from request path
+//	r - http request
+func decodeAccountNumber(r *http.Request) (uint64, error) {
+	var vars = mux.Vars(r)
+	accountNumber, ok := vars["account"]
+	if !ok {
+		return 0, servErr.ErrInvalidRequest("invalid account number", nil)
+	}
+
+	accNum, err := strconv.ParseUint(accountNumber, 10, 64)
+	if err != nil {
+		return 0, servErr.ErrInvalidRequest("invalid account number", err)
+	}
+
+	return accNum, nil
+}
+
 func encodeResponse(ctx context.Context, wr http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(errorer); ok && e.error() != nil {
-		encodeError(ctx, e.error(), wr)
+		servErr.EncodeError(ctx, e.error(), wr)
 		return nil
 	}
 	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(wr).Encode(response)
 }
-
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	switch svcErr := err.(type) {
-	case servErr.ServiceError:
-		if svcErr.Kind() == servErr.ErrorKindDB {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
-}
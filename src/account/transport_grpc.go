@@ -0,0 +1,85 @@
+package account
+
+import (
+	"context"
+	"test/coins/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kittransport "github.com/go-kit/kit/transport"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// gRPC server of account service
+type grpcServer struct {
+	pb.UnimplementedAccountServiceServer
+
+	listAccounts kitgrpc.Handler
+}
+
+// Creates gRPC server for account service
+// svc    - service to expose
+// logger - logger
+func NewGRPCServer(svc AccountService, logger kitlog.Logger) pb.AccountServiceServer {
+	var opts = []kitgrpc.ServerOption{
+		kitgrpc.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
+	}
+
+	return &grpcServer{
+		listAccounts: kitgrpc.NewServer(
+			makeListAccountsEndpoint(svc),
+			decodeGRPCListAccountsRequest,
+			encodeGRPCListAccountsResponse,
+			opts...,
+		),
+	}
+}
+
+func (s *grpcServer) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	_, resp, err := s.listAccounts.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ListAccountsResponse), nil
+}
+
+func decodeGRPCListAccountsRequest(_ context.Context, _ interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func encodeGRPCListAccountsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	var resp = response.(listAccountsResponse)
+	if resp.Error != nil {
+		return nil, encodeGRPCError(resp.Error)
+	}
+
+	var accounts = make([]*pb.Account, len(resp.Accounts))
+	for i, acc := range resp.Accounts {
+		accounts[i] = &pb.Account{
+			Number:          uint64(acc.Number),
+			Balance:         acc.Balance,
+			CreditLimit:     acc.CreditLimit,
+			AvailableCredit: acc.AvailableCredit,
+		}
+	}
+	return &pb.ListAccountsResponse{Accounts: accounts}, nil
+}
+
+// Converts service error to gRPC status error
+func encodeGRPCError(err error) error {
+	var code = codes.Internal
+	if svcErr, ok := err.(servErr.ServiceError); ok {
+		switch svcErr.Kind() {
+		case errCodeInvalidAccount:
+			code = codes.NotFound
+		case errCodeInvalidCreditLimit:
+			code = codes.InvalidArgument
+		}
+	}
+
+	return status.Error(code, err.Error())
+}
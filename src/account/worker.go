@@ -0,0 +1,65 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Background worker that periodically rolls up pending credits of hot accounts into their balances
+type Worker struct {
+	svc AccountService
+
+	interval time.Duration
+
+	batchSize int
+
+	logger kitlog.Logger
+}
+
+// Creates new pending credits worker
+//	svc       - account service
+//	interval  - interval between roll-ups
+//	batchSize - max number of accounts rolled up in single transaction
+//	logger    - logger
+func NewWorker(svc AccountService, interval time.Duration, batchSize int, logger kitlog.Logger) *Worker {
+	return &Worker{svc, interval, batchSize, logger}
+}
+
+// Runs worker until context is cancelled
+//	ctx - context used to stop worker
+func (w *Worker) Run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.rollUpPendingCredits(ctx)
+		}
+	}
+}
+
+// Rolls up pending credits batch by batch, until there is no more accounts with pending credits
+//	ctx - context used to stop worker
+func (w *Worker) rollUpPendingCredits(ctx context.Context) {
+	for {
+		rolledUp, err := w.svc.RollUpPendingCredits(ctx, w.batchSize)
+		if err != nil {
+			level.Error(w.logger).Log("msg", "failed to roll up pending credits", "err", err)
+			return
+		}
+
+		if rolledUp > 0 {
+			level.Debug(w.logger).Log("msg", "rolled up pending credits", "accounts", rolledUp)
+		}
+
+		if rolledUp < w.batchSize {
+			return
+		}
+	}
+}
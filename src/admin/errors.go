@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"net/http"
+	servErr "test/coins/errors"
+)
+
+const (
+	ErrKindUnauthorized int = 70 + iota
+)
+
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindUnauthorized, servErr.ErrorDefinition{Code: "unauthorized", Status: http.StatusUnauthorized, Title: "Unauthorized"})
+}
+
+// Error that is expected when admin request has no token or token is invalid
+var ErrUnauthorized = servErr.NewServiceError(
+	"valid admin token should be passed in Authorization header", nil, ErrKindUnauthorized)
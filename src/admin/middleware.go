@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	servErr "test/coins/errors"
+)
+
+// Scheme of Authorization header value that carries admin token
+const bearerScheme = "Bearer "
+
+// Creates middleware that passes only requests authorized by admin token, it should be sent as
+// "Authorization: Bearer <token>" header. Other requests are rejected with "Unauthorized" error
+//	token - admin token, requests are always rejected if it is empty
+// Returns function that wraps handler with middleware
+func Middleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !authorized(r.Header.Get("Authorization"), token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				servErr.EncodeError(r.Context(), ErrUnauthorized, w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Checks that Authorization header carries admin token. Tokens are compared in constant time,
+// so time of comparison doesn't tell how many characters are guessed
+//	header - value of Authorization header
+//	token  - admin token
+func authorized(header string, token string) bool {
+	if token == "" || len(header) < len(bearerScheme) || !strings.EqualFold(header[:len(bearerScheme)], bearerScheme) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(header[len(bearerScheme):]), []byte(token)) == 1
+}
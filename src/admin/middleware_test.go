@@ -0,0 +1,114 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"test/coins/admin"
+	"testing"
+
+	servErr "test/coins/errors"
+)
+
+const testToken = "2b7e1516-28ae-d2a6-abf7-158809cf4f3c"
+
+// Creates middleware with handler that counts calls
+func setupMiddleware(calls *int) http.Handler {
+	return admin.Middleware(testToken)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func Test_Middleware_NoToken_RequestRejected(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = setupMiddleware(&calls)
+	var req = httptest.NewRequest("POST", "/api/v1/admin/deposits", nil)
+	var rec = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+
+	if calls != 0 {
+		t.Fatalf("handler should not be called for unauthenticated request, it was called %d times", calls)
+	}
+
+	var problem servErr.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil || problem.Code != "unauthorized" {
+		t.Fatalf("expected unauthorized problem, got %+v, error %v", problem, err)
+	}
+
+	if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Fatalf("expected WWW-Authenticate header, got [%s]", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func Test_Middleware_InvalidToken_RequestRejected(t *testing.T) {
+	var cases = []string{
+		"Bearer wrong-token",
+		"Basic " + testToken,
+		testToken,
+		"Bearer ",
+	}
+
+	for _, header := range cases {
+		t.Run(header, func(t *testing.T) {
+			// Arrange
+			var calls = 0
+			var handler = setupMiddleware(&calls)
+			var req = httptest.NewRequest("POST", "/api/v1/admin/deposits", nil)
+			req.Header.Set("Authorization", header)
+			var rec = httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusUnauthorized || calls != 0 {
+				t.Fatalf("expected request to be rejected with status 401, got status %d and %d calls", rec.Code, calls)
+			}
+		})
+	}
+}
+
+func Test_Middleware_ValidToken_RequestPassed(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = setupMiddleware(&calls)
+	var req = httptest.NewRequest("POST", "/api/v1/admin/deposits", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	var rec = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("expected request to be passed to handler, got status %d and %d calls", rec.Code, calls)
+	}
+}
+
+func Test_Middleware_EmptyToken_AllRequestsRejected(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = admin.Middleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	var req = httptest.NewRequest("POST", "/api/v1/admin/deposits", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	var rec = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusUnauthorized || calls != 0 {
+		t.Fatalf("expected request to be rejected with status 401, got status %d and %d calls", rec.Code, calls)
+	}
+}
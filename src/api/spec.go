@@ -0,0 +1,26 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// OpenAPI 3 document that describes http API of application
+//go:embed openapi.json
+var spec []byte
+
+// Returns OpenAPI 3 document of application API in JSON format
+func Spec() []byte {
+	return spec
+}
+
+// Registers http handler that serves OpenAPI document
+// mr - Mux router where handler should be registered
+func RegisterHandlers(mr *mux.Router) {
+	mr.HandleFunc("/api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(spec)
+	}).Methods("GET")
+}
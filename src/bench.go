@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"test/coins/bench"
+	"time"
+)
+
+// Runs "bench" command: sends transfers and read requests to running application and reports latency and errors
+//	args - command flags
+//	out  - writer for command output
+func runBench(args []string, out io.Writer) error {
+	var opts = bench.DefaultOptions()
+	var fs = flag.NewFlagSet("coins bench", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&opts.URL, "url", opts.URL, "base url of application")
+	fs.IntVar(&opts.Concurrency, "concurrency", opts.Concurrency, "number of concurrent clients")
+	fs.Float64Var(&opts.Rate, "rate", opts.Rate, "max number of requests per second, 0 - no limit")
+	fs.DurationVar(&opts.Duration, "duration", opts.Duration, "run duration")
+	fs.IntVar(&opts.Requests, "requests", opts.Requests, "max number of requests, 0 - until run duration ends")
+	fs.Float64Var(&opts.ReadShare, "read-share", opts.ReadShare, "share of read requests")
+	fs.StringVar(&opts.Distribution, "distribution", opts.Distribution, "distribution of transfer accounts: uniform or hot")
+	fs.Float64Var(&opts.HotShare, "hot-share", opts.HotShare, "share of transfers that involve hot account")
+	fs.Uint64Var(&opts.MaxAmount, "max-amount", opts.MaxAmount, "max amount of single transfer")
+	fs.Uint64Var(&opts.Fund, "fund", opts.Fund, "money deposited into every account before run, 0 - accounts are not funded")
+	fs.StringVar(&opts.AdminURL, "admin-url", opts.AdminURL, "base url of admin API, used to fund accounts")
+	fs.StringVar(&opts.AdminToken, "admin-token", opts.AdminToken, "admin token, used to fund accounts")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of random generator")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// Interrupted run still reports requests that were sent
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var client = &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: &http.Transport{MaxIdleConnsPerHost: opts.Concurrency},
+	}
+
+	fmt.Fprintf(out, "benchmarking %s by %d clients, %s distribution\n", opts.URL, opts.Concurrency, opts.Distribution)
+	report, err := bench.Run(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(out, report.String())
+	return nil
+}
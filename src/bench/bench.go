@@ -0,0 +1,464 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"test/coins/account"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Transfers are made between random accounts
+const DistributionUniform = "uniform"
+
+// Share of transfers goes from or to the same hot account, the rest are made between random accounts
+const DistributionHot = "hot"
+
+// Names of benchmarked operations
+const (
+	OpTransfer      = "POST /api/v1/transfers"
+	OpListAccounts  = "GET /api/v1/accounts"
+	OpListTransfers = "GET /api/v1/accounts/{account}/transfers"
+)
+
+// Error code of requests that got no response
+const transportError = "transport_error"
+
+// Settings of benchmark run
+type Options struct {
+	// Base url of application, for example "http://localhost:8080"
+	URL string
+
+	// Number of concurrent clients
+	Concurrency int
+
+	// Max number of requests per second of all clients, 0 - no limit
+	Rate float64
+
+	// Run duration
+	Duration time.Duration
+
+	// Max number of requests, 0 - requests are sent until run duration ends
+	Requests int
+
+	// Share of read requests, they are split equally between listing accounts and listing transfers
+	ReadShare float64
+
+	// Distribution of transfer accounts: DistributionUniform or DistributionHot
+	Distribution string
+
+	// Share of transfers that involve hot account, used with DistributionHot
+	HotShare float64
+
+	// Max amount of single transfer, amounts are random from 1 to MaxAmount
+	MaxAmount uint64
+
+	// Money deposited into every account before run, 0 - accounts are not funded
+	Fund uint64
+
+	// Base url of admin API, used to fund accounts, for example "http://localhost:8081"
+	AdminURL string
+
+	// Admin token, sent in Authorization header of admin requests
+	AdminToken string
+
+	// Seed of random generator
+	Seed int64
+}
+
+// Returns default benchmark settings
+func DefaultOptions() Options {
+	return Options{
+		URL:          "http://localhost:8080",
+		AdminURL:     "http://localhost:8081",
+		Concurrency:  16,
+		Duration:     time.Second * 30,
+		ReadShare:    0.2,
+		Distribution: DistributionUniform,
+		HotShare:     0.5,
+		MaxAmount:    100,
+		Seed:         time.Now().UnixNano(),
+	}
+}
+
+// Statistics of single operation
+type OperationStats struct {
+	// Operation name
+	Name string
+
+	// Number of sent requests
+	Count int
+
+	// Number of failed requests by error code of ServiceError kind, "transport_error" - request got no response
+	Errors map[string]int
+
+	// Latency percentiles and max latency
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Result of benchmark run
+type Report struct {
+	// Time spent on sending requests
+	Elapsed time.Duration
+
+	// Statistics of operations that were sent at least once
+	Operations []OperationStats
+}
+
+func (r Report) String() string {
+	var sb strings.Builder
+	var total = 0
+	for _, op := range r.Operations {
+		total += op.Count
+	}
+
+	fmt.Fprintf(&sb, "%d requests in %s, %.1f requests/s\n", total, r.Elapsed.Round(time.Millisecond), float64(total)/r.Elapsed.Seconds())
+	for _, op := range r.Operations {
+		fmt.Fprintf(&sb, "%s: %d requests, %.1f/s, p50 %s, p90 %s, p99 %s, max %s\n",
+			op.Name, op.Count, float64(op.Count)/r.Elapsed.Seconds(), op.P50, op.P90, op.P99, op.Max)
+
+		var codes = []string{}
+		for code := range op.Errors {
+			codes = append(codes, code)
+		}
+
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&sb, "  %s: %d\n", code, op.Errors[code])
+		}
+	}
+
+	return sb.String()
+}
+
+// Result of single request
+type result struct {
+	op      string
+	latency time.Duration
+
+	// Error code, empty if request succeeded
+	code string
+}
+
+// Benchmark run state shared by clients
+type run struct {
+	opts     Options
+	client   *http.Client
+	accounts []account.AccountNumber
+}
+
+// Sends requests to application and collects latency and errors of every operation.
+// Accounts are read from application, at least two customer accounts should exist
+//	ctx    - context, run stops when it is cancelled
+//	client - http client
+//	opts   - run settings
+// Returns run report. Error is returned if accounts could not be read or funded
+func Run(ctx context.Context, client *http.Client, opts Options) (Report, error) {
+	if opts.Concurrency < 1 || opts.MaxAmount < 1 {
+		return Report{}, errors.New("at least 1 client and max amount of 1 are required")
+	}
+
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		return Report{}, errors.New("run duration or number of requests is required")
+	}
+
+	if opts.Distribution != DistributionUniform && opts.Distribution != DistributionHot {
+		return Report{}, fmt.Errorf("unknown distribution [%s], should be %s or %s", opts.Distribution, DistributionUniform, DistributionHot)
+	}
+
+	var r = &run{opts: opts, client: client}
+	err := r.readAccounts(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	if opts.Fund > 0 {
+		for _, number := range r.accounts {
+			res := r.deposit(ctx, number, opts.Fund)
+			if res.code != "" {
+				return Report{}, fmt.Errorf("unable to fund account %d: %s", number, res.code)
+			}
+		}
+	}
+
+	// Run context is cancelled when run ends, so rate limiter is stopped even if run is limited by number of requests
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		sent    int64
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = []result{}
+		tokens  = r.limiter(ctx)
+	)
+
+	var start = time.Now()
+	for c := 0; c < opts.Concurrency; c++ {
+		wg.Add(1)
+		go func(rnd *rand.Rand) {
+			defer wg.Done()
+			var own = []result{}
+			for ctx.Err() == nil {
+				if opts.Requests > 0 && atomic.AddInt64(&sent, 1) > int64(opts.Requests) {
+					break
+				}
+
+				if tokens != nil {
+					if _, ok := <-tokens; !ok {
+						break
+					}
+				}
+
+				var res = r.request(ctx, rnd)
+				// Request interrupted by end of run is not counted
+				if ctx.Err() != nil && res.code == transportError {
+					break
+				}
+
+				own = append(own, res)
+			}
+
+			mu.Lock()
+			results = append(results, own...)
+			mu.Unlock()
+		}(rand.New(rand.NewSource(opts.Seed + int64(c))))
+	}
+	wg.Wait()
+
+	return Report{Elapsed: time.Since(start), Operations: summarize(results)}, nil
+}
+
+// Starts goroutine that allows requests with configured rate
+//	ctx - context, goroutine stops when it is cancelled
+// Returns channel of request permits, nil if rate is not limited
+func (r *run) limiter(ctx context.Context) <-chan struct{} {
+	if r.opts.Rate <= 0 {
+		return nil
+	}
+
+	var tokens = make(chan struct{})
+	go func() {
+		defer close(tokens)
+		var ticker = time.NewTicker(time.Duration(float64(time.Second) / r.opts.Rate))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case tokens <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return tokens
+}
+
+// Reads customer accounts of application
+func (r *run) readAccounts(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.opts.URL+"/api/v1/accounts", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to read accounts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Accounts []account.Account `json:"accounts"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to read accounts, status %d", resp.StatusCode)
+	}
+
+	for _, acc := range body.Accounts {
+		r.accounts = append(r.accounts, acc.Number)
+	}
+
+	if len(r.accounts) < 2 {
+		return errors.New("at least 2 customer accounts are required")
+	}
+
+	// The first account is hot one
+	sort.Slice(r.accounts, func(i, j int) bool { return r.accounts[i] < r.accounts[j] })
+	return nil
+}
+
+// Sends random request
+func (r *run) request(ctx context.Context, rnd *rand.Rand) result {
+	if p := rnd.Float64(); p < r.opts.ReadShare/2 {
+		return r.get(ctx, "/api/v1/accounts", OpListAccounts)
+	} else if p < r.opts.ReadShare {
+		var number = r.accounts[rnd.Intn(len(r.accounts))]
+		return r.get(ctx, fmt.Sprintf("/api/v1/accounts/%d/transfers", number), OpListTransfers)
+	}
+
+	var source, dest = r.pickAccounts(rnd)
+	return r.post(ctx, "/api/v1/transfers", map[string]interface{}{
+		"id":     uuid.New(),
+		"source": source,
+		"dest":   dest,
+		"amount": 1 + uint64(rnd.Int63n(int64(r.opts.MaxAmount))),
+	}, OpTransfer)
+}
+
+// Returns two different accounts picked by configured distribution
+func (r *run) pickAccounts(rnd *rand.Rand) (account.AccountNumber, account.AccountNumber) {
+	var n = len(r.accounts)
+	var source = rnd.Intn(n)
+	// Dest is picked among other accounts, so it always differs from source
+	var dest = (source + 1 + rnd.Intn(n-1)) % n
+
+	if r.opts.Distribution == DistributionHot && rnd.Float64() < r.opts.HotShare {
+		// Hot account is either source or dest, other account is picked among the rest
+		var other = 1 + rnd.Intn(n-1)
+		if rnd.Intn(2) == 0 {
+			return r.accounts[0], r.accounts[other]
+		}
+
+		return r.accounts[other], r.accounts[0]
+	}
+
+	return r.accounts[source], r.accounts[dest]
+}
+
+func (r *run) get(ctx context.Context, path string, op string) result {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.opts.URL+path, nil)
+	if err != nil {
+		return result{op: op, code: transportError}
+	}
+
+	return r.do(req, op)
+}
+
+func (r *run) post(ctx context.Context, path string, body interface{}, op string) result {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return result{op: op, code: transportError}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.opts.URL+path, bytes.NewReader(data))
+	if err != nil {
+		return result{op: op, code: transportError}
+	}
+
+	return r.do(req, op)
+}
+
+// Deposits money into account via admin API
+func (r *run) deposit(ctx context.Context, number account.AccountNumber, amount uint64) result {
+	const op = "deposit"
+	data, err := json.Marshal(map[string]interface{}{"id": uuid.New(), "account": number, "amount": amount})
+	if err != nil {
+		return result{op: op, code: transportError}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.opts.AdminURL+"/api/v1/admin/deposits", bytes.NewReader(data))
+	if err != nil {
+		return result{op: op, code: transportError}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+r.opts.AdminToken)
+	return r.do(req, op)
+}
+
+// Sends request and measures its latency. Error code is read from problem response
+func (r *run) do(req *http.Request, op string) result {
+	var start = time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return result{op: op, latency: time.Since(start), code: transportError}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return result{op: op, latency: time.Since(start)}
+	}
+
+	var problem servErr.Problem
+	err = json.NewDecoder(resp.Body).Decode(&problem)
+	var latency = time.Since(start)
+	if err != nil || problem.Code == "" {
+		return result{op: op, latency: latency, code: fmt.Sprintf("http_%d", resp.StatusCode)}
+	}
+
+	return result{op: op, latency: latency, code: problem.Code}
+}
+
+// Calculates statistics of operations
+//	results - results of requests
+// Returns statistics of operations sorted by name
+func summarize(results []result) []OperationStats {
+	var latencies = map[string][]time.Duration{}
+	var stats = map[string]*OperationStats{}
+	for _, res := range results {
+		var op, ok = stats[res.op]
+		if !ok {
+			op = &OperationStats{Name: res.op, Errors: map[string]int{}}
+			stats[res.op] = op
+		}
+
+		op.Count++
+		if res.code != "" {
+			op.Errors[res.code]++
+		}
+
+		latencies[res.op] = append(latencies[res.op], res.latency)
+	}
+
+	var result = []OperationStats{}
+	for name, op := range stats {
+		var values = latencies[name]
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		op.P50 = percentile(values, 0.5)
+		op.P90 = percentile(values, 0.9)
+		op.P99 = percentile(values, 0.99)
+		op.Max = values[len(values)-1]
+		result = append(result, *op)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Returns percentile of sorted values, nearest-rank method is used
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	var rank = int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
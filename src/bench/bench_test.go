@@ -0,0 +1,101 @@
+package bench_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"test/coins/account"
+	"test/coins/admin"
+	"test/coins/bench"
+	"test/coins/memory"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+)
+
+const testAdminToken = "2b7e1516-28ae-d2a6-abf7-158809cf4f3c"
+
+// Starts application server and admin server that requires admin token
+func setupServer(t *testing.T) (*httptest.Server, *httptest.Server) {
+	var store = memory.NewDemoStore(time.Second*5, nil)
+	for i := 0; i < 8; i++ {
+		store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	}
+
+	var mr = mux.NewRouter()
+	account.RegisterHandlers(mr, account.NewAccountService(store), log.NewNopLogger())
+	transfer.RegisterHandlers(mr, transfer.NewTransferService(store), log.NewNopLogger())
+
+	var adminRouter = mux.NewRouter()
+	transfer.RegisterAdminHandlers(adminRouter, transfer.NewTransferService(store), log.NewNopLogger())
+
+	var server = httptest.NewServer(mr)
+	t.Cleanup(server.Close)
+	var adminServer = httptest.NewServer(admin.Middleware(testAdminToken)(adminRouter))
+	t.Cleanup(adminServer.Close)
+	return server, adminServer
+}
+
+func Test_Run_HotAccount_LatencyAndErrorsReported(t *testing.T) {
+	// Arrange
+	var server, adminServer = setupServer(t)
+	var opts = bench.DefaultOptions()
+	opts.URL = server.URL
+	opts.AdminURL = adminServer.URL
+	opts.AdminToken = testAdminToken
+	opts.Concurrency = 4
+	opts.Duration = 0
+	opts.Requests = 400
+	opts.Distribution = bench.DistributionHot
+	opts.HotShare = 0.8
+	opts.Fund = 50
+	opts.Seed = 1
+
+	// Act
+	report, err := bench.Run(context.Background(), server.Client(), opts)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Run() was called: %s", err.Error())
+	}
+
+	var total = 0
+	var stats = map[string]bench.OperationStats{}
+	for _, op := range report.Operations {
+		total += op.Count
+		stats[op.Name] = op
+		if op.P50 > op.P90 || op.P90 > op.P99 || op.P99 > op.Max || op.Max == 0 {
+			t.Errorf("latency percentiles of %s are not ordered: %+v", op.Name, op)
+		}
+	}
+
+	if total != opts.Requests {
+		t.Fatalf("expected %d requests, got %d", opts.Requests, total)
+	}
+
+	var transfers = stats[bench.OpTransfer]
+	if transfers.Count == 0 || stats[bench.OpListAccounts].Count == 0 || stats[bench.OpListTransfers].Count == 0 {
+		t.Fatalf("expected transfers and both read operations to be sent, got:\n%s", report)
+	}
+
+	// Accounts are funded with small amount, so some transfers fail with not enough money
+	if transfers.Errors["insufficient_funds"] == 0 || len(transfers.Errors) != 1 {
+		t.Fatalf("expected only insufficient funds errors of transfers, got %v", transfers.Errors)
+	}
+}
+
+func Test_Run_UnknownDistribution_ErrorReturned(t *testing.T) {
+	// Arrange
+	var opts = bench.DefaultOptions()
+	opts.Distribution = "zipf"
+
+	// Act
+	_, err := bench.Run(context.Background(), nil, opts)
+
+	// Assert
+	if err == nil {
+		t.Fatalf("expected error for unknown distribution")
+	}
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variable with path of configuration file
+const EnvConfigFile = "CONFIG_FILE"
+
+// Setting that can be set from environment variable and command line flag
+type setting struct {
+	// Flag name
+	flag string
+
+	// Environment variable name
+	env string
+
+	// Description shown in flags usage
+	usage string
+
+	// Flag can be set without value, for example "-feature-stream" or "-feature-stream=false"
+	isBool bool
+
+	// Function that parses value and sets it to configuration
+	set func(cfg *Config, value string) error
+}
+
+func stringSetting(flag string, env string, usage string, field func(cfg *Config) *string) setting {
+	return setting{flag, env, usage, false, func(cfg *Config, v string) error {
+		*field(cfg) = v
+		return nil
+	}}
+}
+
+func intSetting(flag string, env string, usage string, field func(cfg *Config) *int) setting {
+	return setting{flag, env, usage, false, func(cfg *Config, v string) error {
+		return parseInt(v, field(cfg))
+	}}
+}
+
+func durationSetting(flag string, env string, usage string, field func(cfg *Config) *Duration) setting {
+	return setting{flag, env, usage, false, func(cfg *Config, v string) error {
+		return parseDuration(v, field(cfg))
+	}}
+}
+
+func boolSetting(flag string, env string, usage string, field func(cfg *Config) *bool) setting {
+	return setting{flag, env, usage, true, func(cfg *Config, v string) error {
+		return parseBool(v, field(cfg))
+	}}
+}
+
+// Returns all settings that can be set from environment variables and command line flags
+func settings() []setting {
+	return []setting{
+		stringSetting("database-cs", "DATABASE_CS", "database connection string",
+			func(cfg *Config) *string { return &cfg.Database.ConnectionString }),
+		intSetting("database-pool-size", "DATABASE_POOL_SIZE", "max number of database connections",
+			func(cfg *Config) *int { return &cfg.Database.PoolSize }),
+		durationSetting("database-acquire-timeout", "DATABASE_ACQUIRE_TIMEOUT", "time to wait for free database connection",
+			func(cfg *Config) *Duration { return &cfg.Database.AcquireTimeout }),
+		durationSetting("database-transaction-timeout", "DATABASE_TRANSACTION_TIMEOUT", "database transaction timeout",
+			func(cfg *Config) *Duration { return &cfg.Database.TransactionTimeout }),
+		boolSetting("database-auto-migrate", "DATABASE_AUTO_MIGRATE", "apply pending migrations on start",
+			func(cfg *Config) *bool { return &cfg.Database.AutoMigrate }),
+		durationSetting("database-migration-timeout", "DATABASE_MIGRATION_TIMEOUT", "timeout of single migration",
+			func(cfg *Config) *Duration { return &cfg.Database.MigrationTimeout }),
+		stringSetting("http-address", "HTTP_ADDRESS", "http server address",
+			func(cfg *Config) *string { return &cfg.HTTP.Address }),
+		durationSetting("http-read-timeout", "HTTP_READ_TIMEOUT", "http request read timeout",
+			func(cfg *Config) *Duration { return &cfg.HTTP.ReadTimeout }),
+		durationSetting("http-write-timeout", "HTTP_WRITE_TIMEOUT", "http response write timeout",
+			func(cfg *Config) *Duration { return &cfg.HTTP.WriteTimeout }),
+		durationSetting("http-idle-timeout", "HTTP_IDLE_TIMEOUT", "http idle connection timeout",
+			func(cfg *Config) *Duration { return &cfg.HTTP.IdleTimeout }),
+		stringSetting("grpc-address", "GRPC_ADDRESS", "grpc server address, server is not started if empty",
+			func(cfg *Config) *string { return &cfg.GRPC.Address }),
+		stringSetting("admin-address", "ADMIN_ADDRESS", "admin http server address, server is not started if empty",
+			func(cfg *Config) *string { return &cfg.Admin.Address }),
+		stringSetting("admin-token", "ADMIN_TOKEN", "token of admin requests, sent in Authorization: Bearer header",
+			func(cfg *Config) *string { return &cfg.Admin.Token }),
+		{"cors-allowed-origins", "CORS_ALLOWED_ORIGINS", "comma separated origins allowed to make cross-origin requests", false, func(cfg *Config, v string) error {
+			cfg.CORS.AllowedOrigins = splitList(v)
+			return nil
+		}},
+		{"log-level", "LOG_LEVEL", "min log level: debug, info, warn or error", false, func(cfg *Config, v string) error {
+			cfg.Log.Level = strings.ToLower(v)
+			return nil
+		}},
+		stringSetting("outbox-publisher", "OUTBOX_PUBLISHER", "outbox publisher: stdout, file or memory",
+			func(cfg *Config) *string { return &cfg.Outbox.Publisher }),
+		stringSetting("outbox-file", "OUTBOX_FILE", "file path for file outbox publisher",
+			func(cfg *Config) *string { return &cfg.Outbox.File }),
+		boolSetting("feature-scheduler", "FEATURE_SCHEDULER", "execute scheduled transfers",
+			func(cfg *Config) *bool { return &cfg.Features.Scheduler }),
+		boolSetting("feature-webhooks", "FEATURE_WEBHOOKS", "enable webhooks",
+			func(cfg *Config) *bool { return &cfg.Features.Webhooks }),
+		boolSetting("feature-stream", "FEATURE_STREAM", "enable account events stream",
+			func(cfg *Config) *bool { return &cfg.Features.Stream }),
+		boolSetting("feature-idempotency", "FEATURE_IDEMPOTENCY", "enable Idempotency-Key header support",
+			func(cfg *Config) *bool { return &cfg.Features.Idempotency }),
+		boolSetting("feature-single-statement-transfers", "FEATURE_SINGLE_STATEMENT_TRANSFERS", "execute transfers by single statement in Postgres",
+			func(cfg *Config) *bool { return &cfg.Features.SingleStatementTransfers }),
+	}
+}
+
+// Flag value that records raw values, so flags can be applied after file and environment
+type recordedFlag struct {
+	setting *setting
+
+	values *[]flagValue
+
+	isBool bool
+}
+
+// Value of flag passed in command line
+type flagValue struct {
+	setting *setting
+
+	value string
+}
+
+func (f recordedFlag) String() string {
+	return ""
+}
+
+func (f recordedFlag) Set(value string) error {
+	*f.values = append(*f.values, flagValue{f.setting, value})
+	return nil
+}
+
+func (f recordedFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// Loads configuration. Sources are applied in order: defaults, configuration file, environment variables, flags,
+// so later sources override earlier ones. Configuration file is set by "-config" flag or CONFIG_FILE environment variable,
+// it can be in yaml (.yaml, .yml) or json format
+//	name - name of program, used in flags usage
+//	args - command line arguments without program name
+//	env  - function that returns value of environment variable, usually os.LookupEnv
+// Returns loaded and validated configuration
+func Load(name string, args []string, env func(string) (string, bool)) (Config, error) {
+	var cfg = Default()
+
+	// Flags are parsed first to get configuration file path, their values are applied last
+	var flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	var configFile = flags.String("config", "", "path of yaml or json configuration file")
+	var values = []flagValue{}
+	var all = settings()
+	for i := range all {
+		var s = &all[i]
+		flags.Var(recordedFlag{s, &values, s.isBool}, s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if flags.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	var path = *configFile
+	if path == "" {
+		path, _ = env(EnvConfigFile)
+	}
+
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, s := range all {
+		if value, ok := env(s.env); ok {
+			if err := s.set(&cfg, value); err != nil {
+				return cfg, fmt.Errorf("invalid value of environment variable %s: %s", s.env, err.Error())
+			}
+		}
+	}
+
+	for _, f := range values {
+		if err := f.setting.set(&cfg, f.value); err != nil {
+			return cfg, fmt.Errorf("invalid value of flag -%s: %s", f.setting.flag, err.Error())
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Loads configuration file over current configuration
+//	path - file path
+//	cfg  - configuration to update
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read configuration file: %s", err.Error())
+	}
+
+	// Yaml is converted to json, so both formats use the same field names and rules
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var document map[string]interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("unable to parse configuration file %s: %s", path, err.Error())
+		}
+
+		if data, err = json.Marshal(document); err != nil {
+			return fmt.Errorf("unable to parse configuration file %s: %s", path, err.Error())
+		}
+	case ".json":
+	default:
+		return fmt.Errorf("unsupported configuration file format [%s], expected .yaml, .yml or .json", filepath.Ext(path))
+	}
+
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("unable to parse configuration file %s: %s", path, err.Error())
+	}
+
+	return nil
+}
+
+func parseInt(value string, target *int) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*target = parsed
+	return nil
+}
+
+func parseBool(value string, target *bool) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*target = parsed
+	return nil
+}
+
+func parseDuration(value string, target *Duration) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*target = Duration(parsed)
+	return nil
+}
+
+// Splits comma separated list, empty items are skipped
+func splitList(value string) []string {
+	var result = []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
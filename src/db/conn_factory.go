@@ -1,11 +1,43 @@
 package db
 
-import "github.com/jackc/pgx"
+import (
+	"strings"
+	"time"
 
-// Initializes new pgx connection pool from connection string
+	"github.com/jackc/pgx"
+)
+
+// Pool of database connections. Db contexts are created on connections acquired from pool
+type ConnectionPool interface {
+	// Acquires connection and begins transaction on it
+	//	tranTimeout - transaction timeout
+	// Returns new DbContext object
+	CreateContext(tranTimeout time.Duration) (DbContext, error)
+
+	// Closes all connections of pool
+	Close()
+}
+
+// Scheme of connection string that selects SQLite database
+const sqliteScheme = "sqlite://"
+
+// Returns true if connection string selects SQLite database, i.e. has "sqlite://" scheme
 //	cs - connection string
+func IsSQLite(cs string) bool {
+	return strings.HasPrefix(strings.ToLower(cs), sqliteScheme)
+}
+
+// Initializes new connection pool from connection string. Database is selected by connection string scheme:
+// "sqlite://path/to/file.db" opens SQLite database, any other connection string is passed to pgx
+//	cs             - connection string
+//	poolSize       - max number of connections in pool, SQLite pool always has single connection
+//	acquireTimeout - time to wait for free connection, 0 - wait until connection is released
 // Returns connection pool object
-func NewConnectionPool(cs string) (*pgx.ConnPool, error) {
+func NewConnectionPool(cs string, poolSize int, acquireTimeout time.Duration) (ConnectionPool, error) {
+	if IsSQLite(cs) {
+		return newSqliteConnectionPool(cs[len(sqliteScheme):], acquireTimeout)
+	}
+
 	connCfg, err := pgx.ParseConnectionString(cs)
 	if err != nil {
 		return nil, err
@@ -13,8 +45,14 @@ func NewConnectionPool(cs string) (*pgx.ConnPool, error) {
 
 	poolCfg := pgx.ConnPoolConfig{
 		ConnConfig:     connCfg,
-		MaxConnections: 16,
+		MaxConnections: poolSize,
+		AcquireTimeout: acquireTimeout,
+	}
+
+	pool, err := pgx.NewConnPool(poolCfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return pgx.NewConnPool(poolCfg)
+	return pgxConnectionPool{pool}, nil
 }
@@ -22,6 +22,10 @@ type DbContext interface {
 	// Commits current transaction
 	Save() error
 
+	// Begins nested DbContext backed by savepoint of current transaction.
+	// Returned unit of work is DbContext too
+	Nested() (UnitOfWork, error)
+
 	// Executes sql query that is expected to return some data from database
 	// 	sql         - sql query
 	//  sqlParams   - sql parameters to be used with sql query
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx"
+
+	servErr "test/coins/errors"
+)
+
+// Postgres error code of unique constraint violation
+const pgUniqueViolation = "23505"
+
+// Translates error returned by database driver. Violation of unique constraint is returned as ErrUniqueViolation,
+// so repositories can detect duplicate rows inserted by concurrent transactions
+//	err      - error returned by database driver
+//	fallback - error returned if err is not violation of unique constraint
+func translateError(err error, fallback error) error {
+	var pgErr pgx.PgError
+	if (errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation) || isSQLiteUniqueViolation(err) {
+		return servErr.ErrUniqueViolation(err)
+	}
+
+	return fallback
+}
@@ -67,16 +67,26 @@ func (dbContext mockDbContext) Save() error {
 func (dbContext mockDbContext) Query(sql string, sqlParams []interface{}, mapper QueryMapper) error {
 	rows, err := dbContext.db.Query(sql, sqlParams...)
 	if err != nil {
-		return servErr.ErrDatabaseError(err)
+		return translateError(err, servErr.ErrDatabaseError(err))
 	}
 
-	return mapper(sqlRowsWrapper{rows})
+	err = mapper(sqlRowsWrapper{rows})
+	if err != nil {
+		return err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return translateError(err, servErr.ErrDatabaseError(err))
+	}
+
+	return nil
 }
 
 func (dbContext mockDbContext) Execute(sql string, sqlParams ...interface{}) (int64, error) {
 	tag, err := dbContext.db.Exec(sql, sqlParams...)
 	if err != nil {
-		return -1, servErr.ErrDatabaseError(err)
+		return -1, translateError(err, servErr.ErrDatabaseError(err))
 	}
 
 	rowsAffected, err := tag.RowsAffected()
@@ -86,3 +96,7 @@ func (dbContext mockDbContext) Execute(sql string, sqlParams ...interface{}) (in
 
 	return rowsAffected, nil
 }
+
+func (dbContext mockDbContext) Nested() (UnitOfWork, error) {
+	return createSavepoint(dbContext, 1)
+}
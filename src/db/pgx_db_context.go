@@ -23,14 +23,24 @@ type pgxDbContext struct {
 //	connPool    - connection pool to be used to acquire connections
 // 	tranTimeout - transaction timeout
 // Returns new DbContext object with acquired connection and initialized transaction,
-func CreateContext(connPool *pgx.ConnPool, tranTimeout time.Duration) (DbContext, error) {
+func CreateContext(connPool ConnectionPool, tranTimeout time.Duration) (DbContext, error) {
+	return connPool.CreateContext(tranTimeout)
+}
+
+// Connection pool of Postgres database
+type pgxConnectionPool struct {
+	pool *pgx.ConnPool
+}
+
+func (cp pgxConnectionPool) CreateContext(tranTimeout time.Duration) (DbContext, error) {
+	var connPool = cp.pool
 	conn, err := connPool.AcquireEx(context.Background())
 	if err != nil {
 		return nil, servErr.ErrDatabaseError(err)
 	}
 
 	// Making sure transaction will be interrupted by timeout
-	tranCtx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	tranCtx, ctxCancel := context.WithTimeout(context.Background(), tranTimeout)
 	opts := pgx.TxOptions{
 		IsoLevel: pgx.ReadCommitted,
 	}
@@ -50,6 +60,10 @@ func CreateContext(connPool *pgx.ConnPool, tranTimeout time.Duration) (DbContext
 	}, nil
 }
 
+func (cp pgxConnectionPool) Close() {
+	cp.pool.Close()
+}
+
 func (db pgxDbContext) Release() error {
 	// As per docs, should be save to call Rollback() even after Commit() call
 	var err = db.transaction.Rollback()
@@ -65,18 +79,29 @@ func (db pgxDbContext) Release() error {
 func (db pgxDbContext) Query(sql string, args []interface{}, mapper QueryMapper) error {
 	rows, err := db.transaction.Query(sql, args...)
 	if err != nil {
-		return err
+		return translateError(err, err)
 	}
 
 	defer rows.Close()
 
-	return mapper(rows)
+	err = mapper(rows)
+	if err != nil {
+		return err
+	}
+
+	// Error that stopped iteration is reported by rows, not by Next()
+	err = rows.Err()
+	if err != nil {
+		return translateError(err, servErr.ErrDatabaseError(err))
+	}
+
+	return nil
 }
 
 func (db pgxDbContext) Execute(sql string, args ...interface{}) (int64, error) {
 	tag, err := db.transaction.Exec(sql, args...)
 	if err != nil {
-		return -1, err
+		return -1, translateError(err, err)
 	}
 
 	return tag.RowsAffected(), nil
@@ -90,3 +115,7 @@ func (db pgxDbContext) Save() error {
 
 	return nil
 }
+
+func (db pgxDbContext) Nested() (UnitOfWork, error) {
+	return createSavepoint(db, 1)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"fmt"
+
+	servErr "test/coins/errors"
+)
+
+// DbContext nested into other one, backed by savepoint of its transaction
+type savepointDbContext struct {
+	parent DbContext
+
+	// Savepoint name, unique among savepoints that are active at the same time
+	name string
+
+	// Nesting depth, 1 for savepoint of root transaction
+	depth int
+
+	// Set when savepoint is released or rolled back, shared by copies of context
+	finished *bool
+}
+
+// Creates savepoint in transaction of db context
+//	parent - db context savepoint is created in
+//	depth  - nesting depth of savepoint
+// Returns nested db context
+func createSavepoint(parent DbContext, depth int) (DbContext, error) {
+	var name = fmt.Sprintf("uow_savepoint_%d", depth)
+	_, err := parent.Execute("SAVEPOINT " + name)
+	if err != nil {
+		return nil, servErr.ErrDatabaseError(err)
+	}
+
+	return savepointDbContext{parent, name, depth, new(bool)}, nil
+}
+
+func (db savepointDbContext) Release() error {
+	if *db.finished {
+		return nil
+	}
+
+	*db.finished = true
+	_, err := db.parent.Execute("ROLLBACK TO SAVEPOINT " + db.name)
+	if err != nil {
+		return servErr.ErrDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (db savepointDbContext) Save() error {
+	*db.finished = true
+	_, err := db.parent.Execute("RELEASE SAVEPOINT " + db.name)
+	if err != nil {
+		return servErr.ErrDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (db savepointDbContext) Nested() (UnitOfWork, error) {
+	return createSavepoint(db, db.depth+1)
+}
+
+func (db savepointDbContext) Query(sql string, sqlParams []interface{}, mapper QueryMapper) error {
+	return db.parent.Query(sql, sqlParams, mapper)
+}
+
+func (db savepointDbContext) Execute(sql string, sqlParams ...interface{}) (int64, error) {
+	return db.parent.Execute(sql, sqlParams...)
+}
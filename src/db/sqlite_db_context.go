@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	servErr "test/coins/errors"
+)
+
+// Name of database/sql driver used to open SQLite databases
+const sqliteDriver = "sqlite"
+
+// Connection pool of SQLite database. SQLite allows single writer at a time,
+// so pool has single connection and transactions are executed one by one
+type sqliteConnectionPool struct {
+	db *sql.DB
+
+	acquireTimeout time.Duration
+}
+
+// Opens SQLite database. Schema is created by migrations, the same as Postgres schema
+//	path           - path to database file, ":memory:" for in-memory database. Query parameters are passed to driver
+//	acquireTimeout - time to wait for free connection, 0 - wait until connection is released
+// Returns connection pool object
+func newSqliteConnectionPool(path string, acquireTimeout time.Duration) (ConnectionPool, error) {
+	if path == "" {
+		return nil, errors.New("path to SQLite database is required")
+	}
+
+	// Transactions take write lock when they begin, same as Postgres transactions that lock rows with FOR UPDATE
+	var separator = "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	sqlDb, err := sql.Open(sqliteDriver, path+separator+"_txlock=immediate&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	// In-memory database lives as long as its connection, so connection is never closed
+	sqlDb.SetMaxOpenConns(1)
+	sqlDb.SetMaxIdleConns(1)
+	sqlDb.SetConnMaxLifetime(0)
+
+	return sqliteConnectionPool{sqlDb, acquireTimeout}, nil
+}
+
+func (cp sqliteConnectionPool) CreateContext(tranTimeout time.Duration) (DbContext, error) {
+	var acquireCtx = context.Background()
+	if cp.acquireTimeout > 0 {
+		var acquireCancel context.CancelFunc
+		acquireCtx, acquireCancel = context.WithTimeout(acquireCtx, cp.acquireTimeout)
+		defer acquireCancel()
+	}
+
+	conn, err := cp.db.Conn(acquireCtx)
+	if err != nil {
+		return nil, servErr.ErrDatabaseError(err)
+	}
+
+	// Making sure transaction will be interrupted by timeout
+	tranCtx, ctxCancel := context.WithTimeout(context.Background(), tranTimeout)
+	tran, err := conn.BeginTx(tranCtx, nil)
+	if err != nil {
+		ctxCancel()
+		conn.Close()
+		return nil, servErr.ErrDatabaseError(err)
+	}
+
+	return sqliteDbContext{
+		connection:  conn,
+		transaction: tran,
+		ctxCancelFn: ctxCancel,
+	}, nil
+}
+
+func (cp sqliteConnectionPool) Close() {
+	cp.db.Close()
+}
+
+// DbContext of SQLite database. Queries written for Postgres are translated into SQLite dialect
+type sqliteDbContext struct {
+	connection *sql.Conn
+
+	transaction *sql.Tx
+
+	ctxCancelFn context.CancelFunc
+}
+
+func (db sqliteDbContext) Release() error {
+	defer db.connection.Close()
+	defer db.ctxCancelFn()
+
+	// Transaction is already done if it was committed or rolled back by timeout
+	var err = db.transaction.Rollback()
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return servErr.ErrDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (db sqliteDbContext) Save() error {
+	var err = db.transaction.Commit()
+	if err != nil {
+		return servErr.ErrDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (db sqliteDbContext) Query(sql string, sqlParams []interface{}, mapper QueryMapper) error {
+	rows, err := db.transaction.Query(sqliteQuery(sql), sqliteParams(sqlParams)...)
+	if err != nil {
+		return translateError(err, servErr.ErrDatabaseError(err))
+	}
+
+	defer rows.Close()
+
+	err = mapper(sqlRowsWrapper{rows})
+	if err != nil {
+		return err
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return translateError(err, servErr.ErrDatabaseError(err))
+	}
+
+	return nil
+}
+
+func (db sqliteDbContext) Execute(sql string, sqlParams ...interface{}) (int64, error) {
+	result, err := db.transaction.Exec(sqliteQuery(sql), sqliteParams(sqlParams)...)
+	if err != nil {
+		return -1, translateError(err, servErr.ErrDatabaseError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return -1, servErr.ErrDatabaseError(err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (db sqliteDbContext) Nested() (UnitOfWork, error) {
+	return createSavepoint(db, 1)
+}
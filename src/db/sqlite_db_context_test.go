@@ -0,0 +1,395 @@
+package db_test
+
+import (
+	"errors"
+	"test/coins/db"
+	"test/coins/migrations"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+const tranTimeout = time.Second * 5
+
+func setupPool(t *testing.T) db.ConnectionPool {
+	var pool = openPool(t)
+
+	// SQLite schema is created by the same migrations as Postgres schema
+	migrate(t, pool, loadMigrations(t))
+
+	return pool
+}
+
+// Opens connection pool to empty in-memory database
+func openPool(t *testing.T) db.ConnectionPool {
+	pool, err := db.NewConnectionPool("sqlite://:memory:", 1, time.Second)
+	if err != nil {
+		t.Fatalf("unable to open SQLite database: %s", err.Error())
+	}
+
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// Returns embedded migrations
+func loadMigrations(t *testing.T) []migrations.Migration {
+	all, err := migrations.Load()
+	if err != nil {
+		t.Fatalf("unable to load migrations: %s", err.Error())
+	}
+
+	return all
+}
+
+// Applies migrations to database
+func migrate(t *testing.T, pool db.ConnectionPool, all []migrations.Migration) {
+	_, err := migrations.NewMigrator(func() (db.DbContext, error) {
+		return db.CreateContext(pool, tranTimeout)
+	}, all).Up()
+	if err != nil {
+		t.Fatalf("unable to migrate SQLite database: %s", err.Error())
+	}
+}
+
+// Returns balance of account, read in separate transaction
+func readBalance(t *testing.T, pool db.ConnectionPool, accountNumber int64) int64 {
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	var balance int64
+	err = dbContext.Query(
+		"SELECT balance FROM public.accounts WHERE account_number = $1",
+		[]interface{}{accountNumber},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				t.Fatalf("account %d is not found", accountNumber)
+			}
+
+			return rows.Scan(&balance)
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to read balance: %s", err.Error())
+	}
+
+	return balance
+}
+
+func Test_Migrations_SQLite_SchemaIsCreatedWithDemoAccounts(t *testing.T) {
+	// Arrange
+	var pool = setupPool(t)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	// Act
+	var (
+		count int64
+		total int64
+	)
+	err = dbContext.Query(
+		"SELECT COUNT(*) FILTER (WHERE account_type = $1), SUM(balance) FROM public.accounts",
+		[]interface{}{"customer"},
+		func(rows db.QueryResultRows) error {
+			rows.Next()
+			return rows.Scan(&count, &total)
+		},
+	)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Query() was called: %s", err.Error())
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 demo customer accounts, got %d", count)
+	}
+
+	if total != 0 {
+		t.Fatalf("settlement account should balance demo accounts, total is %d", total)
+	}
+}
+
+func Test_Migrations_SQLite_AddedCheckConstraintIsEnforced(t *testing.T) {
+	// Arrange
+	var (
+		pool      = setupPool(t)
+		insertSql = "INSERT INTO public.transfer_schedules (schedule_id, source_account, dest_account, amount, status, next_run_at, start_at) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $6)"
+		runAt = time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(insertSql, uuid.New(), int64(1), int64(2), int64(100), "active", runAt)
+	if err != nil {
+		t.Fatalf("unable to insert schedule: %s", err.Error())
+	}
+
+	// Act
+	_, insertErr := dbContext.Execute(insertSql, uuid.New(), int64(1), int64(2), int64(0), "active", runAt)
+	_, updateErr := dbContext.Execute("UPDATE public.transfer_schedules SET amount = $1", int64(-1))
+
+	// Assert
+	if insertErr == nil {
+		t.Fatalf("schedule with zero amount should not be inserted")
+	}
+
+	if updateErr == nil {
+		t.Fatalf("schedule amount should not be updated to negative value")
+	}
+}
+
+func Test_Migrations_SQLite_SchedulesWithInvalidAmountAreCancelled(t *testing.T) {
+	// Arrange
+	var (
+		pool       = openPool(t)
+		all        = loadMigrations(t)
+		activeId   = uuid.New()
+		completeId = uuid.New()
+		runAt      = time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	)
+
+	// schedules with overflowed amounts were stored before amount check was added
+	var amountCheck = 0
+	for i, migration := range all {
+		if migration.Name == "schedule_amount_check" {
+			amountCheck = i
+		}
+	}
+	migrate(t, pool, all[:amountCheck])
+
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+
+	for id, status := range map[uuid.UUID]string{activeId: "active", completeId: "completed"} {
+		_, err = dbContext.Execute(
+			"INSERT INTO public.transfer_schedules (schedule_id, source_account, dest_account, amount, status, next_run_at) "+
+				"VALUES ($1, $2, $3, $4, $5, $6)",
+			id, int64(1), int64(2), int64(-5), status, runAt,
+		)
+		if err != nil {
+			t.Fatalf("unable to insert schedule: %s", err.Error())
+		}
+	}
+
+	err = dbContext.Save()
+	dbContext.Release()
+	if err != nil {
+		t.Fatalf("unable to save schedules: %s", err.Error())
+	}
+
+	// Act
+	migrate(t, pool, all)
+
+	// Assert
+	dbContext, err = db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	var statuses = map[string]string{}
+	err = dbContext.Query(
+		"SELECT schedule_id, status FROM public.transfer_schedules",
+		nil,
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var id, status string
+				err := rows.Scan(&id, &status)
+				if err != nil {
+					return err
+				}
+
+				statuses[id] = status
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error occured when Query() was called: %s", err.Error())
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("schedules with invalid amounts should be kept, got %d schedules", len(statuses))
+	}
+
+	if status := statuses[activeId.String()]; status != "cancelled" {
+		t.Fatalf("active schedule with invalid amount should be cancelled, got %s", status)
+	}
+
+	if status := statuses[completeId.String()]; status != "completed" {
+		t.Fatalf("completed schedule should keep its status, got %s", status)
+	}
+}
+
+func Test_SQLiteDbContext_PostgresQuery_IsTranslated(t *testing.T) {
+	// Arrange
+	var (
+		pool       = setupPool(t)
+		transferId = uuid.New()
+		createdAt  = time.Date(2026, 10, 5, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	// Act
+	_, err = dbContext.Execute(
+		"INSERT INTO public.transfers (transfer_id, amount, source_account, dest_account, metadata, created_at) VALUES ($1, $2, $3, $3, $4, $5)",
+		transferId, int64(100), int64(1), `{"source":"1"}`, createdAt,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error occured when Execute() was called: %s", err.Error())
+	}
+
+	var (
+		readId        uuid.UUID
+		readCreatedAt time.Time
+		source        int64
+		locked        bool
+	)
+	err = dbContext.Query(
+		"SELECT transfer_id, created_at, (metadata->>'source')::bigint, pg_try_advisory_xact_lock($2) FROM public.transfers "+
+			"WHERE transfer_id = $1 AND created_at < CURRENT_TIMESTAMP FOR UPDATE SKIP LOCKED",
+		[]interface{}{transferId, int64(42)},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				t.Fatalf("inserted transfer is not found")
+			}
+
+			return rows.Scan(&readId, &readCreatedAt, &source, &locked)
+		},
+	)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Query() was called: %s", err.Error())
+	}
+
+	if readId != transferId {
+		t.Fatalf("expected transfer id %s, got %s", transferId, readId)
+	}
+
+	if !readCreatedAt.Equal(createdAt) {
+		t.Fatalf("expected created at %s, got %s", createdAt, readCreatedAt)
+	}
+
+	if source != 1 || !locked {
+		t.Fatalf("json cast and advisory lock are not translated, got source %d, locked %t", source, locked)
+	}
+}
+
+func Test_SQLiteDbContext_DuplicateTransferId_ErrUniqueViolation(t *testing.T) {
+	// Arrange
+	var (
+		pool       = setupPool(t)
+		transferId = uuid.New()
+		insertSql  = "INSERT INTO public.transfers (transfer_id, amount, source_account, dest_account) VALUES ($1, $2, $3, $3)"
+	)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(insertSql, transferId, int64(100), int64(1))
+	if err != nil {
+		t.Fatalf("unable to insert transfer: %s", err.Error())
+	}
+
+	// Act
+	_, err = dbContext.Execute(insertSql, transferId, int64(200), int64(2))
+
+	// Assert
+	if !errors.Is(err, servErr.ErrUniqueViolation(nil)) {
+		t.Fatalf("expected unique violation error, got %v", err)
+	}
+}
+
+func Test_SQLiteDbContext_Release_UncommittedChangesRolledBack(t *testing.T) {
+	// Arrange
+	var pool = setupPool(t)
+	var before = readBalance(t, pool, 1)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+
+	_, err = dbContext.Execute("UPDATE public.accounts SET balance = balance + $1 WHERE account_number = $2", int64(100), int64(1))
+	if err != nil {
+		t.Fatalf("unexpected error occured when Execute() was called: %s", err.Error())
+	}
+
+	// Act
+	err = dbContext.Release()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Release() was called: %s", err.Error())
+	}
+
+	if balance := readBalance(t, pool, 1); balance != before {
+		t.Fatalf("balance should not be changed by rolled back transaction, expected %d, got %d", before, balance)
+	}
+}
+
+func Test_SQLiteDbContext_NestedReleased_OnlyNestedChangesRolledBack(t *testing.T) {
+	// Arrange
+	var pool = setupPool(t)
+	var before = readBalance(t, pool, 1)
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+
+	var update = "UPDATE public.accounts SET balance = balance + $1 WHERE account_number = $2"
+	_, err = dbContext.Execute(update, int64(100), int64(1))
+	if err != nil {
+		t.Fatalf("unexpected error occured when Execute() was called: %s", err.Error())
+	}
+
+	nested, err := dbContext.Nested()
+	if err != nil {
+		t.Fatalf("unexpected error occured when Nested() was called: %s", err.Error())
+	}
+
+	_, err = nested.(db.DbContext).Execute(update, int64(50), int64(1))
+	if err != nil {
+		t.Fatalf("unexpected error occured when Execute() was called: %s", err.Error())
+	}
+
+	// Act
+	err = nested.Release()
+	if err != nil {
+		t.Fatalf("unexpected error occured when Release() was called: %s", err.Error())
+	}
+
+	err = dbContext.Save()
+	dbContext.Release()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Save() was called: %s", err.Error())
+	}
+
+	if balance := readBalance(t, pool, 1); balance != before+100 {
+		t.Fatalf("only changes of nested context should be rolled back, expected balance %d, got %d", before+100, balance)
+	}
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format of timestamps stored in SQLite database. Timestamps are kept as UTC text with millisecond precision,
+// so they are compared in the same order as time values
+const sqliteTimeFormat = "2006-01-02 15:04:05.000"
+
+// Rule that rewrites Postgres construction into SQLite one
+type sqliteRewriteRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Rules that translate queries written for Postgres into SQLite dialect. Order matters,
+// placeholders are rewritten last, because other rules keep them in their replacements
+var sqliteRewriteRules = []sqliteRewriteRule{
+	// SQLite has no schemas, tables are created in main database
+	{regexp.MustCompile(`(?i)\bpublic\.`), ""},
+
+	// Migrations are applied to SQLite database as well. Storage parameters, index methods and options
+	// that SQLite doesn't have are dropped
+	{regexp.MustCompile(`(?i)\s+TABLESPACE\s+pg_default\b`), ""},
+	{regexp.MustCompile(`(?i)\s+USING\s+btree\b`), ""},
+	{regexp.MustCompile(`(?i)\s+NULLS\s+(FIRST|LAST)\b`), ""},
+	{regexp.MustCompile(`(?i)\s+INCLUDE\s*\([^()]*\)`), ""},
+	{regexp.MustCompile(`(?i)\s+MATCH\s+SIMPLE\b`), ""},
+
+	// Integer column that is primary key of table is alias of rowid, so its values are generated by SQLite
+	{regexp.MustCompile(`(?i)\bbigint\s+NOT\s+NULL\s+GENERATED\s+ALWAYS\s+AS\s+IDENTITY\s*\([^()]*\)`), "INTEGER NOT NULL"},
+
+	// Driver converts values of columns declared as timestamp into time
+	{regexp.MustCompile(`(?i)\btimestamp\s+without\s+time\s+zone\b`), "timestamp"},
+
+	// Columns are added and dropped without existence check, migrations are recorded, so they are applied once
+	{regexp.MustCompile(`(?i)\b(ADD|DROP)\s+COLUMN\s+IF\s+(NOT\s+)?EXISTS\b`), "${1} COLUMN"},
+
+	// Constraints and nullability of existing columns can't be changed in SQLite. Check constraints and
+	// not null constraints added to existing table are emulated by triggers that abort writes of violating rows.
+	// Like constraints added as NOT VALID in Postgres, triggers don't check rows that already exist
+	{
+		regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(\w+)\s+ADD\s+CONSTRAINT\s+(\w+)\s+CHECK\s*\(([^;]*)\)(\s+NOT\s+VALID)?\s*;`),
+		sqliteCheckTriggers("${1}", "${2}", "${3}"),
+	},
+	{
+		regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+SET\s+NOT\s+NULL\s*;`),
+		sqliteCheckTriggers("${1}", "${1}_${2}_not_null", "${2} IS NOT NULL"),
+	},
+	{
+		regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+\w+\s+DROP\s+CONSTRAINT\s+(IF\s+EXISTS\s+)?(\w+)\s*;`),
+		sqliteDropCheckTriggers("${2}"),
+	},
+	{
+		regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(\w+)\s+ALTER\s+COLUMN\s+(\w+)\s+DROP\s+NOT\s+NULL\s*;`),
+		sqliteDropCheckTriggers("${1}_${2}_not_null"),
+	},
+
+	// Column referenced by trigger can't be dropped, so not null triggers are dropped together with column
+	{
+		regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(\w+)\s+DROP\s+COLUMN\s+(\w+)\s*;`),
+		sqliteDropCheckTriggers("${1}_${2}_not_null") + "\nALTER TABLE ${1} DROP COLUMN ${2};",
+	},
+
+	// Other constraints and column changes are skipped, they are declared when table is created
+	{regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+\w+\s+(ADD\s+CONSTRAINT|ALTER\s+COLUMN)\b[^;]*;`), ""},
+
+	// SQLite requires AS keyword before alias of updated table
+	{regexp.MustCompile(`(?i)\bUPDATE\s+(\w+)\s+(\w+)\s+SET\b`), "UPDATE ${1} AS ${2} SET"},
+
+	// Columns of VALUES list can't be named by alias, SQLite names them column1, column2, ...
+	// Only single column lists are translated
+	{
+		regexp.MustCompile(`(?i)\(\s*VALUES\s+([^;]*?)\)\s+AS\s+(\w+)\s*\(\s*(\w+)\s*\)`),
+		"(SELECT column1 AS ${3} FROM (VALUES ${1})) AS ${2}",
+	},
+
+	// Default value should be parenthesized if it is expression
+	{regexp.MustCompile(`(?i)\bDEFAULT\s+CURRENT_TIMESTAMP\b`), "DEFAULT (CURRENT_TIMESTAMP)"},
+
+	// SQLite locks whole database for writing, transactions are begun as IMMEDIATE, so row locks are not needed
+	{regexp.MustCompile(`(?i)\s+FOR\s+UPDATE(\s+OF\s+\w+(\s*,\s*\w+)*)?(\s+SKIP\s+LOCKED|\s+NOWAIT)?`), ""},
+
+	// Advisory locks are always acquired, as transactions are serialized anyway
+	{regexp.MustCompile(`(?i)\bpg_(try_)?advisory_xact_lock\(([^()]*)\)`), "(${2} IS NOT NULL)"},
+
+	// Casts of json values
+	{regexp.MustCompile(`(?i)\(([^()]*)\)::(bigint|integer|int)\b`), "CAST(${1} AS INTEGER)"},
+
+	// Current time in format of stored timestamps
+	{regexp.MustCompile(`(?i)\bCURRENT_TIMESTAMP\b`), "strftime('%Y-%m-%d %H:%M:%f', 'now')"},
+
+	// Numbered placeholders $1, $2, ... become ?1, ?2, ...
+	{regexp.MustCompile(`\$(\d+)`), "?${1}"},
+}
+
+// Builds replacement that creates triggers checking condition on inserted and updated rows of table.
+// Condition is evaluated against stored row, so it refers columns the same way as check constraint
+//	table     - name of table
+//	name      - name of constraint, triggers are named after it
+//	condition - condition that rows should satisfy
+// Returns replacement
+func sqliteCheckTriggers(table string, name string, condition string) string {
+	var trigger = func(event string) string {
+		return "CREATE TRIGGER IF NOT EXISTS " + name + "_" + strings.ToLower(event) +
+			" AFTER " + event + " ON " + table +
+			" WHEN EXISTS (SELECT 1 FROM " + table + " WHERE rowid = NEW.rowid AND NOT (" + condition + "))" +
+			" BEGIN SELECT RAISE(ABORT, 'CHECK constraint failed: " + name + "'); END;"
+	}
+
+	return trigger("INSERT") + "\n" + trigger("UPDATE")
+}
+
+// Builds replacement that drops triggers created for constraint by sqliteCheckTriggers
+//	name - name of constraint
+// Returns replacement
+func sqliteDropCheckTriggers(name string) string {
+	return "DROP TRIGGER IF EXISTS " + name + "_insert;\nDROP TRIGGER IF EXISTS " + name + "_update;"
+}
+
+// Queries that were already translated, service queries are built from a small set of constant strings
+var sqliteQueries sync.Map
+
+// Translates query written for Postgres into SQLite dialect
+//	sql - Postgres query
+// Returns SQLite query
+func sqliteQuery(sql string) string {
+	if query, ok := sqliteQueries.Load(sql); ok {
+		return query.(string)
+	}
+
+	var query = sql
+	for _, rule := range sqliteRewriteRules {
+		query = rule.pattern.ReplaceAllString(query, rule.replacement)
+	}
+
+	sqliteQueries.Store(sql, query)
+	return query
+}
+
+// Converts query parameters to values stored in SQLite database. Time values are stored as UTC text,
+// uuid values are stored as text by their driver.Valuer implementation
+//	params - query parameters
+// Returns converted parameters
+func sqliteParams(params []interface{}) []interface{} {
+	var result = make([]interface{}, len(params))
+	for i, param := range params {
+		switch value := param.(type) {
+		case time.Time:
+			result[i] = value.UTC().Format(sqliteTimeFormat)
+		case *time.Time:
+			if value != nil {
+				result[i] = value.UTC().Format(sqliteTimeFormat)
+			}
+		default:
+			result[i] = param
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,16 @@
+package db
+
+import (
+	"errors"
+
+	// Pure Go SQLite driver, so SQLite backend doesn't require cgo
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Checks if error returned by SQLite driver is violation of unique constraint
+//	err - error returned by driver
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
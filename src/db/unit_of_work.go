@@ -0,0 +1,69 @@
+package db
+
+import "context"
+
+// Unit of work groups repository operations into single transaction. Changes made in scope of unit of work
+// become visible to other units of work only after it is saved. DbContext is unit of work of Postgres backend,
+// other backends (for example in-memory) provide their own implementations
+type UnitOfWork interface {
+	// Releases unit of work, rolling it back if it was not saved
+	Release() error
+
+	// Commits unit of work
+	Save() error
+
+	// Begins unit of work nested into current one, backed by savepoint. Saving nested unit of work keeps its changes
+	// as part of current one, releasing it without saving rolls back only changes made after it was begun
+	Nested() (UnitOfWork, error)
+}
+
+// Key of unit of work in context
+type unitOfWorkKey struct{}
+
+// Returns copy of context that carries unit of work, so services called with it join the unit of work
+//	ctx - parent context
+//	uow - unit of work
+func WithUnitOfWork(ctx context.Context, uow UnitOfWork) context.Context {
+	return context.WithValue(ctx, unitOfWorkKey{}, uow)
+}
+
+// Returns unit of work carried by context
+//	ctx - context
+// Returns unit of work and flag if context carries it
+func UnitOfWorkFrom(ctx context.Context) (UnitOfWork, bool) {
+	uow, ok := ctx.Value(unitOfWorkKey{}).(UnitOfWork)
+	return uow, ok
+}
+
+// Joins unit of work carried by context as nested one, or begins new unit of work if context does not carry it
+//	ctx   - context
+//	begin - function that begins new unit of work
+// Returns unit of work that should be saved and released by caller
+func JoinOrBegin(ctx context.Context, begin func() (UnitOfWork, error)) (UnitOfWork, error) {
+	if uow, ok := UnitOfWorkFrom(ctx); ok {
+		return uow.Nested()
+	}
+
+	return begin()
+}
+
+// Runs function inside unit of work. Unit of work is saved if function succeeds and rolled back otherwise.
+// Services called with context passed to function join the unit of work
+//	ctx   - context, if it carries unit of work, function runs in unit of work nested into it
+//	begin - function that begins new unit of work
+//	fn    - function to run
+func RunInUnitOfWork(ctx context.Context, begin func() (UnitOfWork, error), fn func(ctx context.Context) error) error {
+	uow, err := JoinOrBegin(ctx, begin)
+	if err != nil {
+		return err
+	}
+
+	defer uow.Release()
+
+	err = fn(WithUnitOfWork(ctx, uow))
+	if err != nil {
+		return err
+	}
+
+	return uow.Save()
+}
@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Definition of error kind in API responses
+type ErrorDefinition struct {
+	// Stable error code, returned to clients. Clients should use it instead of error message
+	Code string
+
+	// HTTP status code of response
+	Status int
+
+	// Short human-readable summary of error kind
+	Title string
+}
+
+// Definition of service errors with kind that is not registered in catalog
+var unknownServiceError = ErrorDefinition{"bad_request", http.StatusBadRequest, "Request can't be processed"}
+
+// Definition of errors that are not service errors
+var internalError = ErrorDefinition{"internal_error", http.StatusInternalServerError, "Internal server error"}
+
+// Catalog of error kinds, each package registers its own error kinds
+var catalog = struct {
+	sync.RWMutex
+
+	definitions map[int]ErrorDefinition
+}{
+	definitions: map[int]ErrorDefinition{
+		ErrorKindDB:              {"database_error", http.StatusInternalServerError, "Database error"},
+		ErrorKindInvalidRequest:  {"invalid_request", http.StatusBadRequest, "Invalid request"},
+		ErrorKindValidation:      {"validation_failed", http.StatusUnprocessableEntity, "Request validation failed"},
+		ErrorKindRequestTooLarge: {"request_too_large", http.StatusRequestEntityTooLarge, "Request body is too large"},
+		ErrorKindUniqueViolation: {"conflict", http.StatusConflict, "Resource already exists"},
+	},
+}
+
+// Registers error kind in catalog. Should be called from package init function
+//	kind       - error kind
+//	definition - error definition
+func RegisterKind(kind int, definition ErrorDefinition) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	if _, ok := catalog.definitions[kind]; ok {
+		panic("error kind is already registered: " + definition.Code)
+	}
+
+	catalog.definitions[kind] = definition
+}
+
+// Returns definition of error
+//	err - any error
+func Lookup(err error) ErrorDefinition {
+	svcErr, ok := err.(ServiceError)
+	if !ok {
+		return internalError
+	}
+
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	definition, ok := catalog.definitions[svcErr.Kind()]
+	if !ok {
+		return unknownServiceError
+	}
+
+	return definition
+}
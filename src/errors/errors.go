@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Universal error type used to distinct all errors returned by services
 type ServiceError struct {
 	// error message
@@ -10,8 +15,15 @@ type ServiceError struct {
 
 	// error kind. Used to distinct between reasons error occured
 	kind int
+
+	// additional error details returned to client, if any.
+	// Stored by pointer, so ServiceError values stay comparable
+	details *Details
 }
 
+// Additional error details, for example name of exceeded limit
+type Details map[string]interface{}
+
 // Retuns string message of current error
 func (err ServiceError) Error() string {
 	return err.message
@@ -27,6 +39,15 @@ func (err ServiceError) Unwrap() error {
 	return err.innerError
 }
 
+// Returns additional error details, nil if there are no details
+func (err ServiceError) Details() Details {
+	if err.details == nil {
+		return nil
+	}
+
+	return *err.details
+}
+
 // Checks if current error of the same type as target error
 //	target - target error
 func (err ServiceError) Is(target error) bool {
@@ -45,16 +66,88 @@ func (err ServiceError) Is(target error) bool {
 // Error kind - DB error. Used to wrap around errors, returned by DB driver
 const ErrorKindDB int = 1
 
+// Error kind - invalid request. Used when request route parameters or body can't be decoded
+const ErrorKindInvalidRequest int = 2
+
+// Error kind - validation error. Used when request fields have invalid values
+const ErrorKindValidation int = 3
+
+// Error kind - request too large. Used when request body exceeds size limit
+const ErrorKindRequestTooLarge int = 4
+
+// Error kind - unique violation. Used when database rejects row because of unique constraint
+const ErrorKindUniqueViolation int = 5
+
+// Error of single request field
+type FieldError struct {
+	// Field name, as it is named in request
+	Field string `json:"field"`
+
+	// What is wrong with field value
+	Message string `json:"message"`
+}
+
 // Returns new service error
 //	message    - error message
 //	innerError - inner error, if any
 //	kind       - error kind
 // Returns created service error
 func NewServiceError(message string, innerError error, kind int) error {
-	return ServiceError{message, innerError, kind}
+	return ServiceError{message, innerError, kind, nil}
+}
+
+// Returns new service error with additional details
+//	message    - error message
+//	innerError - inner error, if any
+//	kind       - error kind
+//	details    - additional error details returned to client
+// Returns created service error
+func NewServiceErrorWithDetails(message string, innerError error, kind int, details Details) error {
+	return ServiceError{message, innerError, kind, &details}
 }
 
 // Returns new service error wrapping around database error
 func ErrDatabaseError(dbError error) error {
 	return NewServiceError("error occured when trying to work with database", dbError, ErrorKindDB)
 }
+
+// Returns new service error for row rejected by unique constraint of database
+//	dbError - error returned by database driver
+// Returns created service error
+func ErrUniqueViolation(dbError error) error {
+	return NewServiceError("row with the same unique key already exists", dbError, ErrorKindUniqueViolation)
+}
+
+// Returns new service error for request that can't be decoded
+//	reason     - what is wrong with request
+//	innerError - decoding error, if any
+// Returns created service error
+func ErrInvalidRequest(reason string, innerError error) error {
+	var message = "invalid request: " + reason
+	if innerError != nil {
+		message += ": " + innerError.Error()
+	}
+
+	return NewServiceError(message, innerError, ErrorKindInvalidRequest)
+}
+
+// Returns new service error for request with invalid field values
+//	fields - errors of request fields
+// Returns created service error, field errors are returned to client in "fields" detail
+func ErrValidation(fields []FieldError) error {
+	var messages = make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+
+	var message = "request validation failed: " + strings.Join(messages, ", ")
+	return NewServiceErrorWithDetails(message, nil, ErrorKindValidation, Details{"fields": fields})
+}
+
+// Returns new service error for request with too large body
+//	limit - max body size in bytes
+// Returns created service error
+func ErrRequestTooLarge(limit int64) error {
+	var message = fmt.Sprintf("request body should not be larger than %d bytes", limit)
+	return NewServiceErrorWithDetails(message, nil, ErrorKindRequestTooLarge, Details{"limit": limit})
+}
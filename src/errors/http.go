@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Content type of error responses
+const ProblemContentType = "application/problem+json"
+
+// Error response body, as described in RFC 7807
+type Problem struct {
+	// URI that identifies error kind
+	Type string `json:"type"`
+
+	// Short human-readable summary of error kind
+	Title string `json:"title"`
+
+	// HTTP status code
+	Status int `json:"status"`
+
+	// Human-readable explanation of this error
+	Detail string `json:"detail"`
+
+	// Stable error code
+	Code string `json:"code"`
+
+	// Additional error details, if any
+	Details Details `json:"details,omitempty"`
+
+	// The same as detail. Kept for clients that read error message from previous response format
+	Error string `json:"error"`
+}
+
+// Creates problem for error
+//	err - any error
+// Returns created problem
+func NewProblem(err error) Problem {
+	// Decoding errors that were not wrapped by decoders are still client errors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		err = ErrInvalidRequest("invalid request body", err)
+	}
+
+	var definition = Lookup(err)
+	var problem = Problem{
+		Type:   "urn:coins:error:" + definition.Code,
+		Title:  definition.Title,
+		Status: definition.Status,
+		Detail: err.Error(),
+		Code:   definition.Code,
+		Error:  err.Error(),
+	}
+
+	if svcErr, ok := err.(ServiceError); ok {
+		problem.Details = svcErr.Details()
+	}
+
+	if definition.Status == http.StatusInternalServerError {
+		// Internal errors can contain database details, that should not be shown to clients
+		problem.Detail = definition.Title
+		problem.Error = definition.Title
+	}
+
+	return problem
+}
+
+// Writes error response in application/problem+json format. Used as error encoder by http transports
+//	err - error to write
+//	w   - response writer
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
+	var problem = NewProblem(err)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
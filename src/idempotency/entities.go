@@ -0,0 +1,37 @@
+package idempotency
+
+// Header with client-generated key that identifies request. Requests with the same key are executed once
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// Header that is set on responses replayed from idempotency store
+const HeaderReplayed = "Idempotent-Replayed"
+
+// Max length of idempotency key
+const MaxKeyLength = 255
+
+// Max size of request body that can be used with idempotency key
+const MaxRequestBodySize int64 = 1024 * 1024
+
+// Request with key is being executed
+const statusInProgress = "in_progress"
+
+// Request with key is executed, its response is stored
+const statusCompleted = "completed"
+
+// Request that was already executed with the same idempotency key
+type storedRequest struct {
+	// Fingerprint of request: hash of method, path and body
+	fingerprint string
+
+	// Request status. Can have values "in_progress" or "completed"
+	status string
+
+	// Response status code, if request is completed
+	responseStatus int
+
+	// Response content type, if request is completed
+	contentType string
+
+	// Response body, if request is completed
+	responseBody []byte
+}
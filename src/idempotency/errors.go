@@ -0,0 +1,32 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	servErr "test/coins/errors"
+)
+
+const (
+	ErrKindInvalidKey int = 60 + iota
+	ErrKindKeyReused
+	ErrKindRequestInProgress
+)
+
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindInvalidKey, servErr.ErrorDefinition{Code: "invalid_idempotency_key", Status: http.StatusBadRequest, Title: "Invalid idempotency key"})
+	servErr.RegisterKind(ErrKindKeyReused, servErr.ErrorDefinition{Code: "idempotency_key_reused", Status: http.StatusUnprocessableEntity, Title: "Idempotency key is used by other request"})
+	servErr.RegisterKind(ErrKindRequestInProgress, servErr.ErrorDefinition{Code: "idempotent_request_in_progress", Status: http.StatusConflict, Title: "Request with the same idempotency key is in progress"})
+}
+
+// Error that is expected when idempotency key is empty or too long
+var ErrInvalidKey = servErr.NewServiceError(
+	fmt.Sprintf("idempotency key should not be longer than %d characters", MaxKeyLength), nil, ErrKindInvalidKey)
+
+// Error that is expected when idempotency key was already used with different request
+var ErrKeyReused = servErr.NewServiceError(
+	"idempotency key was already used with different request", nil, ErrKindKeyReused)
+
+// Error that is expected when request with the same idempotency key is not completed yet
+var ErrRequestInProgress = servErr.NewServiceError(
+	"request with the same idempotency key is in progress, please retry later", nil, ErrKindRequestInProgress)
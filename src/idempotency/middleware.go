@@ -0,0 +1,196 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	servErr "test/coins/errors"
+)
+
+// Creates middleware that executes mutating requests with the same Idempotency-Key header only once.
+// Response of completed request is stored and replayed on retries, concurrent duplicates are rejected
+// with "Conflict" error. Responses with server errors are not stored, so request can be retried with the same key.
+// If response of executed request can't be stored, server error is returned and key stays in progress until it times out
+// Requests without key and safe requests (GET, HEAD, OPTIONS) are passed as is
+//	store  - idempotency store
+//	logger - logger
+// Returns function that wraps handler with middleware
+func Middleware(store *Store, logger kitlog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key = r.Header.Get(HeaderIdempotencyKey)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			if len(key) > MaxKeyLength {
+				servErr.EncodeError(ctx, ErrInvalidKey, w)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, MaxRequestBodySize+1))
+			if err != nil {
+				servErr.EncodeError(ctx, servErr.ErrInvalidRequest("failed to read request body", err), w)
+				return
+			}
+
+			if int64(len(body)) > MaxRequestBodySize {
+				servErr.EncodeError(ctx, servErr.ErrRequestTooLarge(MaxRequestBodySize), w)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var requestFingerprint = fingerprint(r, body)
+			startedAt, stored, err := store.begin(key, requestFingerprint)
+			if err != nil {
+				servErr.EncodeError(ctx, err, w)
+				return
+			}
+
+			if stored != nil {
+				replay(ctx, w, stored, requestFingerprint)
+				return
+			}
+
+			// Key is released if handler panics, as request was not completed
+			var recorder = newResponseRecorder()
+			var handled = false
+			defer func() {
+				if !handled {
+					releaseKey(store, key, requestFingerprint, startedAt, logger)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+			handled = true
+			// Handler that writes nothing responds with "OK"
+			recorder.WriteHeader(http.StatusOK)
+
+			// Key is released only if handler failed, so request can be retried with the same key
+			if recorder.status >= http.StatusInternalServerError {
+				releaseKey(store, key, requestFingerprint, startedAt, logger)
+				recorder.writeTo(w)
+				return
+			}
+
+			// Request was executed, but its response can't be replayed. Key is kept, so retries are rejected
+			// as requests in progress instead of being executed again, until key lock times out
+			err = store.complete(key, requestFingerprint, startedAt, recorder.status, recorder.header.Get("Content-Type"), recorder.body.Bytes())
+			if err != nil {
+				level.Error(logger).Log("msg", "failed to store idempotent response", "key", key, "err", err)
+				servErr.EncodeError(ctx, err, w)
+				return
+			}
+
+			recorder.writeTo(w)
+		})
+	}
+}
+
+// Releases key of request that was not completed, error is logged as response is already decided
+//	store       - idempotency store
+//	key         - idempotency key
+//	fingerprint - fingerprint of request
+//	startedAt   - time request was started at
+//	logger      - logger
+func releaseKey(store *Store, key string, fingerprint string, startedAt time.Time, logger kitlog.Logger) {
+	err := store.release(key, fingerprint, startedAt)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to release idempotency key", "key", key, "err", err)
+	}
+}
+
+// Writes response to request that was already started with the same key
+//	ctx         - request context
+//	w           - response writer
+//	stored      - stored request
+//	fingerprint - fingerprint of current request
+func replay(ctx context.Context, w http.ResponseWriter, stored *storedRequest, fingerprint string) {
+	if stored.fingerprint != fingerprint {
+		servErr.EncodeError(ctx, ErrKeyReused, w)
+		return
+	}
+
+	if stored.status != statusCompleted {
+		servErr.EncodeError(ctx, ErrRequestInProgress, w)
+		return
+	}
+
+	if stored.contentType != "" {
+		w.Header().Set("Content-Type", stored.contentType)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(stored.responseStatus)
+	w.Write(stored.responseBody)
+}
+
+// Checks if requests with method can change state
+//	method - http method
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+// Calculates fingerprint of request, so key reused with different request can be detected
+//	r    - request
+//	body - request body
+// Returns hex encoded SHA-256 of request method, url and body
+func fingerprint(r *http.Request, body []byte) string {
+	var hash = sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Response writer that buffers response, so it can be stored before it is sent
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}}
+}
+
+func (recorder *responseRecorder) Header() http.Header {
+	return recorder.header
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.WriteHeader(http.StatusOK)
+	return recorder.body.Write(data)
+}
+
+// Writes buffered response
+//	w - response writer
+func (recorder *responseRecorder) writeTo(w http.ResponseWriter) {
+	for name, values := range recorder.header {
+		w.Header()[name] = values
+	}
+
+	w.WriteHeader(recorder.status)
+	w.Write(recorder.body.Bytes())
+}
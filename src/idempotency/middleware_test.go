@@ -0,0 +1,369 @@
+package idempotency_test
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test/coins/db"
+	"test/coins/idempotency"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	kitlog "github.com/go-kit/log"
+)
+
+const (
+	testKey  = "5f0c1a52-7a4e-4e0e-9d51-0e6b8a4f1a2b"
+	testPath = "/api/v1/accounts"
+	testBody = `{"creditLimit":100}`
+)
+
+var storedColumns = []string{"fingerprint", "status", "response_status", "content_type", "response_body"}
+
+// Creates middleware with handler that counts calls, each created db context is set up by next setup function
+func setupMiddleware(handler http.HandlerFunc, setupMocks ...func(mock sqlmock.Sqlmock)) http.Handler {
+	var created = 0
+	var store = idempotency.NewStore(func() (db.DbContext, error) {
+		var setupMock = setupMocks[created]
+		created++
+		return db.CreateMockDbContext(setupMock)
+	}, time.Hour, time.Minute)
+
+	return idempotency.Middleware(store, kitlog.NewNopLogger())(handler)
+}
+
+func createdHandler(calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}
+}
+
+func fingerprint(method string, path string, body string) string {
+	var hash = sha256.Sum256([]byte(method + " " + path + "\n" + body))
+	return hex.EncodeToString(hash[:])
+}
+
+func newRequest(method string, body string) *http.Request {
+	var req = httptest.NewRequest(method, testPath, strings.NewReader(body))
+	req.Header.Set(idempotency.HeaderIdempotencyKey, testKey)
+	return req
+}
+
+// Matches any time and keeps it, so the same time can be expected later
+type capturedTime struct {
+	value *time.Time
+}
+
+func (c capturedTime) Match(v driver.Value) bool {
+	value, ok := v.(time.Time)
+	if ok {
+		*c.value = value
+	}
+
+	return ok
+}
+
+// Matches time that was kept by capturedTime
+type sameTime struct {
+	value *time.Time
+}
+
+func (s sameTime) Match(v driver.Value) bool {
+	value, ok := v.(time.Time)
+	return ok && value.Equal(*s.value)
+}
+
+// Returns mock setup for key that is already used by other request
+func keyUsed(dbMock *sqlmock.Sqlmock, rows *sqlmock.Rows) func(mock sqlmock.Sqlmock) {
+	return func(mock sqlmock.Sqlmock) {
+		*dbMock = mock
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO public.idempotency_keys").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT .* FROM public.idempotency_keys WHERE key = \\$1").
+			WithArgs(testKey).
+			WillReturnRows(rows)
+	}
+}
+
+func problemCode(t *testing.T, resp *httptest.ResponseRecorder) string {
+	var problem map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+
+	code, _ := problem["code"].(string)
+	return code
+}
+
+func Test_Middleware_RequestWithoutKey_PassesThrough(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = setupMiddleware(createdHandler(&calls))
+	var req = httptest.NewRequest(http.MethodPost, testPath, strings.NewReader(testBody))
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, req)
+
+	// Assert
+	if calls != 1 || resp.Code != http.StatusCreated {
+		t.Errorf("request expected to be passed to handler, calls: %d, status: %d", calls, resp.Code)
+	}
+}
+
+func Test_Middleware_GetRequestWithKey_PassesThrough(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = setupMiddleware(createdHandler(&calls))
+	var req = newRequest(http.MethodGet, "")
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, req)
+
+	// Assert
+	if calls != 1 || resp.Code != http.StatusCreated {
+		t.Errorf("request expected to be passed to handler, calls: %d, status: %d", calls, resp.Code)
+	}
+}
+
+func Test_Middleware_NewKey_ResponseIsStored(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var beginMock, completeMock sqlmock.Sqlmock
+	var handler = setupMiddleware(createdHandler(&calls),
+		func(mock sqlmock.Sqlmock) {
+			beginMock = mock
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO public.idempotency_keys .* ON CONFLICT \\(key\\) DO UPDATE").
+				WithArgs(testKey, fingerprint(http.MethodPost, testPath, testBody), "in_progress",
+					sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+		func(mock sqlmock.Sqlmock) {
+			completeMock = mock
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE public.idempotency_keys SET status = \\$1").
+				WithArgs("completed", http.StatusCreated, "application/json", []byte(testBody), testKey, "in_progress",
+					fingerprint(http.MethodPost, testPath, testBody), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+	)
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if calls != 1 {
+		t.Errorf("handler expected to be called once, but was called %d times", calls)
+	}
+
+	if resp.Code != http.StatusCreated || resp.Body.String() != testBody {
+		t.Errorf("handler response expected, but got status %d and body %s", resp.Code, resp.Body.String())
+	}
+
+	if resp.Header().Get(idempotency.HeaderReplayed) != "" {
+		t.Errorf("response of executed request should not be marked as replayed")
+	}
+
+	if err := beginMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations on begin: %s", err)
+	}
+
+	if err := completeMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations on complete: %s", err)
+	}
+}
+
+func Test_Middleware_CompletedKey_ResponseIsReplayed(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var dbMock sqlmock.Sqlmock
+	var rows = sqlmock.NewRows(storedColumns).
+		AddRow(fingerprint(http.MethodPost, testPath, testBody), "completed", http.StatusCreated, "application/json", []byte(`{"accountNumber":3}`))
+	var handler = setupMiddleware(createdHandler(&calls), keyUsed(&dbMock, rows))
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if calls != 0 {
+		t.Errorf("handler should not be called for completed key, but was called %d times", calls)
+	}
+
+	if resp.Code != http.StatusCreated || resp.Body.String() != `{"accountNumber":3}` {
+		t.Errorf("stored response expected, but got status %d and body %s", resp.Code, resp.Body.String())
+	}
+
+	if resp.Header().Get(idempotency.HeaderReplayed) != "true" || resp.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replayed response should have stored content type and replayed header, got %v", resp.Header())
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Middleware_KeyInProgress_ConflictIsReturned(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var dbMock sqlmock.Sqlmock
+	var rows = sqlmock.NewRows(storedColumns).
+		AddRow(fingerprint(http.MethodPost, testPath, testBody), "in_progress", nil, nil, nil)
+	var handler = setupMiddleware(createdHandler(&calls), keyUsed(&dbMock, rows))
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if calls != 0 {
+		t.Errorf("handler should not be called for key in progress, but was called %d times", calls)
+	}
+
+	if resp.Code != http.StatusConflict {
+		t.Errorf("status %d expected, but got %d", http.StatusConflict, resp.Code)
+	}
+
+	if code := problemCode(t, resp); code != "idempotent_request_in_progress" {
+		t.Errorf("error code idempotent_request_in_progress expected, but got %s", code)
+	}
+}
+
+func Test_Middleware_KeyReusedWithDifferentBody_ErrorIsReturned(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var dbMock sqlmock.Sqlmock
+	var rows = sqlmock.NewRows(storedColumns).
+		AddRow(fingerprint(http.MethodPost, testPath, `{"creditLimit":5}`), "completed", http.StatusCreated, "application/json", []byte(`{}`))
+	var handler = setupMiddleware(createdHandler(&calls), keyUsed(&dbMock, rows))
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if calls != 0 {
+		t.Errorf("handler should not be called for reused key, but was called %d times", calls)
+	}
+
+	if resp.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status %d expected, but got %d", http.StatusUnprocessableEntity, resp.Code)
+	}
+
+	if code := problemCode(t, resp); code != "idempotency_key_reused" {
+		t.Errorf("error code idempotency_key_reused expected, but got %s", code)
+	}
+}
+
+func Test_Middleware_ServerError_KeyIsReleased(t *testing.T) {
+	// Arrange
+	var releaseMock sqlmock.Sqlmock
+	var startedAt time.Time
+	var handler = setupMiddleware(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+		func(mock sqlmock.Sqlmock) {
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO public.idempotency_keys").
+				WithArgs(testKey, sqlmock.AnyArg(), "in_progress", capturedTime{&startedAt}, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+		func(mock sqlmock.Sqlmock) {
+			releaseMock = mock
+			mock.ExpectBegin()
+			mock.ExpectExec("DELETE FROM public.idempotency_keys WHERE key = \\$1 AND status = \\$2 AND fingerprint = \\$3 AND created_at = \\$4").
+				WithArgs(testKey, "in_progress", fingerprint(http.MethodPost, testPath, testBody), sameTime{&startedAt}).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+	)
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("status %d expected, but got %d", http.StatusInternalServerError, resp.Code)
+	}
+
+	if err := releaseMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Middleware_ResponseNotStored_KeyIsKeptAndServerErrorReturned(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var completeMock sqlmock.Sqlmock
+	var handler = setupMiddleware(createdHandler(&calls),
+		func(mock sqlmock.Sqlmock) {
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO public.idempotency_keys").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+		// Key is not released after failed completion, there is no db context for release
+		func(mock sqlmock.Sqlmock) {
+			completeMock = mock
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE public.idempotency_keys SET status = \\$1").
+				WillReturnError(errors.New("connection reset"))
+			mock.ExpectRollback()
+		},
+	)
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, newRequest(http.MethodPost, testBody))
+
+	// Assert
+	if calls != 1 {
+		t.Errorf("handler expected to be called once, but was called %d times", calls)
+	}
+
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("status %d expected, but got %d", http.StatusInternalServerError, resp.Code)
+	}
+
+	if err := completeMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Middleware_TooLongKey_ErrorIsReturned(t *testing.T) {
+	// Arrange
+	var calls = 0
+	var handler = setupMiddleware(createdHandler(&calls))
+	var req = newRequest(http.MethodPost, testBody)
+	req.Header.Set(idempotency.HeaderIdempotencyKey, strings.Repeat("k", idempotency.MaxKeyLength+1))
+	var resp = httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(resp, req)
+
+	// Assert
+	if calls != 0 || resp.Code != http.StatusBadRequest {
+		t.Errorf("status %d expected without calling handler, but got %d and %d calls", http.StatusBadRequest, resp.Code, calls)
+	}
+}
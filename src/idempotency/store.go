@@ -0,0 +1,196 @@
+package idempotency
+
+import (
+	"context"
+	"test/coins/db"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	servErr "test/coins/errors"
+)
+
+type sqlParams = []interface{}
+
+// Store keeps idempotency keys with fingerprints of requests and their responses
+type Store struct {
+	dbContextFactory func() (db.DbContext, error)
+
+	ttl time.Duration
+
+	lockTimeout time.Duration
+}
+
+// Creates new idempotency store
+//	dbContextFactory - factory function used to create new db context
+//	ttl              - time key and stored response are kept. Request with expired key is executed again
+//	lockTimeout      - time after which request that is still in progress is considered abandoned
+//	                   (for example, if server stopped while executing it), so it can be executed again
+// Returns created store
+func NewStore(dbContextFactory func() (db.DbContext, error), ttl time.Duration, lockTimeout time.Duration) *Store {
+	return &Store{dbContextFactory, ttl, lockTimeout}
+}
+
+// Starts request with idempotency key. Key is locked until request is completed or released.
+// Expired key or abandoned request is taken over
+//	key         - idempotency key
+//	fingerprint - fingerprint of request
+// Returns time request was started at, that identifies key lock together with fingerprint,
+// and nil if request was started, otherwise request that was already started with the same key
+func (store *Store) begin(key string, fingerprint string) (time.Time, *storedRequest, error) {
+	// SQLite keeps timestamps with millisecond precision, so start time is matched by both databases
+	var now = time.Now().UTC().Truncate(time.Millisecond)
+
+	dbContext, err := store.dbContextFactory()
+	if err != nil {
+		return now, nil, err
+	}
+	defer dbContext.Release()
+
+	started, err := dbContext.Execute(
+		"INSERT INTO public.idempotency_keys (key, fingerprint, status, created_at, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = EXCLUDED.status, "+
+			"response_status = NULL, content_type = NULL, response_body = NULL, "+
+			"created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at "+
+			"WHERE idempotency_keys.expires_at <= $4 OR (idempotency_keys.status = $3 AND idempotency_keys.created_at <= $6)",
+		key, fingerprint, statusInProgress, now, now.Add(store.ttl), now.Add(-store.lockTimeout),
+	)
+	if err != nil {
+		return now, nil, err
+	}
+
+	if started > 0 {
+		return now, nil, dbContext.Save()
+	}
+
+	var request *storedRequest
+	err = dbContext.Query(
+		"SELECT fingerprint, status, response_status, content_type, response_body FROM public.idempotency_keys WHERE key = $1",
+		sqlParams{key},
+		func(rows db.QueryResultRows) error {
+			defer rows.Close()
+
+			if rows.Next() {
+				var responseStatus *int
+				var contentType *string
+				request = &storedRequest{}
+				err := rows.Scan(&request.fingerprint, &request.status, &responseStatus, &contentType, &request.responseBody)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				if responseStatus != nil {
+					request.responseStatus = *responseStatus
+				}
+
+				if contentType != nil {
+					request.contentType = *contentType
+				}
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return now, nil, err
+	}
+
+	// Key was removed between insert and select, which means request with it was released
+	if request == nil {
+		return now, &storedRequest{fingerprint: fingerprint, status: statusInProgress}, nil
+	}
+
+	return now, request, nil
+}
+
+// Stores response of request and unlocks key. Nothing is stored if key was taken over by other request
+//	key          - idempotency key
+//	fingerprint  - fingerprint of request
+//	startedAt    - time request was started at, returned by begin
+//	status       - response status code
+//	contentType  - response content type
+//	responseBody - response body
+func (store *Store) complete(key string, fingerprint string, startedAt time.Time, status int, contentType string, responseBody []byte) error {
+	dbContext, err := store.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(
+		"UPDATE public.idempotency_keys SET status = $1, response_status = $2, content_type = $3, response_body = $4 "+
+			"WHERE key = $5 AND status = $6 AND fingerprint = $7 AND created_at = $8",
+		statusCompleted, status, contentType, responseBody, key, statusInProgress, fingerprint, startedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return dbContext.Save()
+}
+
+// Removes key of request that was not completed, so request can be retried with the same key.
+// Key is not removed if it was taken over by other request
+//	key         - idempotency key
+//	fingerprint - fingerprint of request
+//	startedAt   - time request was started at, returned by begin
+func (store *Store) release(key string, fingerprint string, startedAt time.Time) error {
+	dbContext, err := store.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(
+		"DELETE FROM public.idempotency_keys WHERE key = $1 AND status = $2 AND fingerprint = $3 AND created_at = $4",
+		key, statusInProgress, fingerprint, startedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return dbContext.Save()
+}
+
+// Removes expired keys
+// Returns number of removed keys
+func (store *Store) PurgeExpired() (int64, error) {
+	dbContext, err := store.dbContextFactory()
+	if err != nil {
+		return 0, err
+	}
+	defer dbContext.Release()
+
+	purged, err := dbContext.Execute(
+		"DELETE FROM public.idempotency_keys WHERE expires_at <= $1",
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, dbContext.Save()
+}
+
+// Removes expired keys periodically until context is cancelled
+//	ctx      - context used to stop purging
+//	interval - interval between purges
+//	logger   - logger
+func (store *Store) RunPurge(ctx context.Context, interval time.Duration, logger kitlog.Logger) {
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := store.PurgeExpired()
+			if err != nil {
+				level.Error(logger).Log("msg", "failed to purge expired idempotency keys", "err", err)
+			}
+		}
+	}
+}
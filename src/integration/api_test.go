@@ -0,0 +1,558 @@
+//go:build integration
+// +build integration
+
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/migrations"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Transaction timeout of tests, concurrent transfers wait for each other's row locks
+const tranTimeout = time.Second * 10
+
+var (
+	// Connection string of test database
+	connectionString string
+
+	// Connection pool of test database
+	pool db.ConnectionPool
+
+	// Factory of db contexts in test database
+	factory func() (db.DbContext, error)
+
+	// Account service, used to open accounts as there is no http endpoint for it
+	accountService account.AccountService
+
+	// Transfer service, used by stress test
+	transferService transfer.TransferService
+
+	// Http server with account and transfer API
+	server *httptest.Server
+)
+
+func TestMain(m *testing.M) {
+	connectionString = os.Getenv("INTEGRATION_DATABASE_CS")
+	if connectionString == "" {
+		fmt.Fprintln(os.Stderr, "INTEGRATION_DATABASE_CS variable should contain connection string of test database")
+		os.Exit(1)
+	}
+
+	code, err := run(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	os.Exit(code)
+}
+
+// Prepares database and http server, then runs tests
+// Returns exit code of tests
+func run(m *testing.M) (int, error) {
+	var err error
+	pool, err = db.NewConnectionPool(connectionString, 16, tranTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	defer pool.Close()
+
+	factory = func() (db.DbContext, error) {
+		return db.CreateContext(pool, tranTimeout)
+	}
+
+	all, err := migrations.Load()
+	if err != nil {
+		return 0, fmt.Errorf("unable to load migrations: %w", err)
+	}
+
+	_, err = migrations.NewMigrator(factory, all).Up()
+	if err != nil {
+		return 0, fmt.Errorf("unable to migrate database: %w", err)
+	}
+
+	accountService = account.NewAccountService(account.NewPostgresStorage(factory))
+	transferService = transfer.NewTransferService(newTransferStorage())
+
+	var mr = mux.NewRouter()
+	account.RegisterHandlers(mr, accountService, log.NewNopLogger())
+	transfer.RegisterHandlers(mr, transferService, log.NewNopLogger())
+	transfer.RegisterAdminHandlers(mr, transferService, log.NewNopLogger())
+
+	server = httptest.NewServer(mr)
+	defer server.Close()
+
+	return m.Run(), nil
+}
+
+// Creates storage of money transfers, Postgres executes transfers by single statement as by default configuration
+func newTransferStorage() transfer.Storage {
+	if db.IsSQLite(connectionString) {
+		return transfer.NewPostgresStorage(factory)
+	}
+
+	return transfer.NewSingleStatementStorage(factory)
+}
+
+// Sends request to API
+//	method - http method
+//	path   - request path
+//	body   - request body, serialized to JSON, nil - no body
+//	result - pointer to value response body is decoded to, nil - body is not decoded
+// Returns response status code
+func send(method, path string, body, result interface{}) (int, error) {
+	var reader = &bytes.Buffer{}
+	if body != nil {
+		err := json.NewEncoder(reader).Encode(body)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if result != nil && resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sends money transfer request
+// Returns response status code
+func sendTransfer(id uuid.UUID, source, dest account.AccountNumber, amount uint64) (int, error) {
+	return send("POST", "/api/v1/transfers", map[string]interface{}{
+		"id":     id,
+		"source": source,
+		"dest":   dest,
+		"amount": amount,
+	}, nil)
+}
+
+// Opens new account and deposits money into it
+//	balance - initial balance, 0 - no deposit is made
+// Returns account number
+func openAccount(t testing.TB, balance uint64) account.AccountNumber {
+	acc, err := accountService.CreateAccount(context.Background())
+	if err != nil {
+		t.Fatalf("unable to open account: %s", err.Error())
+	}
+
+	if balance > 0 {
+		status, err := send("POST", "/api/v1/admin/deposits", map[string]interface{}{
+			"id":      uuid.New(),
+			"account": acc.Number,
+			"amount":  balance,
+		}, nil)
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("unable to deposit money, status %d, error %v", status, err)
+		}
+	}
+
+	return acc.Number
+}
+
+// Returns balances of customer accounts
+func readBalances(t *testing.T) map[account.AccountNumber]int64 {
+	var response struct {
+		Accounts []struct {
+			Number  account.AccountNumber `json:"number"`
+			Balance int64                 `json:"balance"`
+		} `json:"accounts"`
+	}
+
+	status, err := send("GET", "/api/v1/accounts", nil, &response)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unable to list accounts, status %d, error %v", status, err)
+	}
+
+	var result = map[account.AccountNumber]int64{}
+	for _, acc := range response.Accounts {
+		result[acc.Number] = acc.Balance
+	}
+
+	return result
+}
+
+// Returns directions of account transfers, latest transfers first
+func readTransferDirections(t *testing.T, accountNumber account.AccountNumber) []string {
+	var response struct {
+		Transfers []struct {
+			Direction string `json:"direction"`
+		} `json:"transfers"`
+	}
+
+	status, err := send("GET", fmt.Sprintf("/api/v1/accounts/%d/transfers", accountNumber), nil, &response)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unable to list transfers, status %d, error %v", status, err)
+	}
+
+	var result = []string{}
+	for _, tr := range response.Transfers {
+		result = append(result, tr.Direction)
+	}
+
+	return result
+}
+
+// Returns total balance of all accounts including settlement one. Money is only moved between accounts,
+// so it should always be zero
+func readTotalBalance(t *testing.T) int64 {
+	dbContext, err := db.CreateContext(pool, tranTimeout)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	var total int64
+	err = dbContext.Query("SELECT COALESCE(SUM(balance), 0) FROM public.accounts", nil, func(rows db.QueryResultRows) error {
+		rows.Next()
+		return rows.Scan(&total)
+	})
+	if err != nil {
+		t.Fatalf("unable to read total balance: %s", err.Error())
+	}
+
+	return total
+}
+
+func Test_Transfer_ConcurrentTransfers_BalanceIsConserved(t *testing.T) {
+	// Arrange
+	const (
+		accountCount     = 5
+		initialBalance   = 1000
+		workers          = 8
+		transfersPerWork = 25
+	)
+
+	var accounts = []account.AccountNumber{}
+	for i := 0; i < accountCount; i++ {
+		accounts = append(accounts, openAccount(t, initialBalance))
+	}
+
+	// Act
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded = 0
+		failures  = []string{}
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			var rnd = rand.New(rand.NewSource(seed))
+			for i := 0; i < transfersPerWork; i++ {
+				// Dest is picked among other accounts, so it always differs from source
+				var source = rnd.Intn(accountCount)
+				var dest = (source + 1 + rnd.Intn(accountCount-1)) % accountCount
+
+				status, err := sendTransfer(uuid.New(), accounts[source], accounts[dest], uint64(1+rnd.Intn(300)))
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					failures = append(failures, err.Error())
+				case status == http.StatusOK:
+					succeeded++
+				case status != http.StatusUnprocessableEntity:
+					failures = append(failures, fmt.Sprintf("unexpected status %d", status))
+				}
+				mu.Unlock()
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+
+	// Assert
+	if len(failures) > 0 {
+		t.Fatalf("transfers should either succeed or fail with not enough money, got: %v", failures)
+	}
+
+	var balances = readBalances(t)
+	var sum int64 = 0
+	var outgoing = 0
+	for _, acc := range accounts {
+		if balances[acc] < 0 {
+			t.Errorf("account %d without credit limit has negative balance %d", acc, balances[acc])
+		}
+
+		sum += balances[acc]
+		for _, direction := range readTransferDirections(t, acc) {
+			if direction == transfer.DirectionOutgoing {
+				outgoing++
+			}
+		}
+	}
+
+	if sum != accountCount*initialBalance {
+		t.Errorf("money should not be created or lost by transfers, expected %d in accounts, got %d", accountCount*initialBalance, sum)
+	}
+
+	if outgoing != succeeded {
+		t.Errorf("every succeeded transfer should be recorded once, succeeded %d, recorded %d", succeeded, outgoing)
+	}
+
+	if total := readTotalBalance(t); total != 0 {
+		t.Errorf("total balance of all accounts should be zero, got %d", total)
+	}
+}
+
+func Test_Transfer_DuplicateIdSentConcurrently_ExecutedOnce(t *testing.T) {
+	// Arrange
+	const requests = 10
+	var (
+		source = openAccount(t, 1000)
+		dest   = openAccount(t, 0)
+		id     = uuid.New()
+	)
+
+	// Act
+	var (
+		wg       sync.WaitGroup
+		statuses = make(chan int, requests)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := sendTransfer(id, source, dest, 100)
+			if err != nil {
+				status = 0
+			}
+
+			statuses <- status
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	// Assert
+	var counts = map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != requests-1 {
+		t.Fatalf("expected one succeeded transfer and %d duplicates, got statuses %v", requests-1, counts)
+	}
+
+	var balances = readBalances(t)
+	if balances[source] != 900 || balances[dest] != 100 {
+		t.Fatalf("transfer should be executed once, balances are %d and %d", balances[source], balances[dest])
+	}
+}
+
+func Test_Transfer_DuplicateIdSentConcurrentlyForDifferentAccounts_ExecutedOnce(t *testing.T) {
+	// Arrange
+	const requests = 10
+	var (
+		sources = make([]account.AccountNumber, requests)
+		dests   = make([]account.AccountNumber, requests)
+		id      = uuid.New()
+	)
+
+	// Transfers lock different accounts, so they don't wait for each other before duplicate check
+	for i := 0; i < requests; i++ {
+		sources[i] = openAccount(t, 1000)
+		dests[i] = openAccount(t, 0)
+	}
+
+	// Act
+	var (
+		wg       sync.WaitGroup
+		statuses = make(chan int, requests)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status, err := sendTransfer(id, sources[i], dests[i], 100)
+			if err != nil {
+				status = 0
+			}
+
+			statuses <- status
+		}(i)
+	}
+	wg.Wait()
+	close(statuses)
+
+	// Assert
+	var counts = map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != requests-1 {
+		t.Fatalf("expected one succeeded transfer and %d duplicates, got statuses %v", requests-1, counts)
+	}
+
+	var balances = readBalances(t)
+	var moved = 0
+	for i := 0; i < requests; i++ {
+		if balances[dests[i]] == 100 && balances[sources[i]] == 900 {
+			moved++
+		} else if balances[dests[i]] != 0 || balances[sources[i]] != 1000 {
+			t.Fatalf("rejected duplicate should not change balances, got %d and %d", balances[sources[i]], balances[dests[i]])
+		}
+	}
+
+	if moved != 1 {
+		t.Fatalf("transfer should be executed once, executed %d times", moved)
+	}
+}
+
+func Test_TransferMoney_StepByStep_DuplicateIdSentConcurrentlyForDifferentAccounts_ExecutedOnce(t *testing.T) {
+	// Arrange
+	const requests = 10
+	var (
+		service = transfer.NewTransferService(transfer.NewPostgresStorage(factory))
+		id      = transfer.TransferId(uuid.New())
+		sources = make([]account.AccountNumber, requests)
+		dests   = make([]account.AccountNumber, requests)
+	)
+
+	for i := 0; i < requests; i++ {
+		sources[i] = openAccount(t, 1000)
+		dests[i] = openAccount(t, 0)
+	}
+
+	// Act
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, requests)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- service.TransferMoney(context.Background(), id, sources[i], dests[i], 100, transfer.TransferDetails{})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert
+	var succeeded, duplicates = 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, transfer.ErrTransferAlreadyComplete):
+			duplicates++
+		default:
+			t.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+		}
+	}
+
+	if succeeded != 1 || duplicates != requests-1 {
+		t.Fatalf("expected one succeeded transfer and %d duplicates, got %d and %d", requests-1, succeeded, duplicates)
+	}
+}
+
+func Test_Transfer_NotEnoughMoney_NothingChangedAndIdCanBeReused(t *testing.T) {
+	// Arrange
+	var (
+		source = openAccount(t, 100)
+		dest   = openAccount(t, 0)
+		id     = uuid.New()
+	)
+
+	// Act
+	status, err := sendTransfer(id, source, dest, 500)
+
+	// Assert
+	if err != nil || status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected not enough money response, status %d, error %v", status, err)
+	}
+
+	var balances = readBalances(t)
+	if balances[source] != 100 || balances[dest] != 0 {
+		t.Fatalf("failed transfer should not change balances, got %d and %d", balances[source], balances[dest])
+	}
+
+	if directions := readTransferDirections(t, dest); len(directions) != 0 {
+		t.Fatalf("failed transfer should not be recorded, got %v", directions)
+	}
+
+	// Failed transfer is not recorded, so its id can be used again
+	status, err = sendTransfer(id, source, dest, 50)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("transfer with id of failed one should succeed, status %d, error %v", status, err)
+	}
+}
+
+func Test_Transfer_DatabaseErrorAfterDebit_TransactionRolledBack(t *testing.T) {
+	if db.IsSQLite(connectionString) {
+		t.Skip("SQLite converts integer overflow to real number instead of failing")
+	}
+
+	// Arrange
+	var (
+		source = openAccount(t, 0)
+		dest   = openAccount(t, 100)
+	)
+
+	// Source can go deep below zero, but dest balance overflows bigint after source is debited
+	status, err := send("PUT", fmt.Sprintf("/api/v1/accounts/%d/credit-limit", source), map[string]interface{}{
+		"creditLimit": int64(math.MaxInt64),
+		"reason":      "integration test",
+	}, nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unable to set credit limit, status %d, error %v", status, err)
+	}
+
+	// Act
+	status, err = sendTransfer(uuid.New(), source, dest, math.MaxInt64-50)
+
+	// Assert
+	if err != nil || status != http.StatusInternalServerError {
+		t.Fatalf("expected database error response, status %d, error %v", status, err)
+	}
+
+	var balances = readBalances(t)
+	if balances[source] != 0 || balances[dest] != 100 {
+		t.Fatalf("debit of source should be rolled back, balances are %d and %d", balances[source], balances[dest])
+	}
+
+	if directions := readTransferDirections(t, source); len(directions) != 0 {
+		t.Fatalf("failed transfer should not be recorded, got %v", directions)
+	}
+
+	if total := readTotalBalance(t); total != 0 {
+		t.Fatalf("total balance of all accounts should be zero, got %d", total)
+	}
+}
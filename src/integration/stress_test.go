@@ -0,0 +1,28 @@
+//go:build integration
+// +build integration
+
+package integration_test
+
+import (
+	"context"
+	"test/coins/stress"
+	"testing"
+)
+
+func Test_Stress_ConcurrentTransfers_InvariantsHold(t *testing.T) {
+	// Arrange
+	var opts = stress.DefaultOptions()
+	opts.Transfers = 2000
+
+	// Act
+	report, err := stress.Run(context.Background(), accountService, transferService, opts)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Run() was called: %s", err.Error())
+	}
+
+	if !report.OK() {
+		t.Fatalf("expected all invariants to hold, seed %d, got report:\n%s", opts.Seed, report)
+	}
+}
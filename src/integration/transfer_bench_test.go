@@ -0,0 +1,53 @@
+//go:build integration
+// +build integration
+
+package integration_test
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/transfer"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Runs parallel transfers between neighbour accounts
+//	b       - benchmark
+//	storage - storage of money transfers
+func benchmarkTransfers(b *testing.B, storage transfer.Storage) {
+	const accounts = 16
+	var service = transfer.NewTransferService(storage)
+	var numbers = make([]account.AccountNumber, accounts)
+	for i := range numbers {
+		numbers[i] = openAccount(b, math.MaxInt32)
+	}
+
+	var counter uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var n = atomic.AddUint64(&counter, 1)
+			var err = service.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), numbers[n%accounts], numbers[(n+1)%accounts], 1, transfer.TransferDetails{})
+			if err != nil {
+				b.Errorf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+				return
+			}
+		}
+	})
+}
+
+func Benchmark_TransferMoney_StepByStep(b *testing.B) {
+	benchmarkTransfers(b, transfer.NewPostgresStorage(factory))
+}
+
+func Benchmark_TransferMoney_SingleStatement(b *testing.B) {
+	if db.IsSQLite(connectionString) {
+		b.Skip("SQLite does not support data-modifying CTE used by single statement")
+	}
+
+	benchmarkTransfers(b, transfer.NewSingleStatementStorage(factory))
+}
@@ -1,81 +1,419 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"test/coins/account"
+	"test/coins/admin"
+	"test/coins/api"
+	"test/coins/config"
 	"test/coins/db"
+	"test/coins/idempotency"
+	"test/coins/memory"
+	"test/coins/outbox"
+	"test/coins/pb"
+	"test/coins/schedule"
+	"test/coins/stream"
 	"test/coins/transfer"
+	"test/coins/webhook"
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Loading database connection string
+	// Loading configuration, .env file is loaded into environment variables
 	godotenv.Load()
-	var cs = os.Getenv("DATABASE_CS")
 
-	if cs == "" {
-		panic("unable to start service - connection string is not provided")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil && err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	// Creating new connection pool
-	cnPool, err := db.NewConnectionPool(cs)
-	if err != nil {
-		panic("Unable to create connection pool: " + err.Error())
+	if len(os.Args) > 1 && os.Args[1] == "stress" {
+		if err := runStress(os.Args[2:], os.Stdout); err != nil && err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	defer cnPool.Close()
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		if err := runBench(os.Args[2:], os.Stdout); err != nil && err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.LookupEnv)
+	if err == flag.ErrHelp {
+		return
+	}
+
+	if err != nil {
+		panic("Unable to load configuration: " + err.Error())
+	}
 
 	// Initializing logger
 	var logger log.Logger
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = level.NewFilter(logger, logLevel(cfg.Log.Level))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-	var httpLogger = log.With(logger, "component", "http")
 
-	// Initializing services
-	var factory = func() (db.DbContext, error) {
-		return db.CreateContext(cnPool, time.Second*5)
+	level.Info(logger).Log("msg", "starting", "config", cfg.String())
+
+	publisher, closePublisher, err := createOutboxPublisher(cfg.Outbox.Publisher, cfg.Outbox.File)
+	if err != nil {
+		panic("Unable to create outbox publisher: " + err.Error())
 	}
-	var accountService = account.NewAccountService(factory)
-	var transferService = transfer.NewTransferService(factory)
 
-	// Registering routes and handles
-	var mr = mux.NewRouter()
+	defer closePublisher()
 
-	account.RegisterHandlers(mr, accountService, httpLogger)
-	transfer.RegisterHandlers(mr, transferService, httpLogger)
-	http.Handle("/", accessControl(mr))
+	// Starting background workers
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var startBackend = startDatabaseBackend
+	if cfg.Database.Backend() == config.BackendMemory {
+		startBackend = startMemoryBackend
+	}
+
+	svcs, handlers, closeBackend := startBackend(workersCtx, cfg, publisher, logger)
+	defer closeBackend()
+
+	http.Handle("/", accessControl(handlers.public, cfg.CORS.AllowedOrigins))
 
 	// Setting up http server
-	var httpAddress = "localhost:" + os.Getenv("PORT")
-	errs := make(chan error, 2)
+	var httpServer = &http.Server{
+		Addr:         cfg.HTTP.Address,
+		ReadTimeout:  cfg.HTTP.ReadTimeout.Std(),
+		WriteTimeout: cfg.HTTP.WriteTimeout.Std(),
+		IdleTimeout:  cfg.HTTP.IdleTimeout.Std(),
+	}
+	errs := make(chan error, 4)
 	go func() {
-		logger.Log("transport", "http", "address", httpAddress, "msg", "listening")
-		httpError := http.ListenAndServe(httpAddress, nil)
+		level.Info(logger).Log("transport", "http", "address", httpServer.Addr, "msg", "listening")
+		httpError := httpServer.ListenAndServe()
 		errs <- httpError
 	}()
+
+	// Setting up admin http server, it is started only if address is configured
+	if cfg.Admin.Address != "" {
+		var adminServer = &http.Server{
+			Addr:         cfg.Admin.Address,
+			Handler:      handlers.admin,
+			ReadTimeout:  cfg.HTTP.ReadTimeout.Std(),
+			WriteTimeout: cfg.HTTP.WriteTimeout.Std(),
+			IdleTimeout:  cfg.HTTP.IdleTimeout.Std(),
+		}
+
+		go func() {
+			level.Info(logger).Log("transport", "admin", "address", adminServer.Addr, "msg", "listening")
+			errs <- adminServer.ListenAndServe()
+		}()
+	}
+
+	// Setting up grpc server, it is started only if address is configured
+	if cfg.GRPC.Address != "" {
+		var grpcLogger = log.With(logger, "component", "grpc")
+		var grpcServer = grpc.NewServer()
+		pb.RegisterAccountServiceServer(grpcServer, account.NewGRPCServer(svcs.account, grpcLogger))
+		pb.RegisterTransferServiceServer(grpcServer, transfer.NewGRPCServer(svcs.transfer, grpcLogger))
+		defer grpcServer.GracefulStop()
+
+		go func() {
+			listener, err := net.Listen("tcp", cfg.GRPC.Address)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			level.Info(logger).Log("transport", "grpc", "address", cfg.GRPC.Address, "msg", "listening")
+			errs <- grpcServer.Serve(listener)
+		}()
+	}
 	go func() {
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT)
 		errs <- fmt.Errorf("%s", <-c)
 	}()
 
-	logger.Log("terminated", <-errs)
+	level.Info(logger).Log("terminated", <-errs)
+}
+
+// Creates services that keep data in Postgres or SQLite database and starts their background workers.
+// Pending migrations are applied to Postgres when auto migration is enabled, SQLite database is always migrated.
+// Webhooks are not supported by SQLite backend and are disabled
+//	ctx       - context used to stop background workers
+//	cfg       - configuration
+//	publisher - publisher of outbox events
+//	logger    - logger
+// Returns services, their http handlers and function that closes connection pool
+func startDatabaseBackend(ctx context.Context, cfg config.Config, publisher outbox.Publisher, logger log.Logger) (services, handlers, func()) {
+	// Webhook dispatcher writes deliveries in its own transaction while relay transaction is open,
+	// SQLite allows single writer, so they would wait for each other
+	if cfg.Database.Backend() == config.BackendSQLite && cfg.Features.Webhooks {
+		level.Warn(logger).Log("msg", "webhooks are not supported by SQLite backend and are disabled")
+		cfg.Features.Webhooks = false
+	}
+
+	// Creating new connection pool
+	cnPool, err := db.NewConnectionPool(cfg.Database.ConnectionString, cfg.Database.PoolSize, cfg.Database.AcquireTimeout.Std())
+	if err != nil {
+		panic("Unable to create connection pool: " + err.Error())
+	}
+
+	// SQLite database is always migrated on start, as it is created by application
+	if cfg.Database.AutoMigrate || cfg.Database.Backend() == config.BackendSQLite {
+		migrator, err := newMigrator(cnPool, cfg)
+		if err != nil {
+			panic(err.Error())
+		}
+
+		applied, err := migrator.Up()
+		if err != nil {
+			panic("Unable to migrate database: " + err.Error())
+		}
+
+		for _, migration := range applied {
+			level.Info(logger).Log("msg", "migration applied", "version", migration.Version, "name", migration.Name)
+		}
+	}
+
+	// Initializing services
+	var factory = func() (db.DbContext, error) {
+		return db.CreateContext(cnPool, cfg.Database.TransactionTimeout.Std())
+	}
+	var svcs = services{
+		account:  account.NewAccountService(account.NewPostgresStorage(factory)),
+		transfer: transfer.NewTransferService(newTransferStorage(cfg, factory)),
+	}
+	svcs.schedule = schedule.NewScheduleService(factory, svcs.transfer)
+	if cfg.Features.Webhooks {
+		svcs.webhook = webhook.NewWebhookService(factory)
+	}
+
+	if cfg.Features.Stream {
+		svcs.stream = stream.NewStreamService(factory)
+		svcs.streamBroker = stream.NewBroker()
+	}
+
+	// Registering routes and handles
+	var mr = mux.NewRouter()
+	registerHandlers(mr, svcs, log.With(logger, "component", "http"))
+
+	var adminRouter = mux.NewRouter()
+	registerAdminHandlers(adminRouter, svcs, log.With(logger, "component", "admin"))
+
+	var result = handlers{public: mr, admin: adminRouter}
+	if cfg.Features.Idempotency {
+		// Mutating requests with Idempotency-Key header are executed once, keys are kept for a day
+		var idempotencyLogger = log.With(logger, "component", "idempotency")
+		var idempotencyStore = idempotency.NewStore(factory, time.Hour*24, time.Minute)
+		var idempotent = idempotency.Middleware(idempotencyStore, idempotencyLogger)
+		result = handlers{public: idempotent(result.public), admin: idempotent(result.admin)}
+		go idempotencyStore.RunPurge(ctx, time.Hour, idempotencyLogger)
+	}
+
+	// Admin token is checked first, so unauthorized requests don't hold idempotency keys
+	result.admin = admin.Middleware(cfg.Admin.Token)(result.admin)
+
+	// Pending credits of hot accounts are rolled up into balances in background
+	var creditsWorker = account.NewWorker(svcs.account, time.Second, 100, log.With(logger, "component", "credits"))
+	go creditsWorker.Run(ctx)
+
+	if cfg.Features.Scheduler {
+		var scheduleWorker = schedule.NewWorker(svcs.schedule, time.Second*10, 50, log.With(logger, "component", "scheduler"))
+		go scheduleWorker.Run(ctx)
+	}
+
+	// Outbox events are also dispatched to webhook subscriptions and pushed to account streams.
+	// Stream broker never fails, so it goes last and gets each event once
+	var publishers = []outbox.Publisher{publisher}
+	if cfg.Features.Webhooks {
+		publishers = append(publishers, webhook.NewDispatcher(factory))
+
+		var webhookClient = &http.Client{Timeout: time.Second * 10}
+		var webhookSender = webhook.NewSender(factory, webhookClient, webhook.DefaultRetryPolicy, time.Second*5, 50, log.With(logger, "component", "webhook"))
+		go webhookSender.Run(ctx)
+	}
+
+	if cfg.Features.Stream {
+		publishers = append(publishers, svcs.streamBroker)
+	}
+
+	var outboxRelay = outbox.NewRelay(factory, outbox.NewMultiPublisher(publishers...), time.Second, 100, log.With(logger, "component", "outbox"))
+	go outboxRelay.Run(ctx)
+
+	return svcs, result, cnPool.Close
+}
+
+// Creates storage of money transfers in Postgres or SQLite database.
+// Single statement execution uses data-modifying CTE, which SQLite does not support
+//	cfg     - configuration
+//	factory - factory function used to create new db context
+// Returns created storage
+func newTransferStorage(cfg config.Config, factory func() (db.DbContext, error)) transfer.Storage {
+	if cfg.Features.SingleStatementTransfers && cfg.Database.Backend() == config.BackendPostgres {
+		return transfer.NewSingleStatementStorage(factory)
+	}
+
+	return transfer.NewPostgresStorage(factory)
+}
+
+// Creates services that keep data in memory. Memory store is seeded with demo accounts and
+// publishes events right after commit. Scheduler, webhooks, stream and idempotency keys need database, so they are disabled
+//	ctx       - context used to stop background workers
+//	cfg       - configuration
+//	publisher - publisher of events
+//	logger    - logger
+// Returns services, their http handlers and function that releases resources
+func startMemoryBackend(ctx context.Context, cfg config.Config, publisher outbox.Publisher, logger log.Logger) (services, handlers, func()) {
+	if cfg.Features.Scheduler || cfg.Features.Webhooks || cfg.Features.Stream || cfg.Features.Idempotency {
+		level.Warn(logger).Log("msg", "scheduler, webhooks, stream and idempotency are not supported by memory backend and are disabled")
+	}
+
+	var store = memory.NewDemoStore(cfg.Database.TransactionTimeout.Std(), publisher)
+	var svcs = services{
+		account:  account.NewAccountService(store),
+		transfer: transfer.NewTransferService(store),
+	}
+
+	var creditsWorker = account.NewWorker(svcs.account, time.Second, 100, log.With(logger, "component", "credits"))
+	go creditsWorker.Run(ctx)
+
+	var mr = mux.NewRouter()
+	registerHandlers(mr, svcs, log.With(logger, "component", "http"))
+
+	var adminRouter = mux.NewRouter()
+	registerAdminHandlers(adminRouter, svcs, log.With(logger, "component", "admin"))
+
+	return svcs, handlers{public: mr, admin: admin.Middleware(cfg.Admin.Token)(adminRouter)}, func() {}
+}
+
+// Http handlers of application
+type handlers struct {
+	// Handler of public API
+	public http.Handler
+
+	// Handler of admin API, it is served on admin address and requires admin token
+	admin http.Handler
+}
+
+// Services exposed via http
+type services struct {
+	account      account.AccountService
+	transfer     transfer.TransferService
+	schedule     schedule.ScheduleService
+	webhook      webhook.WebhookService
+	stream       stream.StreamService
+	streamBroker *stream.Broker
+}
+
+// Registers http handlers of all services. Handlers of disabled (nil) schedule, webhook and stream services are not registered
+//	mr     - Mux router where handlers should be registered
+//	svcs   - services to register
+//	logger - logger
+func registerHandlers(mr *mux.Router, svcs services, logger log.Logger) {
+	account.RegisterHandlers(mr, svcs.account, logger)
+	transfer.RegisterHandlers(mr, svcs.transfer, logger)
+	if svcs.schedule != nil {
+		schedule.RegisterHandlers(mr, svcs.schedule, logger)
+	}
+
+	if svcs.webhook != nil {
+		webhook.RegisterHandlers(mr, svcs.webhook, logger)
+	}
+
+	if svcs.stream != nil {
+		stream.RegisterHandlers(mr, svcs.stream, svcs.streamBroker, logger)
+	}
+
+	api.RegisterHandlers(mr)
+}
+
+// Registers http handlers of admin API. They are not registered on public router
+//	mr     - Mux router where handlers should be registered
+//	svcs   - services to register
+//	logger - logger
+func registerAdminHandlers(mr *mux.Router, svcs services, logger log.Logger) {
+	transfer.RegisterAdminHandlers(mr, svcs.transfer, logger)
+}
+
+// Converts configured log level to level filter option
+//	name - level name: "debug", "info", "warn" or "error"
+func logLevel(name string) level.Option {
+	switch name {
+	case "debug":
+		return level.AllowDebug()
+	case "warn":
+		return level.AllowWarn()
+	case "error":
+		return level.AllowError()
+	default:
+		return level.AllowInfo()
+	}
+}
+
+// Creates publisher for outbox events
+//	kind - publisher kind: "stdout" (default), "file" or "memory"
+//	path - file path for "file" publisher
+// Returns publisher and function that releases its resources
+func createOutboxPublisher(kind string, path string) (outbox.Publisher, func() error, error) {
+	var noop = func() error { return nil }
+	switch kind {
+	case "", "stdout":
+		return outbox.NewStdoutPublisher(), noop, nil
+	case "file":
+		return outbox.NewFilePublisher(path)
+	case "memory":
+		return outbox.NewMemoryPublisher(), noop, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown outbox publisher [%s]", kind)
+	}
 }
 
-func accessControl(h *mux.Router) http.Handler {
+// Adds CORS headers to responses and answers preflight requests
+//	h              - handler of other requests
+//	allowedOrigins - origins allowed to make cross-origin requests, "*" allows any origin
+func accessControl(h http.Handler, allowedOrigins []string) http.Handler {
+	var anyOrigin = false
+	var origins = map[string]bool{}
+	for _, origin := range allowedOrigins {
+		anyOrigin = anyOrigin || origin == "*"
+		origins[origin] = true
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+		if anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Add("Vary", "Origin")
+			if origin := r.Header.Get("Origin"); origins[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Last-Event-ID, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
 
 		if r.Method == "OPTIONS" {
 			return
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"test/coins/account"
+	"test/coins/api"
+	"test/coins/config"
+	"test/coins/outbox"
+	"test/coins/schedule"
+	"test/coins/stream"
+	"test/coins/transfer"
+	"test/coins/webhook"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+)
+
+var pathParamRegex = regexp.MustCompile(`\{[^}]+\}`)
+
+// Returns list of "METHOD path" strings, path parameter names are replaced with {}
+// because router and spec use different names
+func normalizeRoutes(routes map[string]bool) []string {
+	var result = []string{}
+	for route := range routes {
+		result = append(result, pathParamRegex.ReplaceAllString(route, "{}"))
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func Test_Routes_MatchOpenApiSpec(t *testing.T) {
+	// Arrange
+	var mr = mux.NewRouter()
+	// Services are not called, so they don't need db context factory
+	var transferService = transfer.NewTransferService(transfer.NewPostgresStorage(nil))
+	registerHandlers(mr, services{
+		account:      account.NewAccountService(account.NewPostgresStorage(nil)),
+		transfer:     transferService,
+		schedule:     schedule.NewScheduleService(nil, transferService),
+		webhook:      webhook.NewWebhookService(nil),
+		stream:       stream.NewStreamService(nil),
+		streamBroker: stream.NewBroker(),
+	}, log.NewNopLogger())
+	registerAdminHandlers(mr, services{transfer: transferService}, log.NewNopLogger())
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err := json.Unmarshal(api.Spec(), &spec)
+	if err != nil {
+		t.Fatalf("unable to parse OpenAPI spec: %s", err.Error())
+	}
+
+	// Act
+	var registered = map[string]bool{}
+	err = mr.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			registered[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to walk registered routes: %s", err.Error())
+	}
+
+	var documented = map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	// Assert
+	var expected = strings.Join(normalizeRoutes(documented), "\n")
+	var actual = strings.Join(normalizeRoutes(registered), "\n")
+	if expected != actual {
+		t.Fatalf("registered routes do not match OpenAPI spec.\nspec:\n%s\n\nrouter:\n%s", expected, actual)
+	}
+}
+
+const testAdminToken = "2b7e1516-28ae-d2a6-abf7-158809cf4f3c"
+
+// Starts memory backend with admin token
+// Returns http handlers of backend
+func startTestBackend(t *testing.T) handlers {
+	var cfg = config.Default()
+	cfg.Database.ConnectionString = "memory://"
+	cfg.Admin.Token = testAdminToken
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	_, h, closeBackend := startMemoryBackend(ctx, cfg, outbox.NewMemoryPublisher(), log.NewNopLogger())
+	t.Cleanup(closeBackend)
+
+	return h
+}
+
+// Sends deposit request to handler
+//	handler - handler of request
+//	header  - value of Authorization header, empty - header is not sent
+// Returns response status
+func sendDeposit(handler http.Handler, header string) int {
+	var body = `{"id":"8c1d8c29-4c2e-4b8f-9a57-0a2d5c7e3f10","account":1,"amount":100}`
+	var req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/deposits", strings.NewReader(body))
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+
+	var recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func Test_AdminHandler_UnauthenticatedDeposit_RequestRejected(t *testing.T) {
+	// Arrange
+	var h = startTestBackend(t)
+
+	// Act
+	var status = sendDeposit(h.admin, "")
+
+	// Assert
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", status)
+	}
+}
+
+func Test_AdminHandler_AuthenticatedDeposit_MoneyDeposited(t *testing.T) {
+	// Arrange
+	var h = startTestBackend(t)
+
+	// Act
+	var status = sendDeposit(h.admin, "Bearer "+testAdminToken)
+
+	// Assert
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+}
+
+func Test_PublicHandler_Deposit_RouteIsNotFound(t *testing.T) {
+	// Arrange
+	var h = startTestBackend(t)
+
+	// Act
+	var status = sendDeposit(h.public, "Bearer "+testAdminToken)
+
+	// Assert
+	if status != http.StatusNotFound {
+		t.Fatalf("admin routes should not be served by public handler, got status %d", status)
+	}
+}
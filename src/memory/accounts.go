@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"test/coins/account"
+
+	servErr "test/coins/errors"
+)
+
+var errSettlementAccountExists = errors.New("external settlement account already exists")
+
+// Account repository of in-memory unit of work
+type accountRepository struct {
+	tx *Tx
+}
+
+func (repo accountRepository) List(filter account.ListAccountsFilter) ([]account.Account, error) {
+	var result = []account.Account{}
+	for _, number := range repo.tx.accountNumbers() {
+		var row, _ = repo.tx.row(number)
+		if filter.Type == "" || row.accountType == filter.Type {
+			result = append(result, repo.tx.account(row))
+		}
+	}
+
+	return result, nil
+}
+
+func (repo accountRepository) Get(accountNum account.AccountNumber) (*account.Account, error) {
+	var row, exists = repo.tx.row(accountNum)
+	if !exists {
+		return nil, nil
+	}
+
+	var acc = repo.tx.account(row)
+	return &acc, nil
+}
+
+func (repo accountRepository) LockForUpdate(accountNums ...account.AccountNumber) ([]account.Account, error) {
+	err := repo.tx.lock(accountNums...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = []account.Account{}
+	for _, number := range repo.tx.locked {
+		for _, accountNum := range accountNums {
+			if number == accountNum {
+				var row, _ = repo.tx.row(number)
+				result = append(result, repo.tx.account(row))
+				break
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Number < result[j].Number })
+	return result, nil
+}
+
+func (repo accountRepository) Insert(acc account.Account) (account.AccountNumber, error) {
+	if acc.Type == account.AccountTypeSettlement {
+		for _, number := range repo.tx.accountNumbers() {
+			if row, _ := repo.tx.row(number); row.accountType == account.AccountTypeSettlement {
+				return 0, servErr.ErrDatabaseError(errSettlementAccountExists)
+			}
+		}
+	}
+
+	// Account numbers are not reused, even if unit of work is rolled back
+	var store = repo.tx.store
+	store.mutex.Lock()
+	store.lastAccountNumber++
+	var number = store.lastAccountNumber
+	store.mutex.Unlock()
+
+	repo.tx.accounts[number] = accountRow{
+		number:      number,
+		balance:     acc.Balance,
+		creditLimit: acc.CreditLimit,
+		accountType: acc.Type,
+	}
+
+	return number, repo.tx.lock(number)
+}
+
+func (repo accountRepository) AdjustBalance(accountNum account.AccountNumber, amount int64) error {
+	err := repo.tx.lock(accountNum)
+	if err != nil {
+		return err
+	}
+
+	row, exists := repo.tx.row(accountNum)
+	if !exists {
+		return account.ErrInvalidAccount(accountNum)
+	}
+
+	row.balance += amount
+	repo.tx.accounts[accountNum] = row
+	return nil
+}
+
+func (repo accountRepository) SetCreditLimit(accountNum account.AccountNumber, oldLimit, newLimit int64, reason string) error {
+	err := repo.tx.lock(accountNum)
+	if err != nil {
+		return err
+	}
+
+	row, exists := repo.tx.row(accountNum)
+	if !exists {
+		return nil
+	}
+
+	row.creditLimit = newLimit
+	repo.tx.accounts[accountNum] = row
+	repo.tx.creditLimitChanges = append(repo.tx.creditLimitChanges, creditLimitChange{
+		accountNum: accountNum,
+		oldLimit:   oldLimit,
+		newLimit:   newLimit,
+		reason:     reason,
+		changedAt:  repo.tx.startedAt,
+	})
+
+	return nil
+}
+
+func (repo accountRepository) SetHot(accountNum account.AccountNumber, hot bool) error {
+	err := repo.tx.lock(accountNum)
+	if err != nil {
+		return err
+	}
+
+	row, exists := repo.tx.row(accountNum)
+	if !exists {
+		return nil
+	}
+
+	row.hot = hot
+	repo.tx.accounts[accountNum] = row
+	return nil
+}
+
+func (repo accountRepository) AddPendingCredit(accountNum account.AccountNumber, amount int64) error {
+	if _, exists := repo.tx.row(accountNum); !exists {
+		return account.ErrInvalidAccount(accountNum)
+	}
+
+	repo.tx.credits[accountNum] += amount
+	return nil
+}
+
+func (repo accountRepository) ApplyPendingCredits(accountNum account.AccountNumber) (int64, error) {
+	err := repo.tx.lock(accountNum)
+	if err != nil {
+		return 0, err
+	}
+
+	row, exists := repo.tx.row(accountNum)
+	if !exists {
+		return 0, account.ErrInvalidAccount(accountNum)
+	}
+
+	// Credits committed by other transactions are subtracted from store on save,
+	// credits of this transaction are not added to store at all
+	var amount = repo.tx.pendingCredit(accountNum)
+	repo.tx.applied[accountNum] += amount - repo.tx.credits[accountNum]
+	repo.tx.credits[accountNum] = 0
+
+	row.balance += amount
+	repo.tx.accounts[accountNum] = row
+	return amount, nil
+}
+
+func (repo accountRepository) ListWithPendingCredits(limit int) ([]account.AccountNumber, error) {
+	var result = []account.AccountNumber{}
+	for _, number := range repo.tx.accountNumbers() {
+		if len(result) >= limit {
+			break
+		}
+
+		if repo.tx.pendingCredit(number) != 0 {
+			result = append(result, number)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"test/coins/transfer"
+	"time"
+)
+
+var errLockTimeout = errors.New("transaction timed out while waiting for lock")
+
+var errTransactionTimeout = errors.New("transaction timed out")
+
+var errTransactionFinished = errors.New("transaction is already finished")
+
+// Account row
+type accountRow struct {
+	number      account.AccountNumber
+	balance     int64
+	creditLimit int64
+	accountType string
+	limitTier   string
+	hot         bool
+}
+
+// Returns account entity of row
+func (row accountRow) account() account.Account {
+	var acc = account.NewAccount(row.number, row.balance, row.creditLimit)
+	acc.Type = row.accountType
+	acc.Hot = row.hot
+	return acc
+}
+
+// Transfer row
+type transferRow struct {
+	id           transfer.TransferId
+	amount       int64
+	source       account.AccountNumber
+	dest         account.AccountNumber
+	transferType string
+	details      transfer.TransferDetails
+	createdAt    time.Time
+}
+
+// Credit limit change audit trail record
+type creditLimitChange struct {
+	accountNum account.AccountNumber
+	oldLimit   int64
+	newLimit   int64
+	reason     string
+	changedAt  time.Time
+}
+
+// In-memory storage of accounts and transfers, used for tests and demo without database.
+// It has the same semantics as Postgres storage: accounts are locked until transaction ends,
+// changes become visible to other transactions only after commit and transfer ids are unique.
+// Events written in transaction are published to publisher right after commit, because there is no outbox to retry them
+type Store struct {
+	mutex sync.RWMutex
+
+	// Committed accounts
+	accounts map[account.AccountNumber]accountRow
+
+	lastAccountNumber account.AccountNumber
+
+	// Account locks, lock is held while value is in channel
+	locks map[account.AccountNumber]chan struct{}
+
+	// Committed transfers in order of commit
+	transfers []transferRow
+
+	// Ids of committed transfers
+	transferIds map[transfer.TransferId]bool
+
+	// Ids of transfers inserted by transactions that are not committed yet
+	reservedIds map[transfer.TransferId]*Tx
+
+	limitTiers map[string]transfer.TransferLimits
+
+	accountLimits map[account.AccountNumber]transfer.TransferLimits
+
+	creditLimitChanges []creditLimitChange
+
+	// Committed pending credits of accounts that are not rolled up yet
+	pendingCredits map[account.AccountNumber]int64
+
+	lastEventId int64
+
+	publisher outbox.Publisher
+
+	timeout time.Duration
+}
+
+// Creates new empty in-memory store
+//	timeout   - transaction timeout, transaction that waits for lock longer is failed
+//	publisher - publisher of committed events, can be nil
+// Returns created store
+func NewStore(timeout time.Duration, publisher outbox.Publisher) *Store {
+	return &Store{
+		accounts:       map[account.AccountNumber]accountRow{},
+		locks:          map[account.AccountNumber]chan struct{}{},
+		transferIds:    map[transfer.TransferId]bool{},
+		reservedIds:    map[transfer.TransferId]*Tx{},
+		limitTiers:     map[string]transfer.TransferLimits{},
+		accountLimits:  map[account.AccountNumber]transfer.TransferLimits{},
+		pendingCredits: map[account.AccountNumber]int64{},
+		publisher:      publisher,
+		timeout:        timeout,
+	}
+}
+
+// Creates new in-memory store with the same demo accounts as initial database migration:
+// two customer accounts and external settlement account
+//	timeout   - transaction timeout
+//	publisher - publisher of committed events, can be nil
+// Returns created store
+func NewDemoStore(timeout time.Duration, publisher outbox.Publisher) *Store {
+	var store = NewStore(timeout, publisher)
+	store.AddAccount(account.AccountTypeCustomer, 10000, 0)
+	store.AddAccount(account.AccountTypeCustomer, 250000, 0)
+	store.AddAccount(account.AccountTypeSettlement, -260000, 0)
+
+	return store
+}
+
+// Adds account. Only one settlement account should be added
+//	accountType - account type
+//	balance     - initial balance
+//	creditLimit - credit limit
+// Returns number of added account
+func (store *Store) AddAccount(accountType string, balance, creditLimit int64) account.AccountNumber {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.lastAccountNumber++
+	store.accounts[store.lastAccountNumber] = accountRow{
+		number:      store.lastAccountNumber,
+		balance:     balance,
+		creditLimit: creditLimit,
+		accountType: accountType,
+	}
+
+	return store.lastAccountNumber
+}
+
+// Sets transfer limits of account tier
+//	tier   - tier name
+//	limits - tier limits
+func (store *Store) SetLimitTier(tier string, limits transfer.TransferLimits) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.limitTiers[tier] = limits
+}
+
+// Sets tier of account
+//	accountNum - account number
+//	tier       - tier name
+func (store *Store) SetAccountTier(accountNum account.AccountNumber, tier string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var row = store.accounts[accountNum]
+	row.limitTier = tier
+	store.accounts[accountNum] = row
+}
+
+// Sets transfer limits of account, that override limits of account tier
+//	accountNum - account number
+//	limits     - account limits
+func (store *Store) SetAccountLimits(accountNum account.AccountNumber, limits transfer.TransferLimits) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.accountLimits[accountNum] = limits
+}
+
+func (store *Store) Begin(ctx context.Context) (db.UnitOfWork, error) {
+	return db.JoinOrBegin(ctx, store.begin)
+}
+
+func (store *Store) Accounts(uow db.UnitOfWork) account.AccountRepository {
+	return accountRepository{txOf(uow)}
+}
+
+func (store *Store) Transfers(uow db.UnitOfWork) transfer.TransferRepository {
+	return transferRepository{txOf(uow)}
+}
+
+// Begins new transaction
+func (store *Store) begin() (db.UnitOfWork, error) {
+	var now = time.Now()
+	return &Tx{
+		store:         store,
+		startedAt:     now.UTC(),
+		deadline:      now.Add(store.timeout),
+		accounts:      map[account.AccountNumber]accountRow{},
+		limitTiers:    map[string]transfer.TransferLimits{},
+		accountLimits: map[account.AccountNumber]transfer.TransferLimits{},
+		credits:       map[account.AccountNumber]int64{},
+		applied:       map[account.AccountNumber]int64{},
+	}, nil
+}
+
+// Adds committed pending credit of account. Should be called with store mutex locked
+//	accountNum - account number
+//	amount     - credited amount, negative for applied credits
+func (store *Store) addPendingCredit(accountNum account.AccountNumber, amount int64) {
+	store.pendingCredits[accountNum] += amount
+	if store.pendingCredits[accountNum] == 0 {
+		delete(store.pendingCredits, accountNum)
+	}
+}
+
+// Returns lock of account
+//	accountNum - account number
+func (store *Store) lockOf(accountNum account.AccountNumber) chan struct{} {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var lock, ok = store.locks[accountNum]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		store.locks[accountNum] = lock
+	}
+
+	return lock
+}
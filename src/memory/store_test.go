@@ -0,0 +1,527 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/memory"
+	"test/coins/outbox"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+func newTransferId() transfer.TransferId {
+	return transfer.TransferId(uuid.New())
+}
+
+// Returns balances of customer accounts
+func balances(t *testing.T, store *memory.Store) map[account.AccountNumber]int64 {
+	accounts, err := account.NewAccountService(store).ListAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var result = map[account.AccountNumber]int64{}
+	for _, acc := range accounts {
+		result[acc.Number] = acc.Balance
+	}
+
+	return result
+}
+
+func Test_TransferMoney_SuccessOnValidDataProvided(t *testing.T) {
+	// Arrange
+	var publisher = outbox.NewMemoryPublisher()
+	var store = memory.NewStore(time.Second, publisher)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var service = transfer.NewTransferService(store)
+	var id = newTransferId()
+
+	// Act
+	err := service.TransferMoney(context.Background(), id, source, dest, 30, transfer.TransferDetails{Memo: "rent"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var result = balances(t, store)
+	if result[source] != 70 || result[dest] != 30 {
+		t.Errorf("balances expected to be 70 and 30, got %d and %d", result[source], result[dest])
+	}
+
+	transfers, err := service.ListTransfers(context.Background(), dest, transfer.ListTransfersFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(transfers) != 1 || transfers[0].Id != id || transfers[0].Direction != transfer.DirectionIncoming || transfers[0].Memo != "rent" {
+		t.Errorf("incoming transfer expected, got %+v", transfers)
+	}
+
+	var events = publisher.Events()
+	if len(events) != 1 || events[0].Type != transfer.EventTransferCompleted {
+		t.Fatalf("single %s event expected, got %+v", transfer.EventTransferCompleted, events)
+	}
+
+	var event transfer.TransferCompletedEvent
+	if err := json.Unmarshal(events[0].Payload, &event); err != nil || event.SourceBalance != 70 || event.DestBalance == nil || *event.DestBalance != 30 {
+		t.Errorf("event expected to contain balances after transfer, got %s", string(events[0].Payload))
+	}
+}
+
+func Test_TransferMoney_CheckForEnoughMoney(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 20)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var service = transfer.NewTransferService(store)
+
+	// Act
+	err := service.TransferMoney(context.Background(), newTransferId(), source, dest, 121, transfer.TransferDetails{})
+
+	// Assert
+	if err != transfer.ErrNotEnoughMoney {
+		t.Errorf("ErrNotEnoughMoney expected, got %v", err)
+	}
+
+	var result = balances(t, store)
+	if result[source] != 100 || result[dest] != 0 {
+		t.Errorf("balances should not be changed, got %d and %d", result[source], result[dest])
+	}
+}
+
+func Test_TransferMoney_DoesNotAllowToCreateTwoTransfersWithSameId(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var service = transfer.NewTransferService(store)
+	var id = newTransferId()
+	err := service.TransferMoney(context.Background(), id, source, dest, 10, transfer.TransferDetails{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	err = service.TransferMoney(context.Background(), id, source, dest, 10, transfer.TransferDetails{})
+
+	// Assert
+	if err != transfer.ErrTransferAlreadyComplete {
+		t.Errorf("ErrTransferAlreadyComplete expected, got %v", err)
+	}
+
+	if result := balances(t, store); result[source] != 90 {
+		t.Errorf("transfer expected to be booked once, source balance is %d", result[source])
+	}
+}
+
+func Test_InsertTransfer_IdReservedByOtherTransaction_ErrTransferAlreadyComplete(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var id = newTransferId()
+	first, _ := store.Begin(context.Background())
+	defer first.Release()
+	second, _ := store.Begin(context.Background())
+	defer second.Release()
+	err := store.Transfers(first).Insert(id, source, dest, 10, transfer.TransferTypeTransfer, transfer.TransferDetails{}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	err = store.Transfers(second).Insert(id, source, dest, 10, transfer.TransferTypeTransfer, transfer.TransferDetails{}, time.Now().UTC())
+
+	// Assert
+	if err != transfer.ErrTransferAlreadyComplete {
+		t.Errorf("ErrTransferAlreadyComplete expected, got %v", err)
+	}
+}
+
+func Test_Release_ChangesAreRolledBack(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var id = newTransferId()
+	tx, _ := store.Begin(context.Background())
+	var accounts = store.Accounts(tx)
+	if err := accounts.AdjustBalance(source, -40); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := accounts.AdjustBalance(dest, 40); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := store.Transfers(tx).Insert(id, source, dest, 40, transfer.TransferTypeTransfer, transfer.TransferDetails{}, time.Now().UTC()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	tx.Release()
+
+	// Assert
+	if result := balances(t, store); result[source] != 100 || result[dest] != 0 {
+		t.Errorf("balances should not be changed, got %d and %d", result[source], result[dest])
+	}
+
+	// Transfer id and account locks are released, so transfer with the same id can be made
+	err := transfer.NewTransferService(store).TransferMoney(context.Background(), id, source, dest, 40, transfer.TransferDetails{})
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_LockAccounts_AccountLockedByOtherTransaction_TimeoutError(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Millisecond*50, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	first, _ := store.Begin(context.Background())
+	defer first.Release()
+	_, err := store.Accounts(first).LockForUpdate(dest, source)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	err = transfer.NewTransferService(store).TransferMoney(context.Background(), newTransferId(), source, dest, 10, transfer.TransferDetails{})
+
+	// Assert
+	var serviceErr servErr.ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.Kind() != servErr.ErrorKindDB {
+		t.Errorf("database error expected, got %v", err)
+	}
+}
+
+func Test_TransferMoney_ConcurrentTransfers_MoneyIsConserved(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second*10, nil)
+	var first = store.AddAccount(account.AccountTypeCustomer, 1000, 0)
+	var second = store.AddAccount(account.AccountTypeCustomer, 1000, 0)
+	var service = transfer.NewTransferService(store)
+	var wg sync.WaitGroup
+
+	// Act
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			service.TransferMoney(context.Background(), newTransferId(), first, second, 7, transfer.TransferDetails{})
+		}()
+		go func() {
+			defer wg.Done()
+			service.TransferMoney(context.Background(), newTransferId(), second, first, 3, transfer.TransferDetails{})
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	var result = balances(t, store)
+	if result[first] != 600 || result[second] != 1400 {
+		t.Errorf("balances expected to be 600 and 1400, got %d and %d", result[first], result[second])
+	}
+}
+
+func Test_Deposit_BookedFromSettlementAccount(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second, nil)
+	var service = transfer.NewTransferService(store)
+	var before = balances(t, store)
+
+	// Act
+	err := service.Deposit(context.Background(), newTransferId(), 1, 500)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if result := balances(t, store); result[1] != before[1]+500 {
+		t.Errorf("balance expected to be %d, got %d", before[1]+500, result[1])
+	}
+}
+
+func Test_TransferMoney_CheckForDailyCountLimit(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var maxCount int64 = 1
+	store.SetLimitTier("basic", transfer.TransferLimits{MaxDailyCount: &maxCount})
+	store.SetAccountTier(source, "basic")
+	var service = transfer.NewTransferService(store)
+	err := service.TransferMoney(context.Background(), newTransferId(), source, dest, 10, transfer.TransferDetails{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	err = service.TransferMoney(context.Background(), newTransferId(), source, dest, 10, transfer.TransferDetails{})
+
+	// Assert
+	if err == nil {
+		t.Errorf("limit error expected")
+	}
+}
+
+func Test_SetAccountLimits_AccountLimitsOverrideTierLimits(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 1000, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var tierMaxAmount int64 = 100
+	var accountMaxAmount int64 = 300
+	var service = transfer.NewTransferService(store)
+	err := service.SetLimitTier(context.Background(), "basic", transfer.TransferLimits{MaxTransferAmount: &tierMaxAmount})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	limits, err := service.SetAccountLimits(context.Background(), source, "basic", transfer.TransferLimits{MaxTransferAmount: &accountMaxAmount})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if limits.MaxTransferAmount == nil || *limits.MaxTransferAmount != accountMaxAmount {
+		t.Errorf("account limit expected to override tier limit, got %+v", limits)
+	}
+
+	if err = service.TransferMoney(context.Background(), newTransferId(), source, dest, 200, transfer.TransferDetails{}); err != nil {
+		t.Errorf("transfer within account limit expected to succeed, got %v", err)
+	}
+
+	if err = service.TransferMoney(context.Background(), newTransferId(), source, dest, 400, transfer.TransferDetails{}); err == nil {
+		t.Errorf("limit error expected")
+	}
+}
+
+func Test_SetAccountLimits_UnknownTier_LimitsAreNotChanged(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 1000, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var maxAmount int64 = 100
+	var service = transfer.NewTransferService(store)
+
+	// Act
+	_, err := service.SetAccountLimits(context.Background(), source, "premium", transfer.TransferLimits{MaxTransferAmount: &maxAmount})
+
+	// Assert
+	if !errors.Is(err, transfer.ErrLimitTierNotFound("premium")) {
+		t.Fatalf("limit tier not found error expected, got %v", err)
+	}
+
+	if err = service.TransferMoney(context.Background(), newTransferId(), source, dest, 200, transfer.TransferDetails{}); err != nil {
+		t.Errorf("limits should not be changed, got %v", err)
+	}
+}
+
+func Test_SetCreditLimit_CreditLimitIsChanged(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var accountNum = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var service = account.NewAccountService(store)
+
+	// Act
+	updated, err := service.SetCreditLimit(context.Background(), accountNum, 500, "salary")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	accounts, _ := service.ListAccounts(context.Background())
+	if updated.CreditLimit != 500 || len(accounts) != 1 || accounts[0].CreditLimit != 500 {
+		t.Errorf("credit limit expected to be 500, got %+v", accounts)
+	}
+}
+
+func Test_RunInUnitOfWork_CreateAccountAndFundIt(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second, nil)
+	var accounts = account.NewAccountService(store)
+	var transfers = transfer.NewTransferService(store)
+	var created *account.Account
+
+	// Act
+	err := db.RunInUnitOfWork(context.Background(), func() (db.UnitOfWork, error) { return store.Begin(context.Background()) }, func(ctx context.Context) error {
+		var err error
+		created, err = accounts.CreateAccount(ctx)
+		if err != nil {
+			return err
+		}
+
+		return transfers.TransferMoney(ctx, newTransferId(), 1, created.Number, 700, transfer.TransferDetails{})
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if result := balances(t, store); result[created.Number] != 700 || result[1] != 10000-700 {
+		t.Errorf("created account expected to be funded, got balances %v", result)
+	}
+}
+
+func Test_RunInUnitOfWork_FundingFailed_AccountIsNotCreated(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second, nil)
+	var accounts = account.NewAccountService(store)
+	var transfers = transfer.NewTransferService(store)
+
+	// Act
+	err := db.RunInUnitOfWork(context.Background(), func() (db.UnitOfWork, error) { return store.Begin(context.Background()) }, func(ctx context.Context) error {
+		created, err := accounts.CreateAccount(ctx)
+		if err != nil {
+			return err
+		}
+
+		return transfers.TransferMoney(ctx, newTransferId(), 1, created.Number, 1000000, transfer.TransferDetails{})
+	})
+
+	// Assert
+	if err != transfer.ErrNotEnoughMoney {
+		t.Fatalf("ErrNotEnoughMoney expected, got %v", err)
+	}
+
+	if result := balances(t, store); len(result) != 2 {
+		t.Errorf("account should not be created, got balances %v", result)
+	}
+}
+
+func Test_Nested_ReleasedWithoutSave_OnlyNestedChangesRolledBack(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second, nil)
+	var transfers = transfer.NewTransferService(store)
+	uow, _ := store.Begin(context.Background())
+	defer uow.Release()
+	var ctx = db.WithUnitOfWork(context.Background(), uow)
+	var failedId = newTransferId()
+	err := transfers.TransferMoney(ctx, newTransferId(), 1, 2, 100, transfer.TransferDetails{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	nested, _ := store.Begin(ctx)
+	err = store.Accounts(nested).AdjustBalance(1, -50)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err = store.Transfers(nested).Insert(failedId, 1, 2, 50, transfer.TransferTypeTransfer, transfer.TransferDetails{}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	nested.Release()
+	err = uow.Save()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if result := balances(t, store); result[1] != 10000-100 || result[2] != 250000+100 {
+		t.Errorf("only transfer made before savepoint expected to be saved, got balances %v", result)
+	}
+
+	// Id of rolled back transfer is not reserved anymore
+	err = transfers.TransferMoney(context.Background(), failedId, 1, 2, 50, transfer.TransferDetails{})
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_TransferMoney_HotDestAccount_CreditedWhileAccountIsLocked(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 50, 0)
+	var accounts = account.NewAccountService(store)
+	var transfers = transfer.NewTransferService(store)
+	_, err := accounts.SetHot(context.Background(), dest, true)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Dest account is locked by other unit of work until transfer is complete
+	locking, _ := store.Begin(context.Background())
+	_, err = store.Accounts(locking).LockForUpdate(dest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Act
+	err = transfers.TransferMoney(context.Background(), newTransferId(), source, dest, 30, transfer.TransferDetails{})
+	locking.Release()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("transfer to hot account should not wait for its lock, got %v", err)
+	}
+
+	if result := balances(t, store); result[source] != 70 || result[dest] != 80 {
+		t.Errorf("balances expected to be 70 and 80 including pending credit, got %v", result)
+	}
+}
+
+func Test_RollUpPendingCredits_CreditsMovedToBalance(t *testing.T) {
+	// Arrange
+	var store = memory.NewStore(time.Second, nil)
+	var source = store.AddAccount(account.AccountTypeCustomer, 100, 0)
+	var dest = store.AddAccount(account.AccountTypeCustomer, 0, 0)
+	var accounts = account.NewAccountService(store)
+	var transfers = transfer.NewTransferService(store)
+	accounts.SetHot(context.Background(), dest, true)
+	for i := 0; i < 3; i++ {
+		err := transfers.TransferMoney(context.Background(), newTransferId(), source, dest, 20, transfer.TransferDetails{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	// Act
+	rolledUp, err := accounts.RollUpPendingCredits(context.Background(), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if rolledUp != 1 {
+		t.Errorf("pending credits of 1 account expected to be rolled up, got %d", rolledUp)
+	}
+
+	if result := balances(t, store); result[source] != 40 || result[dest] != 60 {
+		t.Errorf("balances expected to be 40 and 60, got %v", result)
+	}
+
+	rolledUp, _ = accounts.RollUpPendingCredits(context.Background(), 10)
+	if rolledUp != 0 {
+		t.Errorf("no pending credits expected to be left, got %d rolled up accounts", rolledUp)
+	}
+
+	// Debit of hot account is checked against balance with all credits
+	err = transfers.TransferMoney(context.Background(), newTransferId(), dest, source, 61, transfer.TransferDetails{})
+	if err != transfer.ErrNotEnoughMoney {
+		t.Errorf("ErrNotEnoughMoney expected, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"sort"
+	"test/coins/account"
+	"test/coins/transfer"
+	"time"
+
+	servErr "test/coins/errors"
+)
+
+// Transfer repository of in-memory unit of work
+type transferRepository struct {
+	tx *Tx
+}
+
+func (repo transferRepository) Exists(id transfer.TransferId) (bool, error) {
+	var found = repo.tx.transfersWhere(func(row transferRow) bool { return row.id == id })
+	return len(found) > 0, nil
+}
+
+func (repo transferRepository) ReadLimits(accountNum account.AccountNumber) (transfer.TransferLimits, error) {
+	var row, _ = repo.tx.row(accountNum)
+	var limits = repo.tx.accountLimitsOf(accountNum)
+	var tierLimits, _ = repo.tx.tierLimits(row.limitTier)
+	return transfer.TransferLimits{
+		MaxTransferAmount: coalesce(limits.MaxTransferAmount, tierLimits.MaxTransferAmount),
+		MaxDailyAmount:    coalesce(limits.MaxDailyAmount, tierLimits.MaxDailyAmount),
+		MaxMonthlyAmount:  coalesce(limits.MaxMonthlyAmount, tierLimits.MaxMonthlyAmount),
+		MaxDailyCount:     coalesce(limits.MaxDailyCount, tierLimits.MaxDailyCount),
+	}, nil
+}
+
+func (repo transferRepository) LimitTierExists(tier string) (bool, error) {
+	var _, exists = repo.tx.tierLimits(tier)
+	return exists, nil
+}
+
+func (repo transferRepository) SaveLimitTier(tier string, limits transfer.TransferLimits) error {
+	repo.tx.limitTiers[tier] = limits
+	return nil
+}
+
+func (repo transferRepository) SaveAccountLimits(accountNum account.AccountNumber, tier string, limits transfer.TransferLimits) error {
+	err := repo.tx.lock(accountNum)
+	if err != nil {
+		return err
+	}
+
+	row, exists := repo.tx.row(accountNum)
+	if !exists {
+		return nil
+	}
+
+	row.limitTier = tier
+	repo.tx.accounts[accountNum] = row
+	repo.tx.accountLimits[accountNum] = limits
+	return nil
+}
+
+func (repo transferRepository) ReadUsage(accountNum account.AccountNumber, dayStart, monthStart time.Time) (transfer.LimitsUsage, error) {
+	var usage = transfer.LimitsUsage{}
+	var transfers = repo.tx.transfersWhere(func(row transferRow) bool {
+		return row.source == accountNum && !row.createdAt.Before(monthStart)
+	})
+
+	for _, row := range transfers {
+		usage.MonthlyAmount += row.amount
+		if !row.createdAt.Before(dayStart) {
+			usage.DailyAmount += row.amount
+			usage.DailyCount++
+		}
+	}
+
+	return usage, nil
+}
+
+func (repo transferRepository) Insert(id transfer.TransferId, source, dest account.AccountNumber, amount uint64, transferType string, details transfer.TransferDetails, createdAt time.Time) error {
+	if !repo.tx.reserveTransferId(id) {
+		return transfer.ErrTransferAlreadyComplete
+	}
+
+	var metadata map[string]string
+	if len(details.Metadata) > 0 {
+		metadata = make(map[string]string, len(details.Metadata))
+		for key, value := range details.Metadata {
+			metadata[key] = value
+		}
+	}
+
+	repo.tx.transfers = append(repo.tx.transfers, transferRow{
+		id:           id,
+		amount:       int64(amount),
+		source:       source,
+		dest:         dest,
+		transferType: transferType,
+		details:      transfer.TransferDetails{Memo: details.Memo, ExternalReference: details.ExternalReference, Metadata: metadata},
+		createdAt:    createdAt.UTC(),
+	})
+
+	return nil
+}
+
+func (repo transferRepository) List(accountNum account.AccountNumber, filter transfer.ListTransfersFilter) ([]transfer.Transfer, error) {
+	var rows = repo.tx.transfersWhere(func(row transferRow) bool {
+		if row.source != accountNum && row.dest != accountNum {
+			return false
+		}
+
+		return filter.ExternalReference == "" || row.details.ExternalReference == filter.ExternalReference
+	})
+
+	// Rows are in order of commit, they are reversed first so transfers with the same timestamp are returned latest first too
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].createdAt.After(rows[j].createdAt) })
+
+	var result = make([]transfer.Transfer, 0, len(rows))
+	for _, row := range rows {
+		var event = transfer.TransferCompletedEvent{
+			Id:                row.id,
+			Type:              row.transferType,
+			Source:            row.source,
+			Dest:              row.dest,
+			Amount:            row.amount,
+			Memo:              row.details.Memo,
+			ExternalReference: row.details.ExternalReference,
+			Metadata:          row.details.Metadata,
+			CreatedAt:         row.createdAt,
+		}
+		result = append(result, event.TransferFor(accountNum))
+	}
+
+	return result, nil
+}
+
+func (repo transferRepository) WriteEvent(eventType string, payload interface{}) error {
+	err := repo.tx.writeEvent(eventType, payload)
+	if err != nil {
+		return servErr.ErrDatabaseError(err)
+	}
+
+	return nil
+}
+
+// Returns first limit that is set
+func coalesce(values ...*int64) *int64 {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,429 @@
+package memory
+
+import (
+	"encoding/json"
+	"sort"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"test/coins/transfer"
+	"time"
+
+	servErr "test/coins/errors"
+)
+
+// Event written in transaction
+type pendingEvent struct {
+	eventType string
+	payload   []byte
+}
+
+// Transaction of in-memory store, unit of work of memory backend. Changes are kept in transaction until it is saved.
+// It is not thread safe
+type Tx struct {
+	store *Store
+
+	// Transaction start time, used as created timestamp of rows
+	startedAt time.Time
+
+	deadline time.Time
+
+	// Locked accounts
+	locked []account.AccountNumber
+
+	// Changed accounts
+	accounts map[account.AccountNumber]accountRow
+
+	transfers []transferRow
+
+	creditLimitChanges []creditLimitChange
+
+	// Changed limit tiers and account limits
+	limitTiers    map[string]transfer.TransferLimits
+	accountLimits map[account.AccountNumber]transfer.TransferLimits
+
+	// Pending credits added by transaction
+	credits map[account.AccountNumber]int64
+
+	// Committed pending credits moved to account balance by transaction
+	applied map[account.AccountNumber]int64
+
+	events []pendingEvent
+
+	finished bool
+}
+
+func (tx *Tx) Release() error {
+	tx.finish()
+	return nil
+}
+
+func (tx *Tx) Save() error {
+	if tx.finished {
+		return servErr.ErrDatabaseError(errTransactionFinished)
+	}
+
+	defer tx.finish()
+
+	if time.Now().After(tx.deadline) {
+		return servErr.ErrDatabaseError(errTransactionTimeout)
+	}
+
+	var store = tx.store
+	var events = make([]outbox.Event, 0, len(tx.events))
+	store.mutex.Lock()
+	for number, row := range tx.accounts {
+		store.accounts[number] = row
+	}
+
+	for _, row := range tx.transfers {
+		store.transfers = append(store.transfers, row)
+		store.transferIds[row.id] = true
+		delete(store.reservedIds, row.id)
+	}
+
+	store.creditLimitChanges = append(store.creditLimitChanges, tx.creditLimitChanges...)
+
+	for tier, limits := range tx.limitTiers {
+		store.limitTiers[tier] = limits
+	}
+
+	for number, limits := range tx.accountLimits {
+		store.accountLimits[number] = limits
+	}
+
+	for number, amount := range tx.applied {
+		store.addPendingCredit(number, -amount)
+	}
+
+	for number, amount := range tx.credits {
+		store.addPendingCredit(number, amount)
+	}
+
+	for _, event := range tx.events {
+		store.lastEventId++
+		events = append(events, outbox.Event{Id: store.lastEventId, Type: event.eventType, Payload: event.payload, CreatedAt: time.Now().UTC()})
+	}
+	store.mutex.Unlock()
+
+	// Events are published while accounts are still locked, so events of each account are published in order
+	if store.publisher != nil {
+		for _, event := range events {
+			store.publisher.Publish(event)
+		}
+	}
+
+	return nil
+}
+
+// Releases locks and reserved transfer ids of transaction
+func (tx *Tx) finish() {
+	if tx.finished {
+		return
+	}
+
+	tx.finished = true
+
+	var store = tx.store
+	store.mutex.Lock()
+	for _, row := range tx.transfers {
+		if store.reservedIds[row.id] == tx {
+			delete(store.reservedIds, row.id)
+		}
+	}
+	store.mutex.Unlock()
+
+	for _, number := range tx.locked {
+		<-store.lockOf(number)
+	}
+	tx.locked = nil
+}
+
+// Locks accounts until transaction ends. Accounts are locked in order of their numbers, so transactions
+// that lock the same accounts don't deadlock. Accounts that don't exist are not locked
+//	numbers - account numbers
+func (tx *Tx) lock(numbers ...account.AccountNumber) error {
+	if tx.finished {
+		return servErr.ErrDatabaseError(errTransactionFinished)
+	}
+
+	var sorted = append([]account.AccountNumber{}, numbers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, number := range sorted {
+		if _, exists := tx.row(number); !exists || tx.holds(number) {
+			continue
+		}
+
+		var timer = time.NewTimer(time.Until(tx.deadline))
+		select {
+		case tx.store.lockOf(number) <- struct{}{}:
+			timer.Stop()
+			tx.locked = append(tx.locked, number)
+		case <-timer.C:
+			return servErr.ErrDatabaseError(errLockTimeout)
+		}
+	}
+
+	return nil
+}
+
+// Checks if transaction holds lock of account
+//	number - account number
+func (tx *Tx) holds(number account.AccountNumber) bool {
+	for _, locked := range tx.locked {
+		if locked == number {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns account as it is seen by transaction
+//	number - account number
+// Returns account row and flag if account exists
+func (tx *Tx) row(number account.AccountNumber) (accountRow, bool) {
+	if row, ok := tx.accounts[number]; ok {
+		return row, true
+	}
+
+	tx.store.mutex.RLock()
+	defer tx.store.mutex.RUnlock()
+
+	row, ok := tx.store.accounts[number]
+	return row, ok
+}
+
+// Returns account entity of row, balance includes pending credits seen by transaction
+//	row - account row
+func (tx *Tx) account(row accountRow) account.Account {
+	row.balance += tx.pendingCredit(row.number)
+	return row.account()
+}
+
+// Returns sum of pending credits of account as it is seen by transaction
+//	number - account number
+func (tx *Tx) pendingCredit(number account.AccountNumber) int64 {
+	tx.store.mutex.RLock()
+	var committed = tx.store.pendingCredits[number]
+	tx.store.mutex.RUnlock()
+
+	return committed - tx.applied[number] + tx.credits[number]
+}
+
+// Returns numbers of accounts as they are seen by transaction, in ascending order
+func (tx *Tx) accountNumbers() []account.AccountNumber {
+	var numbers = []account.AccountNumber{}
+	tx.store.mutex.RLock()
+	for number := range tx.store.accounts {
+		numbers = append(numbers, number)
+	}
+
+	// Accounts inserted by transaction are not committed yet
+	for number := range tx.accounts {
+		if _, committed := tx.store.accounts[number]; !committed {
+			numbers = append(numbers, number)
+		}
+	}
+	tx.store.mutex.RUnlock()
+
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// Returns transfers as they are seen by transaction
+//	filter - function that returns true for transfers that should be returned
+func (tx *Tx) transfersWhere(filter func(row transferRow) bool) []transferRow {
+	var result = []transferRow{}
+
+	tx.store.mutex.RLock()
+	for _, row := range tx.store.transfers {
+		if filter(row) {
+			result = append(result, row)
+		}
+	}
+	tx.store.mutex.RUnlock()
+
+	for _, row := range tx.transfers {
+		if filter(row) {
+			result = append(result, row)
+		}
+	}
+
+	return result
+}
+
+// Returns limits of tier as they are seen by transaction
+//	tier - tier name
+// Returns tier limits and flag if tier exists
+func (tx *Tx) tierLimits(tier string) (transfer.TransferLimits, bool) {
+	if limits, ok := tx.limitTiers[tier]; ok {
+		return limits, true
+	}
+
+	tx.store.mutex.RLock()
+	defer tx.store.mutex.RUnlock()
+
+	limits, ok := tx.store.limitTiers[tier]
+	return limits, ok
+}
+
+// Returns limits set for account itself as they are seen by transaction
+//	number - account number
+func (tx *Tx) accountLimitsOf(number account.AccountNumber) transfer.TransferLimits {
+	if limits, ok := tx.accountLimits[number]; ok {
+		return limits
+	}
+
+	tx.store.mutex.RLock()
+	defer tx.store.mutex.RUnlock()
+
+	return tx.store.accountLimits[number]
+}
+
+// Writes event that is published after commit
+//	eventType - event type
+//	payload   - event payload, serialized to JSON
+func (tx *Tx) writeEvent(eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	tx.events = append(tx.events, pendingEvent{eventType, data})
+	return nil
+}
+
+// Reserves transfer id until transaction ends
+//	id - transfer id
+// Returns false if transfer with the same id is committed or inserted by other transaction
+func (tx *Tx) reserveTransferId(id transfer.TransferId) bool {
+	tx.store.mutex.Lock()
+	defer tx.store.mutex.Unlock()
+
+	if owner, reserved := tx.store.reservedIds[id]; tx.store.transferIds[id] || (reserved && owner != tx) {
+		return false
+	}
+
+	tx.store.reservedIds[id] = tx
+	return true
+}
+
+func (tx *Tx) Nested() (db.UnitOfWork, error) {
+	if tx.finished {
+		return nil, servErr.ErrDatabaseError(errTransactionFinished)
+	}
+
+	var accounts = make(map[account.AccountNumber]accountRow, len(tx.accounts))
+	for number, row := range tx.accounts {
+		accounts[number] = row
+	}
+
+	var limitTiers = make(map[string]transfer.TransferLimits, len(tx.limitTiers))
+	for tier, limits := range tx.limitTiers {
+		limitTiers[tier] = limits
+	}
+
+	var accountLimits = make(map[account.AccountNumber]transfer.TransferLimits, len(tx.accountLimits))
+	for number, limits := range tx.accountLimits {
+		accountLimits[number] = limits
+	}
+
+	return &savepoint{
+		tx:                 tx,
+		accounts:           accounts,
+		limitTiers:         limitTiers,
+		accountLimits:      accountLimits,
+		credits:            copyAmounts(tx.credits),
+		applied:            copyAmounts(tx.applied),
+		transfers:          len(tx.transfers),
+		creditLimitChanges: len(tx.creditLimitChanges),
+		events:             len(tx.events),
+	}, nil
+}
+
+// Savepoint of in-memory transaction, nested unit of work of memory backend.
+// Accounts locked after savepoint stay locked until transaction ends
+type savepoint struct {
+	tx *Tx
+
+	// Changed accounts at the moment savepoint was created
+	accounts map[account.AccountNumber]accountRow
+
+	// Changed limit tiers and account limits at the moment savepoint was created
+	limitTiers    map[string]transfer.TransferLimits
+	accountLimits map[account.AccountNumber]transfer.TransferLimits
+
+	// Added and applied pending credits at the moment savepoint was created
+	credits map[account.AccountNumber]int64
+	applied map[account.AccountNumber]int64
+
+	// Number of transfers, credit limit changes and events at the moment savepoint was created
+	transfers          int
+	creditLimitChanges int
+	events             int
+
+	finished bool
+}
+
+func (sp *savepoint) Release() error {
+	if sp.finished {
+		return nil
+	}
+
+	sp.finished = true
+
+	var tx = sp.tx
+	tx.store.mutex.Lock()
+	for _, row := range tx.transfers[sp.transfers:] {
+		if tx.store.reservedIds[row.id] == tx {
+			delete(tx.store.reservedIds, row.id)
+		}
+	}
+	tx.store.mutex.Unlock()
+
+	tx.accounts = sp.accounts
+	tx.limitTiers = sp.limitTiers
+	tx.accountLimits = sp.accountLimits
+	tx.credits = sp.credits
+	tx.applied = sp.applied
+	tx.transfers = tx.transfers[:sp.transfers]
+	tx.creditLimitChanges = tx.creditLimitChanges[:sp.creditLimitChanges]
+	tx.events = tx.events[:sp.events]
+	return nil
+}
+
+func (sp *savepoint) Save() error {
+	if sp.tx.finished {
+		return servErr.ErrDatabaseError(errTransactionFinished)
+	}
+
+	sp.finished = true
+	return nil
+}
+
+func (sp *savepoint) Nested() (db.UnitOfWork, error) {
+	return sp.tx.Nested()
+}
+
+// Returns copy of amounts by account
+//	amounts - amounts by account
+func copyAmounts(amounts map[account.AccountNumber]int64) map[account.AccountNumber]int64 {
+	var result = make(map[account.AccountNumber]int64, len(amounts))
+	for number, amount := range amounts {
+		result[number] = amount
+	}
+
+	return result
+}
+
+// Returns transaction of unit of work of memory backend
+//	uow - transaction or savepoint
+func txOf(uow db.UnitOfWork) *Tx {
+	if sp, ok := uow.(*savepoint); ok {
+		return sp.tx
+	}
+
+	return uow.(*Tx)
+}
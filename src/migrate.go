@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"test/coins/config"
+	"test/coins/db"
+	"test/coins/migrations"
+	"time"
+)
+
+// Usage of migrate command
+const migrateUsage = "usage: coins migrate up|down|status [flags]"
+
+// Runs "migrate" command
+//	args - command arguments: action followed by configuration flags
+//	out  - writer for command output
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var action = args[0]
+	if action != "up" && action != "down" && action != "status" {
+		return fmt.Errorf("unknown migrate action [%s], %s", action, migrateUsage)
+	}
+
+	cfg, err := config.Load("coins migrate "+action, args[1:], os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Database.Backend() == config.BackendMemory {
+		return errors.New("memory backend has no schema to migrate")
+	}
+
+	cnPool, err := db.NewConnectionPool(cfg.Database.ConnectionString, 1, cfg.Database.AcquireTimeout.Std())
+	if err != nil {
+		return fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	defer cnPool.Close()
+
+	migrator, err := newMigrator(cnPool, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d %s\n", migration.Version, migration.Name)
+		}
+
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+
+		return err
+	case "down":
+		migration, err := migrator.Down()
+		if migration != nil {
+			fmt.Fprintf(out, "rolled back %04d %s\n", migration.Version, migration.Name)
+		} else if err == nil {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+
+		return err
+	default:
+		statuses, err := migrator.Status()
+		for _, status := range statuses {
+			switch {
+			case status.Unknown:
+				fmt.Fprintf(out, "%04d %-40s applied %s, unknown to application\n", status.Version, "?", status.AppliedAt.Format(time.RFC3339))
+			case status.AppliedAt != nil:
+				fmt.Fprintf(out, "%04d %-40s applied %s\n", status.Version, status.Name, status.AppliedAt.Format(time.RFC3339))
+			default:
+				fmt.Fprintf(out, "%04d %-40s pending\n", status.Version, status.Name)
+			}
+		}
+
+		return err
+	}
+}
+
+// Creates migrator of embedded migrations
+//	cnPool - connection pool
+//	cfg    - configuration
+// Returns created migrator
+func newMigrator(cnPool db.ConnectionPool, cfg config.Config) (*migrations.Migrator, error) {
+	all, err := migrations.Load()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load migrations: %w", err)
+	}
+
+	return migrations.NewMigrator(func() (db.DbContext, error) {
+		return db.CreateContext(cnPool, cfg.Database.MigrationTimeout.Std())
+	}, all), nil
+}
@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration files, named "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+//go:embed sql/*.sql
+var files embed.FS
+
+// Regex that matches migration file name
+var fileNameRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Versioned schema change
+type Migration struct {
+	// Migration version. Migrations are applied in order of versions
+	Version int
+
+	// Migration name
+	Name string
+
+	// Sql script that applies migration
+	Up string
+
+	// Sql script that rolls migration back
+	Down string
+}
+
+// Loads migrations embedded in application
+// Returns migrations sorted by version
+func Load() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(sub)
+}
+
+// Parses migration files. Each migration should have up and down scripts, versions should start from 1 and have no gaps
+//	fsys - file system with migration files
+// Returns migrations sorted by version
+func parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var byVersion = map[int]*Migration{}
+	for _, entry := range entries {
+		var match = fileNameRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name [%s]", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		var migration, ok = byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names [%s] and [%s]", version, migration.Name, match[2])
+		}
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	var result = make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d [%s] should have both up and down scripts", migration.Version, migration.Name)
+		}
+
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	for i, migration := range result {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"test/coins/db"
+	"time"
+
+	servErr "test/coins/errors"
+)
+
+type sqlParams = []interface{}
+
+// Key of advisory lock that prevents concurrent migrations
+const lockKey int64 = 0x636f696e73
+
+// Status of migration in database
+type MigrationStatus struct {
+	Migration
+
+	// Time migration was applied, nil if migration is pending
+	AppliedAt *time.Time
+
+	// Migration is applied in database, but is unknown to application (applied by newer version)
+	Unknown bool
+}
+
+// Migrator applies and rolls back migrations. Applied migrations are recorded in schema_migrations table.
+// Each migration is applied in separate transaction under advisory lock, so several application
+// instances can migrate the same database at the same time
+type Migrator struct {
+	dbContextFactory func() (db.DbContext, error)
+
+	migrations []Migration
+}
+
+// Creates new migrator
+//	dbContextFactory - factory function used to create new db context, transaction timeout should be enough to apply any migration
+//	migrations       - known migrations sorted by version
+// Returns created migrator
+func NewMigrator(dbContextFactory func() (db.DbContext, error), migrations []Migration) *Migrator {
+	return &Migrator{dbContextFactory, migrations}
+}
+
+// Applies all pending migrations
+// Returns applied migrations
+func (migrator *Migrator) Up() ([]Migration, error) {
+	var applied = []Migration{}
+	for {
+		migration, err := migrator.step(func(dbContext db.DbContext, versions map[int]time.Time) (*Migration, error) {
+			for i := range migrator.migrations {
+				var migration = &migrator.migrations[i]
+				if _, ok := versions[migration.Version]; ok {
+					continue
+				}
+
+				_, err := dbContext.Execute(migration.Up)
+				if err != nil {
+					return nil, fmt.Errorf("unable to apply migration %d [%s]: %w", migration.Version, migration.Name, err)
+				}
+
+				_, err = dbContext.Execute(
+					"INSERT INTO public.schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+					migration.Version, migration.Name, time.Now().UTC(),
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				return migration, nil
+			}
+
+			return nil, nil
+		})
+
+		if err != nil || migration == nil {
+			return applied, err
+		}
+
+		applied = append(applied, *migration)
+	}
+}
+
+// Rolls back last applied migration
+// Returns rolled back migration, nil if there are no applied migrations
+func (migrator *Migrator) Down() (*Migration, error) {
+	return migrator.step(func(dbContext db.DbContext, versions map[int]time.Time) (*Migration, error) {
+		var last = 0
+		for version := range versions {
+			if version > last {
+				last = version
+			}
+		}
+
+		if last == 0 {
+			return nil, nil
+		}
+
+		if last > len(migrator.migrations) {
+			return nil, fmt.Errorf("migration %d is unknown to application and can't be rolled back", last)
+		}
+
+		var migration = &migrator.migrations[last-1]
+		_, err := dbContext.Execute(migration.Down)
+		if err != nil {
+			return nil, fmt.Errorf("unable to roll back migration %d [%s]: %w", migration.Version, migration.Name, err)
+		}
+
+		_, err = dbContext.Execute("DELETE FROM public.schema_migrations WHERE version = $1", migration.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		return migration, nil
+	})
+}
+
+// Returns status of known migrations and migrations that are applied, but unknown to application
+func (migrator *Migrator) Status() ([]MigrationStatus, error) {
+	var result = []MigrationStatus{}
+	_, err := migrator.step(func(dbContext db.DbContext, versions map[int]time.Time) (*Migration, error) {
+		for _, migration := range migrator.migrations {
+			var status = MigrationStatus{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+				delete(versions, migration.Version)
+			}
+
+			result = append(result, status)
+		}
+
+		var unknown = []int{}
+		for version := range versions {
+			unknown = append(unknown, version)
+		}
+
+		sort.Ints(unknown)
+		for _, version := range unknown {
+			var appliedAt = versions[version]
+			result = append(result, MigrationStatus{Migration: Migration{Version: version}, AppliedAt: &appliedAt, Unknown: true})
+		}
+
+		return nil, nil
+	})
+
+	return result, err
+}
+
+// Executes migration step in transaction under advisory lock
+//	action - function that gets applied versions and executes step
+// Returns migration returned by action
+func (migrator *Migrator) step(action func(dbContext db.DbContext, versions map[int]time.Time) (*Migration, error)) (*Migration, error) {
+	dbContext, err := migrator.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute("SELECT pg_advisory_xact_lock($1)", lockKey)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dbContext.Execute(
+		"CREATE TABLE IF NOT EXISTS public.schema_migrations (" +
+			"version bigint NOT NULL, name character varying(255) NOT NULL, applied_at timestamp without time zone NOT NULL, " +
+			"CONSTRAINT schema_migrations_pkey PRIMARY KEY (version))",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions = map[int]time.Time{}
+	err = dbContext.Query(
+		"SELECT version, applied_at FROM public.schema_migrations ORDER BY version",
+		sqlParams{},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var version int64
+				var appliedAt time.Time
+				err := rows.Scan(&version, &appliedAt)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				versions[int(version)] = appliedAt
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	migration, err := action(dbContext, versions)
+	if err != nil || migration == nil {
+		return nil, err
+	}
+
+	return migration, dbContext.Save()
+}
@@ -0,0 +1,190 @@
+package migrations_test
+
+import (
+	"strings"
+	"test/coins/db"
+	"test/coins/migrations"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testMigrations = []migrations.Migration{
+	{Version: 1, Name: "create_a", Up: "CREATE TABLE a", Down: "DROP TABLE a"},
+	{Version: 2, Name: "create_b", Up: "CREATE TABLE b", Down: "DROP TABLE b"},
+}
+
+// Creates migrator, each created db context is set up by next setup function
+func setupMigrator(setupMocks ...func(mock sqlmock.Sqlmock)) *migrations.Migrator {
+	var created = 0
+	return migrations.NewMigrator(func() (db.DbContext, error) {
+		var setupMock = setupMocks[created]
+		created++
+		return db.CreateMockDbContext(setupMock)
+	}, testMigrations)
+}
+
+// Returns mock setup for beginning of migration step with applied versions
+func beginStep(dbMock *sqlmock.Sqlmock, applied ...int64) func(mock sqlmock.Sqlmock) {
+	return func(mock sqlmock.Sqlmock) {
+		*dbMock = mock
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS public.schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		var rows = sqlmock.NewRows([]string{"version", "applied_at"})
+		for _, version := range applied {
+			rows.AddRow(version, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+		}
+		mock.ExpectQuery("SELECT version, applied_at FROM public.schema_migrations").WillReturnRows(rows)
+	}
+}
+
+func Test_Load_EmbeddedMigrations_AreValid(t *testing.T) {
+	// Act
+	all, err := migrations.Load()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("embedded migrations expected to be loaded, got error: %s", err.Error())
+	}
+
+	if len(all) == 0 || all[0].Version != 1 {
+		t.Fatalf("first migration expected to have version 1")
+	}
+
+	if !strings.Contains(all[0].Up, "CREATE TABLE IF NOT EXISTS public.accounts") || strings.Contains(all[0].Up, "accounts(amount)") {
+		t.Errorf("initial migration expected to create accounts table with correct seed data")
+	}
+}
+
+func Test_Up_PendingMigrations_AreApplied(t *testing.T) {
+	// Arrange
+	var applyMock, checkMock sqlmock.Sqlmock
+	var migrator = setupMigrator(
+		func(mock sqlmock.Sqlmock) {
+			beginStep(&applyMock, 1)(mock)
+			mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("INSERT INTO public.schema_migrations").
+				WithArgs(2, "create_b", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+		beginStep(&checkMock, 1, 2),
+	)
+
+	// Act
+	applied, err := migrator.Up()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("migrations expected to be applied, got error: %s", err.Error())
+	}
+
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Errorf("only migration 2 expected to be applied, got %v", applied)
+	}
+
+	if err := applyMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	if err := checkMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Up_MigrationFails_ErrorIsReturned(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock
+	var migrator = setupMigrator(
+		func(mock sqlmock.Sqlmock) {
+			beginStep(&dbMock)(mock)
+			mock.ExpectExec("CREATE TABLE a").WillReturnError(sqlmock.ErrCancelled)
+		},
+	)
+
+	// Act
+	applied, err := migrator.Up()
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "create_a") {
+		t.Errorf("error of migration 1 expected, got %v", err)
+	}
+
+	if len(applied) != 0 {
+		t.Errorf("no migrations expected to be applied, got %v", applied)
+	}
+}
+
+func Test_Down_AppliedMigrations_LastIsRolledBack(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock
+	var migrator = setupMigrator(
+		func(mock sqlmock.Sqlmock) {
+			beginStep(&dbMock, 1, 2)(mock)
+			mock.ExpectExec("DROP TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("DELETE FROM public.schema_migrations WHERE version = \\$1").
+				WithArgs(2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		},
+	)
+
+	// Act
+	migration, err := migrator.Down()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("migration expected to be rolled back, got error: %s", err.Error())
+	}
+
+	if migration == nil || migration.Version != 2 {
+		t.Errorf("migration 2 expected to be rolled back, got %v", migration)
+	}
+
+	if err := dbMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Down_UnknownMigrationApplied_ErrorIsReturned(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock
+	var migrator = setupMigrator(beginStep(&dbMock, 1, 2, 3))
+
+	// Act
+	migration, err := migrator.Down()
+
+	// Assert
+	if err == nil || migration != nil {
+		t.Errorf("error expected for unknown migration, got %v and %v", migration, err)
+	}
+}
+
+func Test_Status_AppliedPendingAndUnknownMigrations_AreReported(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock
+	var migrator = setupMigrator(beginStep(&dbMock, 1, 5))
+
+	// Act
+	statuses, err := migrator.Status()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("status expected to be returned, got error: %s", err.Error())
+	}
+
+	if len(statuses) != 3 {
+		t.Fatalf("3 statuses expected, got %d", len(statuses))
+	}
+
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("migration 1 expected to be applied and migration 2 pending, got %+v", statuses[:2])
+	}
+
+	if !statuses[2].Unknown || statuses[2].Version != 5 {
+		t.Errorf("unknown migration 5 expected, got %+v", statuses[2])
+	}
+}
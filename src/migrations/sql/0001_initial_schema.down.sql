@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS public.idempotency_keys;
+DROP TABLE IF EXISTS public.webhook_deliveries;
+DROP TABLE IF EXISTS public.webhook_subscriptions;
+DROP TABLE IF EXISTS public.outbox_events;
+DROP TABLE IF EXISTS public.schedule_occurrences;
+DROP TABLE IF EXISTS public.transfer_schedules;
+DROP TABLE IF EXISTS public.transfers;
+DROP TABLE IF EXISTS public.account_limits;
+DROP TABLE IF EXISTS public.credit_limit_changes;
+DROP TABLE IF EXISTS public.accounts;
+DROP TABLE IF EXISTS public.limit_tiers;
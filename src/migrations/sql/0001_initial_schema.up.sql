@@ -0,0 +1,298 @@
+-- Initial schema. Tables are created only if they don't exist, so migration can be applied
+-- to database that was created by previous version of deploy/create_db.sql
+
+-- limit tiers table. NULL limit value means there is no limit
+CREATE TABLE IF NOT EXISTS public.limit_tiers
+(
+    tier character varying(32) NOT NULL,
+    max_transfer_amount bigint,
+    max_daily_amount bigint,
+    max_monthly_amount bigint,
+    max_daily_count bigint,
+    CONSTRAINT limit_tiers_pkey PRIMARY KEY (tier)
+)
+
+TABLESPACE pg_default;
+
+-- accounts table
+CREATE TABLE IF NOT EXISTS public.accounts
+(
+    account_number bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    balance bigint NOT NULL DEFAULT 0,
+    credit_limit bigint NOT NULL DEFAULT 0,
+    account_type character varying(16) NOT NULL DEFAULT 'customer',
+    limit_tier character varying(32),
+    CONSTRAINT accounts_pkey PRIMARY KEY (account_number),
+    CONSTRAINT accounts_credit_limit_check CHECK (credit_limit >= 0),
+    CONSTRAINT accounts_limit_tiers_fkey FOREIGN KEY (limit_tier)
+        REFERENCES public.limit_tiers (tier) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_accounts_settlement, only one external settlement account is allowed
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_settlement
+    ON public.accounts USING btree
+    (account_type ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE account_type = 'settlement';
+
+-- demo customer accounts, created only in empty database
+INSERT INTO public.accounts(balance)
+	SELECT v.balance FROM (VALUES (10000), (250000)) AS v(balance)
+	WHERE NOT EXISTS (SELECT 1 FROM public.accounts);
+
+-- external settlement account. Its balance is negative amount of money that was deposited into the system,
+-- so total money in accounts is zero
+INSERT INTO public.accounts(balance, account_type)
+	SELECT -COALESCE(SUM(balance), 0), 'settlement' FROM public.accounts
+	HAVING NOT EXISTS (SELECT 1 FROM public.accounts WHERE account_type = 'settlement');
+
+-- credit limit changes audit trail
+CREATE TABLE IF NOT EXISTS public.credit_limit_changes
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_number bigint NOT NULL,
+    old_limit bigint NOT NULL,
+    new_limit bigint NOT NULL,
+    reason text NOT NULL,
+    changed_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT credit_limit_changes_pkey PRIMARY KEY (id),
+    CONSTRAINT credit_limit_changes_accounts_fkey FOREIGN KEY (account_number)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+)
+
+TABLESPACE pg_default;
+
+-- per-account limits table. Overrides limits of account tier, NULL limit value means tier limit is used
+CREATE TABLE IF NOT EXISTS public.account_limits
+(
+    account_number bigint NOT NULL,
+    max_transfer_amount bigint,
+    max_daily_amount bigint,
+    max_monthly_amount bigint,
+    max_daily_count bigint,
+    CONSTRAINT account_limits_pkey PRIMARY KEY (account_number),
+    CONSTRAINT account_limits_accounts_fkey FOREIGN KEY (account_number)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+-- trasnfers table
+CREATE TABLE IF NOT EXISTS public.transfers
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    transfer_id uuid NOT NULL,
+    source_account bigint NOT NULL,
+    dest_account bigint NOT NULL,
+    amount bigint NOT NULL,
+    transfer_type character varying(16) NOT NULL DEFAULT 'transfer',
+    memo character varying(140),
+    external_reference character varying(64),
+    metadata jsonb,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transfers_pkey PRIMARY KEY (id),
+    CONSTRAINT transfers_accounts_dest_fkey FOREIGN KEY (dest_account)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT transfers_accounts_source_fkey FOREIGN KEY (source_account)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_dest_account
+CREATE INDEX IF NOT EXISTS idx_dest_account
+    ON public.transfers USING btree
+    (dest_account ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_source_account
+CREATE INDEX IF NOT EXISTS idx_source_account
+    ON public.transfers USING btree
+    (source_account ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_source_account_created_at, used to calculate transfer limits usage
+CREATE INDEX IF NOT EXISTS idx_source_account_created_at
+    ON public.transfers USING btree
+    (source_account ASC NULLS LAST, created_at ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_transfers_external_reference
+CREATE INDEX IF NOT EXISTS idx_transfers_external_reference
+    ON public.transfers USING btree
+    (external_reference ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_transfers_transaction_id
+CREATE INDEX IF NOT EXISTS idx_transfers_transaction_id
+    ON public.transfers USING btree
+    (transfer_id ASC NULLS LAST)
+    INCLUDE(transfer_id)
+    TABLESPACE pg_default;
+
+-- scheduled transfers table
+CREATE TABLE IF NOT EXISTS public.transfer_schedules
+(
+    schedule_id uuid NOT NULL,
+    source_account bigint NOT NULL,
+    dest_account bigint NOT NULL,
+    amount bigint NOT NULL,
+    memo character varying(140) NOT NULL DEFAULT '',
+    recurrence character varying(128) NOT NULL DEFAULT '',
+    status character varying(16) NOT NULL,
+    next_run_at timestamp without time zone NOT NULL,
+    runs bigint NOT NULL DEFAULT 0,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transfer_schedules_pkey PRIMARY KEY (schedule_id),
+    CONSTRAINT transfer_schedules_accounts_source_fkey FOREIGN KEY (source_account)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT transfer_schedules_accounts_dest_fkey FOREIGN KEY (dest_account)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_transfer_schedules_due, used by scheduler to find due schedules
+CREATE INDEX IF NOT EXISTS idx_transfer_schedules_due
+    ON public.transfer_schedules USING btree
+    (next_run_at ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE status = 'active';
+
+-- scheduled transfer occurrences table
+CREATE TABLE IF NOT EXISTS public.schedule_occurrences
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    schedule_id uuid NOT NULL,
+    scheduled_at timestamp without time zone NOT NULL,
+    transfer_id uuid NOT NULL,
+    status character varying(16) NOT NULL,
+    error text,
+    executed_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT schedule_occurrences_pkey PRIMARY KEY (id),
+    CONSTRAINT schedule_occurrences_schedule_time_key UNIQUE (schedule_id, scheduled_at),
+    CONSTRAINT schedule_occurrences_schedules_fkey FOREIGN KEY (schedule_id)
+        REFERENCES public.transfer_schedules (schedule_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+-- transactional outbox table. Events are written in the same transaction as business data
+CREATE TABLE IF NOT EXISTS public.outbox_events
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    event_type character varying(64) NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at timestamp without time zone,
+    CONSTRAINT outbox_events_pkey PRIMARY KEY (id)
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_outbox_events_unpublished, used by relay to find unpublished events
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished
+    ON public.outbox_events USING btree
+    (id ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE published_at IS NULL;
+
+-- webhook subscriptions table
+CREATE TABLE IF NOT EXISTS public.webhook_subscriptions
+(
+    subscription_id uuid NOT NULL,
+    account_number bigint NOT NULL,
+    url character varying(2048) NOT NULL,
+    secret character varying(256) NOT NULL,
+    event_types character varying(256) NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT webhook_subscriptions_pkey PRIMARY KEY (subscription_id),
+    CONSTRAINT webhook_subscriptions_accounts_fkey FOREIGN KEY (account_number)
+        REFERENCES public.accounts (account_number) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_webhook_subscriptions_account, used by dispatcher to find subscriptions of account
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_account
+    ON public.webhook_subscriptions USING btree
+    (account_number ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE active;
+
+-- webhook deliveries table, also used as delivery log
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    subscription_id uuid NOT NULL,
+    event_id bigint NOT NULL,
+    event_type character varying(64) NOT NULL,
+    payload jsonb NOT NULL,
+    status character varying(16) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp without time zone NOT NULL,
+    last_status_code integer,
+    last_error text,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id),
+    CONSTRAINT webhook_deliveries_event_key UNIQUE (subscription_id, event_id, event_type),
+    CONSTRAINT webhook_deliveries_subscriptions_fkey FOREIGN KEY (subscription_id)
+        REFERENCES public.webhook_subscriptions (subscription_id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_webhook_deliveries_pending, used by sender to find due deliveries
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending
+    ON public.webhook_deliveries USING btree
+    (next_attempt_at ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE status = 'pending';
+
+-- Table: public.idempotency_keys, responses of requests made with Idempotency-Key header
+
+CREATE TABLE IF NOT EXISTS public.idempotency_keys
+(
+    key character varying(255) NOT NULL,
+    fingerprint character(64) NOT NULL,
+    status character varying(16) NOT NULL,
+    response_status integer,
+    content_type character varying(255),
+    response_body bytea,
+    created_at timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY (key)
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_idempotency_keys_expires_at, used to purge expired keys
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at
+    ON public.idempotency_keys USING btree
+    (expires_at ASC NULLS LAST)
+    TABLESPACE pg_default;
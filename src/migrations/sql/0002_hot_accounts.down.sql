@@ -0,0 +1,7 @@
+-- Pending credits are rolled up before table is dropped, so no money is lost
+UPDATE public.accounts SET balance = balance + p.amount
+    FROM (SELECT account_number, SUM(amount) AS amount FROM public.pending_credits GROUP BY account_number) AS p
+    WHERE accounts.account_number = p.account_number;
+
+DROP TABLE IF EXISTS public.pending_credits;
+ALTER TABLE public.accounts DROP COLUMN IF EXISTS hot;
@@ -0,0 +1,22 @@
+-- Hot accounts receive credits as pending credits instead of balance updates,
+-- so incoming transfers to popular accounts don't wait for account row lock
+ALTER TABLE public.accounts ADD COLUMN IF NOT EXISTS hot boolean NOT NULL DEFAULT false;
+
+-- pending credits table. Credits are rolled up into account balance by background worker.
+-- There is no foreign key to accounts: key check would lock account row and credits would wait for debits again
+CREATE TABLE IF NOT EXISTS public.pending_credits
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_number bigint NOT NULL,
+    amount bigint NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pending_credits_pkey PRIMARY KEY (id)
+)
+
+TABLESPACE pg_default;
+
+-- Index: idx_pending_credits_account_number, used to sum and roll up credits of account
+CREATE INDEX IF NOT EXISTS idx_pending_credits_account_number
+    ON public.pending_credits USING btree
+    (account_number ASC NULLS LAST)
+    TABLESPACE pg_default;
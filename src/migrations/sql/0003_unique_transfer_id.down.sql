@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS public.idx_transfers_transfer_id;
+
+CREATE INDEX IF NOT EXISTS idx_transfers_transaction_id
+    ON public.transfers USING btree
+    (transfer_id ASC NULLS LAST)
+    INCLUDE(transfer_id)
+    TABLESPACE pg_default;
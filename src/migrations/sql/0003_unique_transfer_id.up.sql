@@ -0,0 +1,9 @@
+-- Transfer id is unique, so transfer with the same id is rejected by database even if requests are sent concurrently.
+-- If database already has duplicate transfer ids, index is not created and migration fails, duplicates should be resolved manually
+DROP INDEX IF EXISTS public.idx_transfers_transaction_id;
+
+-- Index: idx_transfers_transfer_id
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transfers_transfer_id
+    ON public.transfers USING btree
+    (transfer_id ASC NULLS LAST)
+    TABLESPACE pg_default;
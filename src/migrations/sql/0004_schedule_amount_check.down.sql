@@ -0,0 +1 @@
+ALTER TABLE public.transfer_schedules DROP CONSTRAINT IF EXISTS transfer_schedules_amount_check;
@@ -0,0 +1,10 @@
+-- Amounts greater than max bigint were stored as negative numbers. Such schedules fail on every run
+-- and could never be executed, so active and paused ones are cancelled. They are kept with their occurrences,
+-- so history of failed runs is not lost
+UPDATE public.transfer_schedules SET status = 'cancelled'
+    WHERE amount <= 0 AND status IN ('active', 'paused');
+
+-- Schedules in final states are never executed again, so they may keep invalid amounts
+ALTER TABLE public.transfer_schedules DROP CONSTRAINT IF EXISTS transfer_schedules_amount_check;
+ALTER TABLE public.transfer_schedules ADD CONSTRAINT transfer_schedules_amount_check
+    CHECK (amount > 0 OR status IN ('completed', 'cancelled'));
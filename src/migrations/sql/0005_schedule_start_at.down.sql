@@ -0,0 +1 @@
+ALTER TABLE public.transfer_schedules DROP COLUMN IF EXISTS start_at;
@@ -0,0 +1,9 @@
+-- Time of first transfer, monthly transfers are counted from it so they don't drift after short months.
+-- Existing schedules start at their first occurrence, or at next run if they were not executed yet
+ALTER TABLE public.transfer_schedules ADD COLUMN IF NOT EXISTS start_at timestamp without time zone;
+
+UPDATE public.transfer_schedules s
+    SET start_at = COALESCE((SELECT MIN(o.scheduled_at) FROM public.schedule_occurrences o WHERE o.schedule_id = s.schedule_id), s.next_run_at)
+    WHERE start_at IS NULL;
+
+ALTER TABLE public.transfer_schedules ALTER COLUMN start_at SET NOT NULL;
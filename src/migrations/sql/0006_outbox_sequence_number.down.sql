@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS public.idx_outbox_events_unpublished_sequence_number;
+DROP INDEX IF EXISTS public.idx_outbox_events_unnumbered;
+DROP INDEX IF EXISTS public.idx_outbox_events_sequence_number;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished
+    ON public.outbox_events USING btree
+    (id ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE published_at IS NULL;
+
+ALTER TABLE public.outbox_events DROP COLUMN IF EXISTS sequence_number;
@@ -0,0 +1,28 @@
+-- Relay numbers events in order they are committed and publishes them in order of sequence numbers, which are event ids seen by consumers.
+-- Published events keep their ids as sequence numbers, so consumers that track last event id are not affected
+ALTER TABLE public.outbox_events ADD COLUMN IF NOT EXISTS sequence_number bigint;
+
+UPDATE public.outbox_events SET sequence_number = id WHERE published_at IS NOT NULL;
+
+-- Index: idx_outbox_events_sequence_number
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_sequence_number
+    ON public.outbox_events USING btree
+    (sequence_number ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_outbox_events_unnumbered, used by relay to find events that are not numbered yet
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unnumbered
+    ON public.outbox_events USING btree
+    (id ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE sequence_number IS NULL;
+
+-- Unpublished events are found by sequence number
+DROP INDEX IF EXISTS public.idx_outbox_events_unpublished;
+
+-- Index: idx_outbox_events_unpublished_sequence_number, used by relay to find unpublished events
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished_sequence_number
+    ON public.outbox_events USING btree
+    (sequence_number ASC NULLS LAST)
+    TABLESPACE pg_default
+    WHERE published_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.idx_outbox_events_dest_account;
+DROP INDEX IF EXISTS public.idx_outbox_events_source_account;
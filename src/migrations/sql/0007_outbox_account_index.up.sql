@@ -0,0 +1,14 @@
+-- Stream replays events of account after last event received by client. Events are found by accounts of transfer
+-- in event payload, so replay reads only events of account in order of their sequence numbers
+
+-- Index: idx_outbox_events_source_account
+CREATE INDEX IF NOT EXISTS idx_outbox_events_source_account
+    ON public.outbox_events USING btree
+    (((payload->>'source')::bigint) ASC NULLS LAST, sequence_number ASC NULLS LAST)
+    TABLESPACE pg_default;
+
+-- Index: idx_outbox_events_dest_account
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dest_account
+    ON public.outbox_events USING btree
+    (((payload->>'dest')::bigint) ASC NULLS LAST, sequence_number ASC NULLS LAST)
+    TABLESPACE pg_default;
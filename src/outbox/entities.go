@@ -0,0 +1,22 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event that is written to outbox in the same transaction as business data
+// and delivered to publisher by relay
+type Event struct {
+	// Event id, sequence number assigned to event by relay. Events are delivered in order of their ids
+	Id int64 `json:"id"`
+
+	// Event type, for example "TransferCompleted"
+	Type string `json:"type"`
+
+	// Event payload
+	Payload json.RawMessage `json:"payload"`
+
+	// Event created timestamp
+	CreatedAt time.Time `json:"createdAt"`
+}
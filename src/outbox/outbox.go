@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"encoding/json"
+	"test/coins/db"
+	"time"
+)
+
+// Writes event to outbox. Event would be published by relay only if
+// transaction of db context is committed
+//	dbContext - db context
+//	eventType - event type
+//	payload   - event payload, serialized to JSON
+func Write(dbContext db.DbContext, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbContext.Execute(
+		"INSERT INTO public.outbox_events (event_type, payload, created_at) VALUES ($1, $2, $3)",
+		eventType, string(data), time.Now().UTC(),
+	)
+
+	return err
+}
@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Publisher delivers outbox events to consumers
+type Publisher interface {
+	// Publishes event. If error is returned, event would be published again later
+	Publish(event Event) error
+}
+
+// Publisher that writes events to io.Writer as JSON lines
+type writerPublisher struct {
+	mutex sync.Mutex
+
+	writer io.Writer
+}
+
+// Creates new publisher that writes events to stdout as JSON lines
+func NewStdoutPublisher() Publisher {
+	return &writerPublisher{writer: os.Stdout}
+}
+
+// Creates new publisher that appends events to file as JSON lines
+//	path - file path
+// Returns created publisher and function that closes file
+func NewFilePublisher(path string) (Publisher, func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &writerPublisher{writer: file}, file.Close, nil
+}
+
+func (pub *writerPublisher) Publish(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	pub.mutex.Lock()
+	defer pub.mutex.Unlock()
+
+	_, err = pub.writer.Write(append(data, '\n'))
+	return err
+}
+
+// Publisher that keeps published events in memory. Used in tests and demos
+type MemoryPublisher struct {
+	mutex sync.Mutex
+
+	events []Event
+}
+
+// Creates new in-memory publisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (pub *MemoryPublisher) Publish(event Event) error {
+	pub.mutex.Lock()
+	defer pub.mutex.Unlock()
+
+	pub.events = append(pub.events, event)
+	return nil
+}
+
+// Returns copy of published events in order they were published
+func (pub *MemoryPublisher) Events() []Event {
+	pub.mutex.Lock()
+	defer pub.mutex.Unlock()
+
+	var result = make([]Event, len(pub.events))
+	copy(result, pub.events)
+	return result
+}
+
+// Publisher that delivers events to several publishers
+type multiPublisher struct {
+	publishers []Publisher
+}
+
+// Creates new publisher that delivers each event to all of the given publishers in order.
+// If any publisher fails, event would be published again to all of them, so publishers should tolerate duplicates
+//	publishers - publishers events are delivered to
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &multiPublisher{publishers}
+}
+
+func (pub *multiPublisher) Publish(event Event) error {
+	for _, publisher := range pub.publishers {
+		err := publisher.Publish(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"test/coins/db"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	servErr "test/coins/errors"
+)
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+// Key of advisory lock that allows only one relay to publish events at a time,
+// so events are published in order even if there are several application instances
+const relayLockKey int64 = 0x6f7574626f78
+
+// Relay reads unpublished events from outbox and delivers them to publisher.
+// Events are delivered at least once: if relay stops after event was published
+// but before it was marked as published, event would be published again.
+// Ids are assigned when events are written, not when they are committed, so event with lower id can be committed
+// after event with higher id. Relay sees only committed events, so it numbers them in order it sees them and publishes
+// them in order of these sequence numbers: event committed after relay numbered events is published after them.
+// Events that relay sees at the same time are numbered in order of ids. Transfers that lock the same account are
+// committed in order of their ids, so only events of transfers that don't lock common account, like credits
+// to hot account, may be numbered not in order of their commits
+type Relay struct {
+	dbContextFactory func() (db.DbContext, error)
+
+	publisher Publisher
+
+	interval time.Duration
+
+	batchSize int
+
+	logger kitlog.Logger
+}
+
+// Creates new outbox relay
+//	dbContextFactory - factory function used to create new db context
+//	publisher        - publisher events are delivered to
+//	interval         - interval between checks for unpublished events
+//	batchSize        - max number of events published in single transaction
+//	logger           - logger
+func NewRelay(dbContextFactory func() (db.DbContext, error), publisher Publisher, interval time.Duration, batchSize int, logger kitlog.Logger) *Relay {
+	return &Relay{dbContextFactory, publisher, interval, batchSize, logger}
+}
+
+// Runs relay until context is cancelled
+//	ctx - context used to stop relay
+func (relay *Relay) Run(ctx context.Context) {
+	var ticker = time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				published, err := relay.RelayBatch()
+				if err != nil {
+					level.Error(relay.logger).Log("msg", "failed to relay outbox events", "err", err)
+					break
+				}
+
+				if published < relay.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Publishes single batch of unpublished events
+// Returns number of published events
+func (relay *Relay) RelayBatch() (int, error) {
+	err := relay.assignSequenceNumbers()
+	if err != nil {
+		return 0, err
+	}
+
+	dbContext, err := relay.dbContextFactory()
+	if err != nil {
+		return 0, err
+	}
+	defer dbContext.Release()
+
+	locked, err := tryLock(dbContext)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	var events = []Event{}
+	err = dbContext.Query(
+		"SELECT sequence_number, event_type, payload, created_at FROM public.outbox_events "+
+			"WHERE published_at IS NULL AND sequence_number IS NOT NULL ORDER BY sequence_number LIMIT $1",
+		sqlParams{relay.batchSize},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					event   Event
+					payload []byte
+				)
+
+				err := rows.Scan(&event.Id, &event.Type, &payload, &event.CreatedAt)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				event.Payload = payload
+				events = append(events, event)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Publishing stops on first error, so events published later can't overtake failed one
+	var published = []interface{}{}
+	var publishErr error = nil
+	for _, event := range events {
+		publishErr = relay.publisher.Publish(event)
+		if publishErr != nil {
+			break
+		}
+
+		published = append(published, event.Id)
+	}
+
+	if len(published) > 0 {
+		err = markPublished(dbContext, published)
+		if err != nil {
+			return 0, err
+		}
+
+		err = dbContext.Save()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(published), publishErr
+}
+
+// Numbers events that were committed since previous batch, in order of their ids. Numbers are saved
+// in separate transaction before events are published, so event that is published again keeps its number
+func (relay *Relay) assignSequenceNumbers() error {
+	dbContext, err := relay.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	locked, err := tryLock(dbContext)
+	if err != nil || !locked {
+		return err
+	}
+
+	_, err = dbContext.Execute(
+		"UPDATE public.outbox_events e SET sequence_number = n.sequence_number FROM ("+
+			"SELECT id, (SELECT COALESCE(MAX(sequence_number), 0) FROM public.outbox_events) + ROW_NUMBER() OVER (ORDER BY id) AS sequence_number "+
+			"FROM public.outbox_events WHERE sequence_number IS NULL ORDER BY id LIMIT $1) n "+
+			"WHERE e.id = n.id",
+		relay.batchSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	return dbContext.Save()
+}
+
+// Acquires advisory lock of relay, lock is released automatically when transaction ends
+//	dbContext - db context
+// Returns false if other relay holds the lock
+func tryLock(dbContext db.DbContext) (bool, error) {
+	var locked = false
+	err := dbContext.Query(
+		"SELECT pg_try_advisory_xact_lock($1)",
+		sqlParams{relayLockKey},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return nil
+			}
+
+			err := rows.Scan(&locked)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	return locked, err
+}
+
+// Marks events as published
+//	dbContext       - db context
+//	sequenceNumbers - sequence numbers of published events
+func markPublished(dbContext db.DbContext, sequenceNumbers []interface{}) error {
+	var placeholders = make([]string, len(sequenceNumbers))
+	for i := range sequenceNumbers {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+	}
+
+	_, err := dbContext.Execute(
+		"UPDATE public.outbox_events SET published_at = $1 WHERE sequence_number IN ("+strings.Join(placeholders, ", ")+")",
+		append([]interface{}{time.Now().UTC()}, sequenceNumbers...)...,
+	)
+
+	return err
+}
@@ -0,0 +1,196 @@
+package outbox_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"test/coins/db"
+	"test/coins/outbox"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	kitlog "github.com/go-kit/log"
+)
+
+var eventColumns = []string{"sequence_number", "event_type", "payload", "created_at"}
+
+// Publisher stub that fails to publish event with specific id
+type failingPublisher struct {
+	outbox.MemoryPublisher
+
+	failOn int64
+}
+
+func (pub *failingPublisher) Publish(event outbox.Event) error {
+	if event.Id == pub.failOn {
+		return errors.New("publisher is not available")
+	}
+
+	return pub.MemoryPublisher.Publish(event)
+}
+
+// Creates relay, each created db context is set up by next setup function
+func setupRelay(publisher outbox.Publisher, setupMocks ...func(mock sqlmock.Sqlmock)) *outbox.Relay {
+	var created = 0
+	return outbox.NewRelay(func() (db.DbContext, error) {
+		var setupMock = setupMocks[created]
+		created++
+		return db.CreateMockDbContext(setupMock)
+	}, publisher, time.Second, 10, kitlog.NewNopLogger())
+}
+
+// Returns mock setup of transaction where committed events are numbered
+func numberEvents(dbMock *sqlmock.Sqlmock) func(mock sqlmock.Sqlmock) {
+	return func(mock sqlmock.Sqlmock) {
+		*dbMock = mock
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(true))
+		mock.ExpectExec("UPDATE public.outbox_events e SET sequence_number = n.sequence_number FROM \\(.* ORDER BY id LIMIT \\$1\\) n").
+			WithArgs(10).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+	}
+}
+
+// Matcher of UTC time argument
+type utcTime struct{}
+
+func (m utcTime) Match(v driver.Value) bool {
+	value, ok := v.(time.Time)
+	return ok && value.Location() == time.UTC
+}
+
+func Test_RelayBatch_EventsPublishedInOrder(t *testing.T) {
+	// Arrange
+	var now = time.Now()
+	var publisher = outbox.NewMemoryPublisher()
+	var numberMock, dbMock sqlmock.Sqlmock = nil, nil
+	var relay = setupRelay(publisher, numberEvents(&numberMock), func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(true))
+
+		var rows = sqlmock.NewRows(eventColumns).
+			AddRow(1, "TransferCompleted", []byte(`{"amount":100}`), now).
+			AddRow(2, "TransferCompleted", []byte(`{"amount":200}`), now)
+		mock.ExpectQuery("SELECT sequence_number, event_type, payload, created_at FROM public.outbox_events .* ORDER BY sequence_number").
+			WithArgs(10).
+			WillReturnRows(rows)
+
+		mock.ExpectExec("UPDATE public.outbox_events SET published_at = \\$1 WHERE sequence_number IN").
+			WithArgs(utcTime{}, int64(1), int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	published, err := relay.RelayBatch()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when RelayBatch() was called: %s", err.Error())
+	}
+
+	var events = publisher.Events()
+	if published != 2 || len(events) != 2 {
+		t.Fatalf("expected 2 published events, got %d", len(events))
+	}
+
+	if events[0].Id != 1 || events[1].Id != 2 {
+		t.Fatalf("events should be published in order of their sequence numbers")
+	}
+
+	if string(events[1].Payload) != `{"amount":200}` {
+		t.Fatalf("invalid event payload: %s", string(events[1].Payload))
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{numberMock, dbMock} {
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			t.Fatalf("db methods call expectations were not met: %s", err.Error())
+		}
+	}
+}
+
+func Test_RelayBatch_PublishingStopsOnFirstError(t *testing.T) {
+	// Arrange
+	var now = time.Now()
+	var publisher = &failingPublisher{failOn: 2}
+	var numberMock, dbMock sqlmock.Sqlmock = nil, nil
+	var relay = setupRelay(publisher, numberEvents(&numberMock), func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(true))
+
+		var rows = sqlmock.NewRows(eventColumns).
+			AddRow(1, "TransferCompleted", []byte(`{}`), now).
+			AddRow(2, "TransferCompleted", []byte(`{}`), now).
+			AddRow(3, "TransferCompleted", []byte(`{}`), now)
+		mock.ExpectQuery("SELECT sequence_number, event_type, payload, created_at FROM public.outbox_events").WillReturnRows(rows)
+
+		// Only first event is marked as published, failed and following events would be published again
+		mock.ExpectExec("UPDATE public.outbox_events SET published_at").
+			WithArgs(utcTime{}, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	published, err := relay.RelayBatch()
+
+	// Assert
+	if err == nil {
+		t.Fatalf("expected publisher error to be returned by RelayBatch()")
+	}
+
+	if published != 1 || len(publisher.Events()) != 1 {
+		t.Fatalf("expected only 1 event to be published")
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{numberMock, dbMock} {
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			t.Fatalf("db methods call expectations were not met: %s", err.Error())
+		}
+	}
+}
+
+func Test_RelayBatch_SkippedIfOtherRelayIsRunning(t *testing.T) {
+	// Arrange
+	var publisher = outbox.NewMemoryPublisher()
+	var mocks = make([]sqlmock.Sqlmock, 2)
+	var skipped = func(dbMock *sqlmock.Sqlmock) func(mock sqlmock.Sqlmock) {
+		return func(mock sqlmock.Sqlmock) {
+			*dbMock = mock
+			mock.ExpectBegin()
+
+			mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(false))
+
+			mock.ExpectRollback()
+		}
+	}
+	var relay = setupRelay(publisher, skipped(&mocks[0]), skipped(&mocks[1]))
+
+	// Act
+	published, err := relay.RelayBatch()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when RelayBatch() was called: %s", err.Error())
+	}
+
+	if published != 0 || len(publisher.Events()) != 0 {
+		t.Fatalf("events should not be published without relay lock")
+	}
+
+	for _, mock := range mocks {
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			t.Fatalf("db methods call expectations were not met: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package outbox_test
+
+import (
+	"test/coins/db"
+	"test/coins/migrations"
+	"test/coins/outbox"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+)
+
+// Opens in-memory SQLite database and applies migrations to it
+func openSQLitePool(tb testing.TB) db.ConnectionPool {
+	pool, err := db.NewConnectionPool("sqlite://:memory:", 1, time.Second)
+	if err != nil {
+		tb.Fatalf("unable to open SQLite database: %s", err.Error())
+	}
+	tb.Cleanup(pool.Close)
+
+	all, err := migrations.Load()
+	if err != nil {
+		tb.Fatalf("unable to load migrations: %s", err.Error())
+	}
+
+	_, err = migrations.NewMigrator(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}, all).Up()
+	if err != nil {
+		tb.Fatalf("unable to migrate SQLite database: %s", err.Error())
+	}
+
+	return pool
+}
+
+// Writes event with specific id, as if it was written by transaction that got this id
+func writeEvent(t *testing.T, pool db.ConnectionPool, id int64, payload string) {
+	dbContext, err := db.CreateContext(pool, time.Second*5)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(
+		"INSERT INTO public.outbox_events (id, event_type, payload, created_at) VALUES ($1, $2, $3, $4)",
+		id, "TransferCompleted", payload, time.Now().UTC(),
+	)
+	if err != nil {
+		t.Fatalf("unable to write event: %s", err.Error())
+	}
+
+	err = dbContext.Save()
+	if err != nil {
+		t.Fatalf("unable to save event: %s", err.Error())
+	}
+}
+
+func Test_RelayBatch_SQLite_EventCommittedLaterPublishedAfterPreviousEvents(t *testing.T) {
+	// Arrange
+	var pool = openSQLitePool(t)
+	var publisher = outbox.NewMemoryPublisher()
+	var relay = outbox.NewRelay(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}, publisher, time.Second, 10, kitlog.NewNopLogger())
+
+	writeEvent(t, pool, 5, `{"amount":100}`)
+	writeEvent(t, pool, 6, `{"amount":200}`)
+	_, err := relay.RelayBatch()
+	if err != nil {
+		t.Fatalf("unexpected error occured when RelayBatch() was called: %s", err.Error())
+	}
+
+	// event got lower id, but its transaction was committed after previous events were published
+	writeEvent(t, pool, 3, `{"amount":300}`)
+
+	// Act
+	published, err := relay.RelayBatch()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when RelayBatch() was called: %s", err.Error())
+	}
+
+	var events = publisher.Events()
+	if published != 1 || len(events) != 3 {
+		t.Fatalf("expected 3 published events, got %d", len(events))
+	}
+
+	for i, event := range events {
+		if event.Id != int64(i+1) {
+			t.Fatalf("events should be published in order they were committed, event %d has id %d", i, event.Id)
+		}
+	}
+
+	if string(events[2].Payload) != `{"amount":300}` {
+		t.Fatalf("event committed later should be published last, got %s", string(events[2].Payload))
+	}
+}
@@ -0,0 +1,4 @@
+// Package pb contains protobuf messages and gRPC service definitions generated from coins.proto
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative coins.proto
@@ -0,0 +1,86 @@
+package schedule
+
+import (
+	"context"
+	"test/coins/account"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type createScheduleRequest struct {
+	Source     uint64    `json:"source"`
+	Dest       uint64    `json:"dest"`
+	Amount     uint64    `json:"amount"`
+	Memo       string    `json:"memo"`
+	StartAt    time.Time `json:"startAt"`
+	Recurrence string    `json:"recurrence"`
+}
+
+type scheduleResponse struct {
+	Schedule *Schedule `json:"schedule,omitempty"`
+	Error    error     `json:"error,omitempty"`
+}
+
+func (r scheduleResponse) error() error { return r.Error }
+
+func makeCreateScheduleEndpoint(svc ScheduleService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createScheduleRequest)
+		schedule, err := svc.CreateSchedule(NewSchedule{
+			Source:     account.AccountNumber(req.Source),
+			Dest:       account.AccountNumber(req.Dest),
+			Amount:     req.Amount,
+			Memo:       req.Memo,
+			StartAt:    req.StartAt,
+			Recurrence: req.Recurrence,
+		})
+		return scheduleResponse{schedule, err}, nil
+	}
+}
+
+type listSchedulesRequest struct {
+	AccountNumber uint64
+}
+
+type listSchedulesResponse struct {
+	Schedules []Schedule `json:"schedules,omitempty"`
+	Error     error      `json:"error,omitempty"`
+}
+
+func (r listSchedulesResponse) error() error { return r.Error }
+
+func makeListSchedulesEndpoint(svc ScheduleService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listSchedulesRequest)
+		schedules, err := svc.ListSchedules(account.AccountNumber(req.AccountNumber))
+		return listSchedulesResponse{schedules, err}, nil
+	}
+}
+
+type scheduleIdRequest struct {
+	Id ScheduleId
+}
+
+func makeChangeScheduleEndpoint(change func(id ScheduleId) (*Schedule, error)) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduleIdRequest)
+		schedule, err := change(req.Id)
+		return scheduleResponse{schedule, err}, nil
+	}
+}
+
+type listOccurrencesResponse struct {
+	Occurrences []Occurrence `json:"occurrences,omitempty"`
+	Error       error        `json:"error,omitempty"`
+}
+
+func (r listOccurrencesResponse) error() error { return r.Error }
+
+func makeListOccurrencesEndpoint(svc ScheduleService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduleIdRequest)
+		occurrences, err := svc.ListOccurrences(req.Id)
+		return listOccurrencesResponse{occurrences, err}, nil
+	}
+}
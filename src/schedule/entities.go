@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"test/coins/account"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScheduleId uuid.UUID
+
+// Returns string representation of schedule id
+func (id ScheduleId) String() string {
+	return uuid.UUID(id).String()
+}
+
+// Needed to support proper serialization to JSON
+func (id ScheduleId) MarshalJSON() ([]byte, error) {
+	var guid = uuid.UUID(id)
+	var str = guid.String()
+
+	return []byte("\"" + str + "\""), nil
+}
+
+// Needed to support proper deserialization from JSON
+func (id *ScheduleId) UnmarshalJSON(data []byte) error {
+	guid, err := uuid.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	*id = ScheduleId(guid)
+	return nil
+}
+
+const StatusActive = "active"
+const StatusPaused = "paused"
+const StatusCancelled = "cancelled"
+const StatusCompleted = "completed"
+
+const OccurrenceSucceeded = "succeeded"
+const OccurrenceFailed = "failed"
+
+type Schedule struct {
+	// Schedule id
+	Id ScheduleId `json:"id"`
+
+	// Account from where money is transferred
+	Source account.AccountNumber `json:"source"`
+
+	// Account to where money is transferred
+	Dest account.AccountNumber `json:"dest"`
+
+	// Transfer amount
+	Amount int64 `json:"amount"`
+
+	// Note attached to every transfer made by schedule
+	Memo string `json:"memo,omitempty"`
+
+	// Recurrence rule, for example "FREQ=WEEKLY;INTERVAL=1". Empty for one-off schedules
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// Schedule status. Can have values "active", "paused", "cancelled" or "completed"
+	Status string `json:"status"`
+
+	// Time of next transfer. Not used if schedule is not active
+	NextRunAt time.Time `json:"nextRunAt"`
+
+	// Number of executed occurrences
+	Runs int64 `json:"runs"`
+
+	// Schedule created timestamp
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Time of first transfer. Monthly transfers are made on its day of month, or on last day of shorter month
+	StartAt time.Time `json:"startAt"`
+}
+
+// Single execution of schedule
+type Occurrence struct {
+	// Schedule id
+	ScheduleId ScheduleId `json:"scheduleId"`
+
+	// Time when transfer was scheduled to be executed
+	ScheduledAt time.Time `json:"scheduledAt"`
+
+	// Id of transfer made for this occurrence. Derived from schedule id and scheduled time
+	TransferId uuid.UUID `json:"transferId"`
+
+	// Occurrence status. Can have values "succeeded" or "failed"
+	Status string `json:"status"`
+
+	// Error message if transfer failed
+	Error string `json:"error,omitempty"`
+
+	// Time when occurrence was executed
+	ExecutedAt time.Time `json:"executedAt"`
+}
+
+// Parameters of new schedule
+type NewSchedule struct {
+	Source     account.AccountNumber
+	Dest       account.AccountNumber
+	Amount     uint64
+	Memo       string
+	StartAt    time.Time
+	Recurrence string
+}
+
+// Returns transfer id for occurrence of schedule.
+// Id is deterministic, so repeated execution of the same occurrence can't transfer money twice
+//	scheduleId  - schedule id
+//	scheduledAt - time when occurrence was scheduled
+func occurrenceTransferId(scheduleId ScheduleId, scheduledAt time.Time) uuid.UUID {
+	return uuid.NewSHA1(uuid.UUID(scheduleId), []byte(scheduledAt.UTC().Format(time.RFC3339Nano)))
+}
@@ -0,0 +1,44 @@
+package schedule
+
+import (
+	"fmt"
+	"net/http"
+	servErr "test/coins/errors"
+)
+
+const (
+	ErrKindInvalidSchedule int = 30 + iota
+	ErrKindScheduleNotFound
+	ErrKindInvalidScheduleStatus
+)
+
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindInvalidSchedule, servErr.ErrorDefinition{Code: "invalid_schedule", Status: http.StatusBadRequest, Title: "Invalid schedule"})
+	servErr.RegisterKind(ErrKindScheduleNotFound, servErr.ErrorDefinition{Code: "schedule_not_found", Status: http.StatusNotFound, Title: "Schedule not found"})
+	servErr.RegisterKind(ErrKindInvalidScheduleStatus, servErr.ErrorDefinition{Code: "invalid_schedule_status", Status: http.StatusConflict, Title: "Schedule status does not allow action"})
+}
+
+// Creates new "Invalid schedule" error
+//	reason - reason why schedule is invalid
+// Returns created error
+func ErrInvalidSchedule(reason string) error {
+	return servErr.NewServiceError("invalid schedule: "+reason, nil, ErrKindInvalidSchedule)
+}
+
+// Creates new "Schedule not found" error
+//	id - schedule id
+// Returns created error
+func ErrScheduleNotFound(id ScheduleId) error {
+	var msg = fmt.Sprintf("schedule with id [%s] not found", id.String())
+	return servErr.NewServiceError(msg, nil, ErrKindScheduleNotFound)
+}
+
+// Creates new "Invalid schedule status" error
+//	status - current schedule status
+//	action - action that can't be done
+// Returns created error
+func ErrInvalidScheduleStatus(status, action string) error {
+	var msg = fmt.Sprintf("schedule with status [%s] can't be %s", status, action)
+	return servErr.NewServiceError(msg, nil, ErrKindInvalidScheduleStatus)
+}
@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	freqDaily   = "DAILY"
+	freqWeekly  = "WEEKLY"
+	freqMonthly = "MONTHLY"
+)
+
+// RRULE-like recurrence rule, for example "FREQ=WEEKLY;INTERVAL=2;COUNT=10"
+// Supported parts:
+//	FREQ     - required, one of DAILY, WEEKLY or MONTHLY
+//	INTERVAL - optional, number of periods between occurrences, 1 by default
+//	COUNT    - optional, max number of occurrences, unlimited by default
+type recurrence struct {
+	freq     string
+	interval int
+	count    int64
+}
+
+// Parses recurrence rule
+//	rule - recurrence rule. Empty rule means one-off schedule
+// Returns parsed rule or nil for one-off schedules
+func parseRecurrence(rule string) (*recurrence, error) {
+	if rule == "" {
+		return nil, nil
+	}
+
+	var result = recurrence{interval: 1}
+	for _, part := range strings.Split(strings.ToUpper(rule), ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrInvalidSchedule(fmt.Sprintf("invalid recurrence rule part [%s]", part))
+		}
+
+		switch kv[0] {
+		case "FREQ":
+			if kv[1] != freqDaily && kv[1] != freqWeekly && kv[1] != freqMonthly {
+				return nil, ErrInvalidSchedule(fmt.Sprintf("unsupported recurrence frequency [%s]", kv[1]))
+			}
+			result.freq = kv[1]
+		case "INTERVAL":
+			interval, err := strconv.Atoi(kv[1])
+			if err != nil || interval < 1 {
+				return nil, ErrInvalidSchedule("recurrence interval should be positive integer number")
+			}
+			result.interval = interval
+		case "COUNT":
+			count, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || count < 1 {
+				return nil, ErrInvalidSchedule("recurrence count should be positive integer number")
+			}
+			result.count = count
+		default:
+			return nil, ErrInvalidSchedule(fmt.Sprintf("unsupported recurrence rule part [%s]", kv[0]))
+		}
+	}
+
+	if result.freq == "" {
+		return nil, ErrInvalidSchedule("recurrence frequency is required")
+	}
+
+	return &result, nil
+}
+
+// Returns time of occurrence that follows provided one
+//	start - time of first occurrence, monthly occurrences fall on its day of month
+//	after - time of previous occurrence
+//	runs  - number of occurrences executed so far, including previous one
+// Returns next occurrence time and false if there are no more occurrences
+func (r *recurrence) next(start, after time.Time, runs int64) (time.Time, bool) {
+	if r == nil || (r.count > 0 && runs >= r.count) {
+		return time.Time{}, false
+	}
+
+	switch r.freq {
+	case freqDaily:
+		return after.AddDate(0, 0, r.interval), true
+	case freqWeekly:
+		return after.AddDate(0, 0, 7*r.interval), true
+	}
+
+	// Each monthly occurrence is counted from start, so day clamped to end of short month
+	// doesn't shift occurrences that follow it
+	var months = (after.Year()-start.Year())*12 + int(after.Month()) - int(start.Month())
+	for k := months / r.interval; ; k++ {
+		var next = addMonths(start, k*r.interval)
+		if next.After(after) {
+			return next, true
+		}
+	}
+}
+
+// Adds months to time. Day of month is clamped to last day of resulting month, so Jan 31 + 1 month is Feb 28 (29)
+//	t      - time
+//	months - number of months to add
+func addMonths(t time.Time, months int) time.Time {
+	var firstDay = time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	var lastDay = firstDay.AddDate(0, 1, -1).Day()
+
+	var day = t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return firstDay.AddDate(0, 0, day-1)
+}
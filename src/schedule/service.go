@@ -0,0 +1,535 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/transfer"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+const scheduleColumns = "schedule_id, source_account, dest_account, amount, memo, recurrence, status, next_run_at, runs, created_at, start_at"
+
+// Schedule service. Incapsulates operations with scheduled and recurring transfers
+type ScheduleService interface {
+	// Creates new schedule
+	//	schedule - parameters of new schedule
+	// Returns created schedule
+	CreateSchedule(schedule NewSchedule) (*Schedule, error)
+
+	// Returns list of schedules where account is source or dest
+	//	accountNum - account number
+	ListSchedules(accountNum account.AccountNumber) ([]Schedule, error)
+
+	// Pauses active schedule
+	//	id - schedule id
+	// Returns updated schedule
+	PauseSchedule(id ScheduleId) (*Schedule, error)
+
+	// Resumes paused schedule. Occurrences missed while schedule was paused are skipped
+	//	id - schedule id
+	// Returns updated schedule
+	ResumeSchedule(id ScheduleId) (*Schedule, error)
+
+	// Cancels schedule, cancelled schedule can't be resumed
+	//	id - schedule id
+	// Returns updated schedule
+	CancelSchedule(id ScheduleId) (*Schedule, error)
+
+	// Returns list of executed occurrences of schedule
+	//	id - schedule id
+	ListOccurrences(id ScheduleId) ([]Occurrence, error)
+
+	// Executes transfers for schedules that are due
+	//	now       - current time
+	//	batchSize - max number of schedules to execute
+	// Returns number of executed occurrences and error of occurrence that should be retried later
+	ExecuteDueSchedules(now time.Time, batchSize int) (int, error)
+}
+
+// Schedule service implementation
+type scheduleService struct {
+	dbContextFactory func() (db.DbContext, error)
+
+	transferService transfer.TransferService
+}
+
+// Creates new schedule service
+//	dbContextFactory - factory function used to create new db context
+//	transferService  - transfer service used to execute scheduled transfers
+func NewScheduleService(dbContextFactory func() (db.DbContext, error), transferService transfer.TransferService) ScheduleService {
+	return scheduleService{dbContextFactory, transferService}
+}
+
+func (svc scheduleService) CreateSchedule(schedule NewSchedule) (*Schedule, error) {
+	if schedule.Amount == 0 {
+		return nil, ErrInvalidSchedule("amount should be positive integer number")
+	}
+
+	// Amount is stored as bigint, larger amount would be stored as negative number
+	if schedule.Amount > transfer.MaxAmount {
+		return nil, ErrInvalidSchedule(fmt.Sprintf("amount should not be greater than %d", transfer.MaxAmount))
+	}
+
+	if schedule.Source == schedule.Dest {
+		return nil, ErrInvalidSchedule("source and dest accounts should be different")
+	}
+
+	if schedule.StartAt.IsZero() {
+		return nil, ErrInvalidSchedule("start time is required")
+	}
+
+	_, err := parseRecurrence(schedule.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	err = (transfer.TransferDetails{Memo: schedule.Memo}).Validate()
+	if err != nil {
+		return nil, ErrInvalidSchedule(err.Error())
+	}
+
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var count int64 = 0
+	err = dbContext.Query(
+		"SELECT COUNT(*) FROM public.accounts WHERE (account_number = $1 OR account_number = $2) AND account_type = $3",
+		sqlParams{int64(uint64(schedule.Source)), int64(uint64(schedule.Dest)), account.AccountTypeCustomer},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&count)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count != 2 {
+		return nil, ErrInvalidSchedule("source or dest account not found")
+	}
+
+	var result = Schedule{
+		Id:         ScheduleId(uuid.New()),
+		Source:     schedule.Source,
+		Dest:       schedule.Dest,
+		Amount:     int64(schedule.Amount),
+		Memo:       schedule.Memo,
+		Recurrence: schedule.Recurrence,
+		Status:     StatusActive,
+		NextRunAt:  schedule.StartAt.UTC(),
+		CreatedAt:  time.Now().UTC(),
+		StartAt:    schedule.StartAt.UTC(),
+	}
+
+	_, err = dbContext.Execute(
+		"INSERT INTO public.transfer_schedules ("+scheduleColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		uuid.UUID(result.Id), int64(uint64(result.Source)), int64(uint64(result.Dest)), result.Amount,
+		result.Memo, result.Recurrence, result.Status, result.NextRunAt, result.Runs, result.CreatedAt, result.StartAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbContext.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (svc scheduleService) ListSchedules(accountNum account.AccountNumber) ([]Schedule, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var result = []Schedule{}
+	err = dbContext.Query(
+		"SELECT "+scheduleColumns+" FROM public.transfer_schedules "+
+			"WHERE source_account = $1 OR dest_account = $1 ORDER BY created_at DESC",
+		sqlParams{int64(uint64(accountNum))},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				schedule, err := scanSchedule(rows)
+				if err != nil {
+					return err
+				}
+
+				result = append(result, schedule)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (svc scheduleService) PauseSchedule(id ScheduleId) (*Schedule, error) {
+	return svc.changeStatus(id, func(schedule *Schedule) error {
+		if schedule.Status != StatusActive {
+			return ErrInvalidScheduleStatus(schedule.Status, "paused")
+		}
+
+		schedule.Status = StatusPaused
+		return nil
+	})
+}
+
+func (svc scheduleService) ResumeSchedule(id ScheduleId) (*Schedule, error) {
+	return svc.changeStatus(id, func(schedule *Schedule) error {
+		if schedule.Status != StatusPaused {
+			return ErrInvalidScheduleStatus(schedule.Status, "resumed")
+		}
+
+		// Skipping occurrences missed while schedule was paused
+		rule, err := parseRecurrence(schedule.Recurrence)
+		if err != nil {
+			return err
+		}
+
+		var now = time.Now().UTC()
+		for rule != nil && schedule.NextRunAt.Before(now) {
+			next, ok := rule.next(schedule.StartAt, schedule.NextRunAt, schedule.Runs)
+			if !ok {
+				break
+			}
+			schedule.NextRunAt = next
+		}
+
+		schedule.Status = StatusActive
+		return nil
+	})
+}
+
+func (svc scheduleService) CancelSchedule(id ScheduleId) (*Schedule, error) {
+	return svc.changeStatus(id, func(schedule *Schedule) error {
+		if schedule.Status != StatusActive && schedule.Status != StatusPaused {
+			return ErrInvalidScheduleStatus(schedule.Status, "cancelled")
+		}
+
+		schedule.Status = StatusCancelled
+		return nil
+	})
+}
+
+func (svc scheduleService) ListOccurrences(id ScheduleId) ([]Occurrence, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	schedule, err := readSchedule(dbContext, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule == nil {
+		return nil, ErrScheduleNotFound(id)
+	}
+
+	var result = []Occurrence{}
+	err = dbContext.Query(
+		"SELECT scheduled_at, transfer_id, status, error, executed_at FROM public.schedule_occurrences "+
+			"WHERE schedule_id = $1 ORDER BY scheduled_at DESC",
+		sqlParams{uuid.UUID(id)},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var occurrence = Occurrence{ScheduleId: id}
+				var errMsg *string
+				err := rows.Scan(&occurrence.ScheduledAt, &occurrence.TransferId, &occurrence.Status, &errMsg, &occurrence.ExecutedAt)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				if errMsg != nil {
+					occurrence.Error = *errMsg
+				}
+
+				result = append(result, occurrence)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (svc scheduleService) ExecuteDueSchedules(now time.Time, batchSize int) (int, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return 0, err
+	}
+	defer dbContext.Release()
+
+	// Due schedules are locked until transaction ends, SKIP LOCKED allows
+	// several workers to execute schedules without waiting for each other
+	var due = []Schedule{}
+	err = dbContext.Query(
+		"SELECT "+scheduleColumns+" FROM public.transfer_schedules "+
+			"WHERE status = $1 AND next_run_at <= $2 ORDER BY next_run_at LIMIT $3 FOR UPDATE SKIP LOCKED",
+		sqlParams{StatusActive, now.UTC(), batchSize},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				schedule, err := scanSchedule(rows)
+				if err != nil {
+					return err
+				}
+
+				due = append(due, schedule)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Each occurrence is executed in its own savepoint, so transfer and its occurrence record are saved together
+	// and failed occurrence doesn't roll back occurrences executed before it
+	var executed = 0
+	var occurrenceErr error = nil
+	for _, schedule := range due {
+		err = db.RunInUnitOfWork(context.Background(), dbContext.Nested, func(ctx context.Context) error {
+			return svc.executeOccurrence(ctx, schedule)
+		})
+
+		// Business errors are recorded as failed occurrences by executeOccurrence, other errors roll back
+		// savepoint of occurrence. Schedule keeps its next run time, so occurrence is retried by next execution,
+		// and it doesn't block schedules that are due after it
+		if err != nil {
+			occurrenceErr = err
+			continue
+		}
+
+		executed++
+	}
+
+	// Occurrences executed before error are still saved
+	if executed > 0 {
+		saveErr := dbContext.Save()
+		if saveErr != nil {
+			return 0, saveErr
+		}
+	}
+
+	return executed, occurrenceErr
+}
+
+// Executes single occurrence of schedule, records its result and moves schedule to next occurrence.
+// Transfer is made in the same transaction where schedule is locked
+//	ctx      - context that carries db context where schedule is locked
+//	schedule - schedule to execute
+// Returns error if transfer failed with database error or occurrence can't be recorded
+func (svc scheduleService) executeOccurrence(ctx context.Context, schedule Schedule) error {
+	var transferId = occurrenceTransferId(schedule.Id, schedule.NextRunAt)
+
+	var status = OccurrenceSucceeded
+	var errMsg interface{} = nil
+	err := svc.transferService.TransferMoney(
+		ctx,
+		transfer.TransferId(transferId),
+		schedule.Source,
+		schedule.Dest,
+		uint64(schedule.Amount),
+		transfer.TransferDetails{Memo: schedule.Memo},
+	)
+
+	if err != nil && !errors.Is(err, transfer.ErrTransferAlreadyComplete) {
+		// Database errors are expected to be transient, so occurrence is retried later.
+		// Business errors (not enough money, invalid account, etc.) fail occurrence
+		var svcErr servErr.ServiceError
+		if !errors.As(err, &svcErr) || svcErr.Kind() == servErr.ErrorKindDB {
+			return err
+		}
+
+		status = OccurrenceFailed
+		errMsg = err.Error()
+	}
+
+	// If transfer is already complete, it was made by previous execution that failed to record occurrence
+	return recordOccurrence(ctx, schedule, status, errMsg)
+}
+
+// Records result of schedule occurrence and moves schedule to next occurrence
+//	ctx      - context that carries db context where schedule is locked
+//	schedule - schedule which occurrence is recorded
+//	status   - occurrence status
+//	errMsg   - why occurrence failed, nil if it succeeded
+func recordOccurrence(ctx context.Context, schedule Schedule, status string, errMsg interface{}) error {
+	var uow, _ = db.UnitOfWorkFrom(ctx)
+	var dbContext = uow.(db.DbContext)
+
+	var scheduledAt = schedule.NextRunAt
+	var transferId = occurrenceTransferId(schedule.Id, scheduledAt)
+
+	_, err := dbContext.Execute(
+		"INSERT INTO public.schedule_occurrences (schedule_id, scheduled_at, transfer_id, status, error) "+
+			"VALUES ($1, $2, $3, $4, $5) ON CONFLICT (schedule_id, scheduled_at) DO NOTHING",
+		uuid.UUID(schedule.Id), scheduledAt, transferId, status, errMsg,
+	)
+	if err != nil {
+		return err
+	}
+
+	schedule.Runs++
+	rule, err := parseRecurrence(schedule.Recurrence)
+	if err != nil {
+		return err
+	}
+
+	next, ok := rule.next(schedule.StartAt, scheduledAt, schedule.Runs)
+	if ok {
+		schedule.NextRunAt = next
+	} else {
+		schedule.Status = StatusCompleted
+	}
+
+	return updateSchedule(dbContext, schedule)
+}
+
+// Reads and locks schedule, changes it and saves changes
+//	id     - schedule id
+//	change - function that changes schedule
+// Returns changed schedule
+func (svc scheduleService) changeStatus(id ScheduleId, change func(schedule *Schedule) error) (*Schedule, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	schedule, err := readSchedule(dbContext, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule == nil {
+		return nil, ErrScheduleNotFound(id)
+	}
+
+	err = change(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateSchedule(dbContext, *schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbContext.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+var errQueryReturnedNoData = errors.New("no data returned from database request")
+
+// Reads schedule by id
+//	dbContext - db context
+//	id        - schedule id
+//	lock      - if true, schedule row is locked until transaction ends
+// Returns schedule or nil if schedule does not exist
+func readSchedule(dbContext db.DbContext, id ScheduleId, lock bool) (*Schedule, error) {
+	var sql = "SELECT " + scheduleColumns + " FROM public.transfer_schedules WHERE schedule_id = $1"
+	if lock {
+		sql += " FOR UPDATE"
+	}
+
+	var result *Schedule = nil
+	err := dbContext.Query(
+		sql,
+		sqlParams{uuid.UUID(id)},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return nil
+			}
+
+			schedule, err := scanSchedule(rows)
+			if err != nil {
+				return err
+			}
+
+			result = &schedule
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Reads schedule from current row. Row should contain columns listed in scheduleColumns
+func scanSchedule(rows db.QueryResultRows) (Schedule, error) {
+	var (
+		id       uuid.UUID
+		source   int64
+		dest     int64
+		schedule Schedule
+	)
+
+	err := rows.Scan(&id, &source, &dest, &schedule.Amount, &schedule.Memo, &schedule.Recurrence,
+		&schedule.Status, &schedule.NextRunAt, &schedule.Runs, &schedule.CreatedAt, &schedule.StartAt)
+	if err != nil {
+		return schedule, servErr.ErrDatabaseError(err)
+	}
+
+	schedule.Id = ScheduleId(id)
+	schedule.Source = account.AccountNumber(uint64(source))
+	schedule.Dest = account.AccountNumber(uint64(dest))
+
+	return schedule, nil
+}
+
+// Saves status, next run time and number of runs of schedule
+func updateSchedule(dbContext db.DbContext, schedule Schedule) error {
+	_, err := dbContext.Execute(
+		"UPDATE public.transfer_schedules SET status = $1, next_run_at = $2, runs = $3, updated_at = CURRENT_TIMESTAMP "+
+			"WHERE schedule_id = $4",
+		schedule.Status, schedule.NextRunAt, schedule.Runs, uuid.UUID(schedule.Id),
+	)
+
+	return err
+}
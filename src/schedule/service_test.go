@@ -0,0 +1,387 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/schedule"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+const (
+	dbAccountNumber1 int64 = 1
+	dbAccountNumber2 int64 = 2
+)
+
+var scheduleColumns = []string{"schedule_id", "source_account", "dest_account", "amount", "memo", "recurrence", "status", "next_run_at", "runs", "created_at", "start_at"}
+
+// Transfer service stub that records executed transfers
+type transferServiceStub struct {
+	transfer.TransferService
+
+	transferIds []transfer.TransferId
+
+	// Set if last transfer was executed in unit of work carried by context
+	joinedUnitOfWork bool
+
+	err error
+}
+
+func (stub *transferServiceStub) TransferMoney(ctx context.Context, id transfer.TransferId, source, dest account.AccountNumber, amount uint64, details transfer.TransferDetails) error {
+	_, stub.joinedUnitOfWork = db.UnitOfWorkFrom(ctx)
+	stub.transferIds = append(stub.transferIds, id)
+	return stub.err
+}
+
+func setupService(transferSvc transfer.TransferService, setupMock func(mock sqlmock.Sqlmock)) schedule.ScheduleService {
+	return schedule.NewScheduleService(func() (db.DbContext, error) {
+		return db.CreateMockDbContext(setupMock)
+	}, transferSvc)
+}
+
+func valdiateServiceError(expectedKind int, actual error, method string) (bool, string) {
+	if actual == nil {
+		return false, fmt.Sprintf("error expected to be returned by method %s", method)
+	}
+
+	err, ok := actual.(servErr.ServiceError)
+	if !ok {
+		return false, "expected error to be of type ServiceError"
+	}
+
+	if err.Kind() != expectedKind {
+		return false, fmt.Sprintf("expected error with kind %d, got %d", expectedKind, err.Kind())
+	}
+
+	return true, ""
+}
+
+func Test_CreateSchedule_InvalidRecurrence(t *testing.T) {
+	// Arrange
+	var service = setupService(&transferServiceStub{}, func(mock sqlmock.Sqlmock) {
+		t.Fatalf("db context should not be created for invalid schedule")
+	})
+
+	// Act
+	result, err := service.CreateSchedule(schedule.NewSchedule{
+		Source:     account.AccountNumber(dbAccountNumber1),
+		Dest:       account.AccountNumber(dbAccountNumber2),
+		Amount:     100,
+		StartAt:    time.Now(),
+		Recurrence: "FREQ=HOURLY",
+	})
+
+	// Assert
+	isValid, msg := valdiateServiceError(schedule.ErrKindInvalidSchedule, err, "CreateSchedule(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if result != nil {
+		t.Fatalf("in case of any error, CreateSchedule() should return (nil, error) as result")
+	}
+}
+
+func Test_CreateSchedule_AmountGreaterThanMaxAmount(t *testing.T) {
+	// Arrange
+	var service = setupService(&transferServiceStub{}, func(mock sqlmock.Sqlmock) {
+		t.Fatalf("db context should not be created for invalid schedule")
+	})
+
+	// Act
+	result, err := service.CreateSchedule(schedule.NewSchedule{
+		Source:  account.AccountNumber(dbAccountNumber1),
+		Dest:    account.AccountNumber(dbAccountNumber2),
+		Amount:  math.MaxUint64,
+		StartAt: time.Now(),
+	})
+
+	// Assert
+	isValid, msg := valdiateServiceError(schedule.ErrKindInvalidSchedule, err, "CreateSchedule(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if result != nil {
+		t.Fatalf("in case of any error, CreateSchedule() should return (nil, error) as result")
+	}
+}
+
+func Test_ExecuteDueSchedules_OccurrenceRecordedAndScheduleAdvanced(t *testing.T) {
+	// Arrange
+	var (
+		scheduleUuid       = uuid.New()
+		scheduledAt        = time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+		amount       int64 = 500
+	)
+	var expectedTransferId = uuid.NewSHA1(scheduleUuid, []byte(scheduledAt.Format(time.RFC3339Nano)))
+
+	var transferSvc = &transferServiceStub{}
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(transferSvc, func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.NewRows(scheduleColumns).
+			AddRow(scheduleUuid, dbAccountNumber1, dbAccountNumber2, amount, "allowance", "FREQ=WEEKLY", schedule.StatusActive, scheduledAt, 0, scheduledAt, scheduledAt)
+		mock.ExpectQuery("SELECT .* FROM public.transfer_schedules .* FOR UPDATE SKIP LOCKED").WillReturnRows(rows)
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec("INSERT INTO public.schedule_occurrences").
+			WithArgs(scheduleUuid, scheduledAt, expectedTransferId, schedule.OccurrenceSucceeded, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("UPDATE public.transfer_schedules").
+			WithArgs(schedule.StatusActive, scheduledAt.AddDate(0, 0, 7), int64(1), scheduleUuid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("RELEASE SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	executed, err := service.ExecuteDueSchedules(scheduledAt.Add(time.Minute), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ExecuteDueSchedules() was called: %s", err.Error())
+	}
+
+	if executed != 1 {
+		t.Fatalf("expected 1 executed occurrence, got %d", executed)
+	}
+
+	if len(transferSvc.transferIds) != 1 || uuid.UUID(transferSvc.transferIds[0]) != expectedTransferId {
+		t.Fatalf("transfer should be executed with id derived from schedule id and occurrence time")
+	}
+
+	if !transferSvc.joinedUnitOfWork {
+		t.Fatalf("transfer should be executed in transaction where schedule is locked")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_ExecuteDueSchedules_MonthlyScheduleAdvancedFromStartDay(t *testing.T) {
+	// Arrange
+	var (
+		scheduleUuid       = uuid.New()
+		startAt            = time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+		scheduledAt        = time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)
+		amount       int64 = 500
+	)
+	var expectedTransferId = uuid.NewSHA1(scheduleUuid, []byte(scheduledAt.Format(time.RFC3339Nano)))
+
+	var transferSvc = &transferServiceStub{}
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(transferSvc, func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		// occurrence in February is clamped to its last day
+		var rows = sqlmock.NewRows(scheduleColumns).
+			AddRow(scheduleUuid, dbAccountNumber1, dbAccountNumber2, amount, "", "FREQ=MONTHLY", schedule.StatusActive, scheduledAt, 1, startAt, startAt)
+		mock.ExpectQuery("SELECT .* FROM public.transfer_schedules .* FOR UPDATE SKIP LOCKED").WillReturnRows(rows)
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec("INSERT INTO public.schedule_occurrences").
+			WithArgs(scheduleUuid, scheduledAt, expectedTransferId, schedule.OccurrenceSucceeded, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("UPDATE public.transfer_schedules").
+			WithArgs(schedule.StatusActive, time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC), int64(2), scheduleUuid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("RELEASE SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	executed, err := service.ExecuteDueSchedules(scheduledAt.Add(time.Minute), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ExecuteDueSchedules() was called: %s", err.Error())
+	}
+
+	if executed != 1 {
+		t.Fatalf("expected 1 executed occurrence, got %d", executed)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_ExecuteDueSchedules_FailedTransferRecorded(t *testing.T) {
+	// Arrange
+	var (
+		scheduleUuid       = uuid.New()
+		scheduledAt        = time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+		amount       int64 = 500
+	)
+
+	var transferSvc = &transferServiceStub{err: transfer.ErrNotEnoughMoney}
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(transferSvc, func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.NewRows(scheduleColumns).
+			AddRow(scheduleUuid, dbAccountNumber1, dbAccountNumber2, amount, "", "", schedule.StatusActive, scheduledAt, 0, scheduledAt, scheduledAt)
+		mock.ExpectQuery("SELECT .* FROM public.transfer_schedules .* FOR UPDATE SKIP LOCKED").WillReturnRows(rows)
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec("INSERT INTO public.schedule_occurrences").
+			WithArgs(scheduleUuid, scheduledAt, sqlmock.AnyArg(), schedule.OccurrenceFailed, transfer.ErrNotEnoughMoney.Error()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// One-off schedule is completed after first occurrence
+		mock.ExpectExec("UPDATE public.transfer_schedules").
+			WithArgs(schedule.StatusCompleted, scheduledAt, int64(1), scheduleUuid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("RELEASE SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	executed, err := service.ExecuteDueSchedules(scheduledAt.Add(time.Minute), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ExecuteDueSchedules() was called: %s", err.Error())
+	}
+
+	if executed != 1 {
+		t.Fatalf("expected 1 executed occurrence, got %d", executed)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_ExecuteDueSchedules_DatabaseError_OccurrenceRetriedLaterAndNextScheduleExecuted(t *testing.T) {
+	// Arrange
+	var (
+		failedUuid        = uuid.New()
+		nextUuid          = uuid.New()
+		scheduledAt       = time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+		amount      int64 = 500
+		expectedErr       = errors.New("database related error")
+	)
+
+	var transferSvc = &transferServiceStub{}
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(transferSvc, func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.NewRows(scheduleColumns).
+			AddRow(failedUuid, dbAccountNumber1, dbAccountNumber2, amount, "", "", schedule.StatusActive, scheduledAt, 0, scheduledAt, scheduledAt).
+			AddRow(nextUuid, dbAccountNumber1, dbAccountNumber2, amount, "", "", schedule.StatusActive, scheduledAt, 0, scheduledAt, scheduledAt)
+		mock.ExpectQuery("SELECT .* FROM public.transfer_schedules .* FOR UPDATE SKIP LOCKED").WillReturnRows(rows)
+
+		// occurrence of first schedule can't be recorded, its savepoint is rolled back
+		// and schedule is not changed, so it is executed again later
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO public.schedule_occurrences").
+			WithArgs(failedUuid, scheduledAt, sqlmock.AnyArg(), schedule.OccurrenceSucceeded, nil).
+			WillReturnError(expectedErr)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO public.schedule_occurrences").
+			WithArgs(nextUuid, scheduledAt, sqlmock.AnyArg(), schedule.OccurrenceSucceeded, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE public.transfer_schedules").
+			WithArgs(schedule.StatusCompleted, scheduledAt, int64(1), nextUuid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	executed, err := service.ExecuteDueSchedules(scheduledAt.Add(time.Minute), 10)
+
+	// Assert
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("expected error of failed occurrence, got %v", err)
+	}
+
+	if executed != 1 {
+		t.Fatalf("expected 1 executed occurrence, got %d", executed)
+	}
+
+	if len(transferSvc.transferIds) != 2 {
+		t.Fatalf("transfer of next schedule should be executed after failed one, got %d transfers", len(transferSvc.transferIds))
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_ResumeSchedule_OnlyPausedScheduleCanBeResumed(t *testing.T) {
+	// Arrange
+	var (
+		scheduleUuid = uuid.New()
+		now          = time.Now().UTC()
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(&transferServiceStub{}, func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.NewRows(scheduleColumns).
+			AddRow(scheduleUuid, dbAccountNumber1, dbAccountNumber2, 100, "", "", schedule.StatusCancelled, now, 0, now, now)
+		mock.ExpectQuery("SELECT .* FROM public.transfer_schedules WHERE schedule_id = \\$1 FOR UPDATE").
+			WithArgs(scheduleUuid).
+			WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	result, err := service.ResumeSchedule(schedule.ScheduleId(scheduleUuid))
+
+	// Assert
+	isValid, msg := valdiateServiceError(schedule.ErrKindInvalidScheduleStatus, err, "ResumeSchedule(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if result != nil {
+		t.Fatalf("in case of any error, ResumeSchedule() should return (nil, error) as result")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"test/coins/transfer"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	kittransport "github.com/go-kit/kit/transport"
+	kithttp "github.com/go-kit/kit/transport/http"
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// Registers http handlers for schedule service
+// mr     - Mux router where handlers should be registered
+// svc    - service to register
+// logger - logger
+func RegisterHandlers(mr *mux.Router, svc ScheduleService, logger kitlog.Logger) {
+	var opts = []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
+		kithttp.ServerErrorEncoder(servErr.EncodeError),
+	}
+
+	var createScheduleHandler = kithttp.NewServer(
+		makeCreateScheduleEndpoint(svc),
+		decodeCreateScheduleRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/schedules", createScheduleHandler).Methods("POST")
+
+	var listSchedulesHandler = kithttp.NewServer(
+		makeListSchedulesEndpoint(svc),
+		decodeListSchedulesRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/accounts/{account}/schedules", listSchedulesHandler).Methods("GET")
+
+	var pauseScheduleHandler = kithttp.NewServer(
+		makeChangeScheduleEndpoint(svc.PauseSchedule),
+		decodeScheduleIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/schedules/{id}/pause", pauseScheduleHandler).Methods("POST")
+
+	var resumeScheduleHandler = kithttp.NewServer(
+		makeChangeScheduleEndpoint(svc.ResumeSchedule),
+		decodeScheduleIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/schedules/{id}/resume", resumeScheduleHandler).Methods("POST")
+
+	var cancelScheduleHandler = kithttp.NewServer(
+		makeChangeScheduleEndpoint(svc.CancelSchedule),
+		decodeScheduleIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/schedules/{id}/cancel", cancelScheduleHandler).Methods("POST")
+
+	var listOccurrencesHandler = kithttp.NewServer(
+		makeListOccurrencesEndpoint(svc),
+		decodeScheduleIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/schedules/{id}/occurrences", listOccurrencesHandler).Methods("GET")
+}
+
+func decodeCreateScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body createScheduleRequest
+	if err := transfer.DecodeStrictJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func decodeListSchedulesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var vars = mux.Vars(r)
+	accountNumber, ok := vars["account"]
+	if !ok {
+		return nil, servErr.ErrInvalidRequest("invalid account number", nil)
+	}
+
+	accNum, err := strconv.ParseUint(accountNumber, 10, 64)
+	if err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid account number", err)
+	}
+	return listSchedulesRequest{accNum}, nil
+}
+
+func decodeScheduleIdRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var vars = mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, servErr.ErrInvalidRequest("invalid schedule id", nil)
+	}
+
+	guid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid schedule id", err)
+	}
+	return scheduleIdRequest{ScheduleId(guid)}, nil
+}
+
+type errorer interface {
+	error() error
+}
+
+func encodeResponse(ctx context.Context, wr http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		servErr.EncodeError(ctx, e.error(), wr)
+		return nil
+	}
+	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(wr).Encode(response)
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Background worker that periodically executes due schedules
+type Worker struct {
+	svc ScheduleService
+
+	interval time.Duration
+
+	batchSize int
+
+	logger kitlog.Logger
+}
+
+// Creates new schedule worker
+//	svc       - schedule service
+//	interval  - interval between checks for due schedules
+//	batchSize - max number of schedules executed in single transaction
+//	logger    - logger
+func NewWorker(svc ScheduleService, interval time.Duration, batchSize int, logger kitlog.Logger) *Worker {
+	return &Worker{svc, interval, batchSize, logger}
+}
+
+// Runs worker until context is cancelled
+//	ctx - context used to stop worker
+func (w *Worker) Run(ctx context.Context) {
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.executeDueSchedules()
+		}
+	}
+}
+
+// Executes due schedules batch by batch, until there is no more due schedules
+func (w *Worker) executeDueSchedules() {
+	for {
+		executed, err := w.svc.ExecuteDueSchedules(time.Now(), w.batchSize)
+		if err != nil {
+			level.Error(w.logger).Log("msg", "failed to execute due schedules", "err", err)
+			return
+		}
+
+		if executed > 0 {
+			level.Info(w.logger).Log("msg", "executed scheduled transfers", "count", executed)
+		}
+
+		if executed < w.batchSize {
+			return
+		}
+	}
+}
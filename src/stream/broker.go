@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+	"test/coins/account"
+	"test/coins/outbox"
+	"test/coins/transfer"
+)
+
+// Number of events buffered for each subscriber. If subscriber does not read events fast enough,
+// its subscription is closed and client should reconnect with Last-Event-ID
+const subscriberBufferSize = 64
+
+// In-process broker that delivers account events to stream subscribers.
+// It implements outbox.Publisher, so events are pushed after transfers are committed
+type Broker struct {
+	mutex sync.Mutex
+
+	subscribers map[account.AccountNumber]map[*Subscriber]struct{}
+}
+
+// Subscriber of account events
+type Subscriber struct {
+	events chan AccountEvent
+}
+
+// Returns channel of account events. Channel is closed when subscriber is removed
+// or does not read events fast enough
+func (sub *Subscriber) Events() <-chan AccountEvent {
+	return sub.events
+}
+
+// Creates new broker
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[account.AccountNumber]map[*Subscriber]struct{}{}}
+}
+
+// Subscribes to events of account
+//	accountNum - account number
+// Returns subscriber and function that removes it
+func (broker *Broker) Subscribe(accountNum account.AccountNumber) (*Subscriber, func()) {
+	var sub = &Subscriber{make(chan AccountEvent, subscriberBufferSize)}
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	if broker.subscribers[accountNum] == nil {
+		broker.subscribers[accountNum] = map[*Subscriber]struct{}{}
+	}
+	broker.subscribers[accountNum][sub] = struct{}{}
+
+	return sub, func() {
+		broker.mutex.Lock()
+		defer broker.mutex.Unlock()
+
+		broker.remove(accountNum, sub)
+	}
+}
+
+// Removes subscriber and closes its channel. Should be called under lock
+func (broker *Broker) remove(accountNum account.AccountNumber, sub *Subscriber) {
+	var subs = broker.subscribers[accountNum]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	close(sub.events)
+	if len(subs) == 0 {
+		delete(broker.subscribers, accountNum)
+	}
+}
+
+func (broker *Broker) Publish(event outbox.Event) error {
+	if event.Type != transfer.EventTransferCompleted {
+		return nil
+	}
+
+	var completed transfer.TransferCompletedEvent
+	err := json.Unmarshal(event.Payload, &completed)
+	if err != nil {
+		return err
+	}
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	for _, accountEvent := range accountEvents(event.Id, completed) {
+		for sub := range broker.subscribers[accountEvent.Account] {
+			select {
+			case sub.events <- accountEvent:
+			default:
+				// Slow subscriber is dropped instead of blocking relay
+				broker.remove(accountEvent.Account, sub)
+			}
+		}
+	}
+
+	return nil
+}
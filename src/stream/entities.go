@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"test/coins/account"
+	"test/coins/transfer"
+)
+
+// Name of SSE event that is sent when account transfer is completed
+const EventTransfer = "transfer"
+
+// Event that is pushed to account stream
+type AccountEvent struct {
+	// Event id, id of outbox event. Used as SSE event id, so client can resume stream
+	Id int64 `json:"id"`
+
+	// Account number
+	Account account.AccountNumber `json:"account"`
+
+	// Completed incoming or outgoing transfer
+	Transfer transfer.Transfer `json:"transfer"`
+
+	// Account balance after transfer. Not set for incoming transfer of hot account
+	Balance *int64 `json:"balance,omitempty"`
+}
+
+// Converts completed transfer to events of its accounts
+//	eventId   - outbox event id
+//	completed - completed transfer
+// Returns events of source and dest accounts
+func accountEvents(eventId int64, completed transfer.TransferCompletedEvent) []AccountEvent {
+	return []AccountEvent{
+		{
+			Id:       eventId,
+			Account:  completed.Source,
+			Transfer: completed.TransferFor(completed.Source),
+			Balance:  &completed.SourceBalance,
+		},
+		{
+			Id:       eventId,
+			Account:  completed.Dest,
+			Transfer: completed.TransferFor(completed.Dest),
+			Balance:  completed.DestBalance,
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package stream
+
+import (
+	"fmt"
+	"net/http"
+	"test/coins/account"
+
+	servErr "test/coins/errors"
+)
+
+const (
+	ErrKindAccountNotFound int = 50 + iota
+	ErrKindInvalidLastEventId
+)
+
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindAccountNotFound, servErr.ErrorDefinition{Code: "account_not_found", Status: http.StatusNotFound, Title: "Account not found"})
+	servErr.RegisterKind(ErrKindInvalidLastEventId, servErr.ErrorDefinition{Code: "invalid_last_event_id", Status: http.StatusBadRequest, Title: "Invalid last event id"})
+}
+
+// Creates new "Account not found" error
+//	accountNum - account number
+// Returns created error
+func ErrAccountNotFound(accountNum account.AccountNumber) error {
+	var msg = fmt.Sprintf("account [%d] not found", uint64(accountNum))
+	return servErr.NewServiceError(msg, nil, ErrKindAccountNotFound)
+}
+
+// Creates new "Invalid Last-Event-ID" error
+//	value - received value
+// Returns created error
+func ErrInvalidLastEventId(value string) error {
+	var msg = fmt.Sprintf("invalid last event id [%s]", value)
+	return servErr.NewServiceError(msg, nil, ErrKindInvalidLastEventId)
+}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/transfer"
+
+	servErr "test/coins/errors"
+)
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+var errQueryReturnedNoData = errors.New("no data returned from database request")
+
+// Stream service. Provides events missed by stream clients
+type StreamService interface {
+	// Returns events of account that happened after specified event, in order they happened
+	//	accountNum  - account number
+	//	lastEventId - id of last event received by client, nil if client does not resume stream.
+	//	              In that case only account is validated and no events are returned
+	//	limit       - max number of returned events
+	ReplayEvents(accountNum account.AccountNumber, lastEventId *int64, limit int) ([]AccountEvent, error)
+}
+
+// Stream service implementation
+type streamService struct {
+	dbContextFactory func() (db.DbContext, error)
+}
+
+// Creates new stream service
+//	dbContextFactory - factory function used to create new db context
+func NewStreamService(dbContextFactory func() (db.DbContext, error)) StreamService {
+	return streamService{dbContextFactory}
+}
+
+func (svc streamService) ReplayEvents(accountNum account.AccountNumber, lastEventId *int64, limit int) ([]AccountEvent, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var count int64 = 0
+	err = dbContext.Query(
+		"SELECT COUNT(*) FROM public.accounts WHERE account_number = $1 AND account_type = $2",
+		sqlParams{int64(uint64(accountNum)), account.AccountTypeCustomer},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&count)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, ErrAccountNotFound(accountNum)
+	}
+
+	var result = []AccountEvent{}
+	if lastEventId == nil {
+		return result, nil
+	}
+
+	// Events are read from outbox by sequence numbers assigned by relay, which are ids of published events,
+	// so client gets events that were numbered but not yet published by relay.
+	// Outgoing and incoming events are read by their own indexes, each part is limited, so only events
+	// of returned page are read
+	err = dbContext.Query(
+		"SELECT sequence_number, payload FROM ("+
+			"SELECT * FROM (SELECT sequence_number, payload FROM public.outbox_events "+
+			"WHERE (payload->>'source')::bigint = $3 AND sequence_number > $1 AND event_type = $2 ORDER BY sequence_number LIMIT $4) outgoing "+
+			"UNION ALL "+
+			"SELECT * FROM (SELECT sequence_number, payload FROM public.outbox_events "+
+			"WHERE (payload->>'dest')::bigint = $3 AND sequence_number > $1 AND event_type = $2 ORDER BY sequence_number LIMIT $4) incoming"+
+			") events ORDER BY sequence_number LIMIT $4",
+		sqlParams{*lastEventId, transfer.EventTransferCompleted, int64(uint64(accountNum)), limit},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					id        int64
+					payload   []byte
+					completed transfer.TransferCompletedEvent
+				)
+
+				err := rows.Scan(&id, &payload)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				err = json.Unmarshal(payload, &completed)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				for _, event := range accountEvents(id, completed) {
+					if event.Account == accountNum {
+						result = append(result, event)
+						break
+					}
+				}
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,347 @@
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"test/coins/stream"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+const (
+	dbAccountNumber1 int64 = 1
+	dbAccountNumber2 int64 = 2
+)
+
+// Stream service stub that returns predefined events
+type streamServiceStub struct {
+	events []stream.AccountEvent
+
+	// Number of ReplayEvents calls
+	calls int
+}
+
+func (stub *streamServiceStub) ReplayEvents(accountNum account.AccountNumber, lastEventId *int64, limit int) ([]stream.AccountEvent, error) {
+	stub.calls++
+	var result = []stream.AccountEvent{}
+	for _, event := range stub.events {
+		if lastEventId != nil && event.Id > *lastEventId && len(result) < limit {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+func setupService(setupMock func(mock sqlmock.Sqlmock)) stream.StreamService {
+	return stream.NewStreamService(func() (db.DbContext, error) {
+		return db.CreateMockDbContext(setupMock)
+	})
+}
+
+func completedEvent(id int64, amount int64) outbox.Event {
+	payload, _ := json.Marshal(transfer.TransferCompletedEvent{
+		Id:            transfer.TransferId(uuid.New()),
+		Type:          transfer.TransferTypeTransfer,
+		Source:        account.AccountNumber(dbAccountNumber1),
+		Dest:          account.AccountNumber(dbAccountNumber2),
+		Amount:        amount,
+		SourceBalance: 1000 - amount,
+		DestBalance:   &amount,
+	})
+
+	return outbox.Event{Id: id, Type: transfer.EventTransferCompleted, Payload: payload}
+}
+
+func valdiateServiceError(expectedKind int, actual error, method string) (bool, string) {
+	if actual == nil {
+		return false, fmt.Sprintf("error expected to be returned by method %s", method)
+	}
+
+	err, ok := actual.(servErr.ServiceError)
+	if !ok {
+		return false, "expected error to be of type ServiceError"
+	}
+
+	if err.Kind() != expectedKind {
+		return false, fmt.Sprintf("expected error with kind %d, got %d", expectedKind, err.Kind())
+	}
+
+	return true, ""
+}
+
+func Test_Broker_EventsDeliveredToSourceAndDest(t *testing.T) {
+	// Arrange
+	var broker = stream.NewBroker()
+	source, unsubscribeSource := broker.Subscribe(account.AccountNumber(dbAccountNumber1))
+	defer unsubscribeSource()
+	dest, unsubscribeDest := broker.Subscribe(account.AccountNumber(dbAccountNumber2))
+	defer unsubscribeDest()
+
+	// Act
+	err := broker.Publish(completedEvent(5, 100))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Publish() was called: %s", err.Error())
+	}
+
+	var sent = <-source.Events()
+	if sent.Id != 5 || sent.Transfer.Direction != transfer.DirectionOutgoing || sent.Balance == nil || *sent.Balance != 900 {
+		t.Fatalf("source account should receive outgoing transfer with new balance")
+	}
+
+	var received = <-dest.Events()
+	if received.Id != 5 || received.Transfer.Direction != transfer.DirectionIncoming || received.Balance == nil || *received.Balance != 100 {
+		t.Fatalf("dest account should receive incoming transfer with new balance")
+	}
+}
+
+func Test_Broker_SlowSubscriberDropped(t *testing.T) {
+	// Arrange
+	var broker = stream.NewBroker()
+	sub, unsubscribe := broker.Subscribe(account.AccountNumber(dbAccountNumber1))
+	defer unsubscribe()
+
+	// Act
+	for i := int64(1); i <= 100; i++ {
+		broker.Publish(completedEvent(i, 1))
+	}
+
+	// Assert
+	var count = 0
+	for range sub.Events() {
+		count++
+	}
+
+	if count == 0 || count >= 100 {
+		t.Fatalf("slow subscriber should receive buffered events and then its channel should be closed, got %d events", count)
+	}
+}
+
+func Test_ReplayEvents_AccountEventsReturned(t *testing.T) {
+	// Arrange
+	var lastEventId int64 = 3
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM public.accounts").
+			WithArgs(dbAccountNumber2, account.AccountTypeCustomer).
+			WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(1))
+
+		var rows = sqlmock.NewRows([]string{"sequence_number", "payload"}).
+			AddRow(int64(4), []byte(completedEvent(4, 100).Payload)).
+			AddRow(int64(6), []byte(completedEvent(6, 200).Payload))
+		mock.ExpectQuery("SELECT sequence_number, payload FROM \\(.* UNION ALL .*\\) events ORDER BY sequence_number LIMIT \\$4").
+			WithArgs(lastEventId, transfer.EventTransferCompleted, dbAccountNumber2, 10).
+			WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	events, err := service.ReplayEvents(account.AccountNumber(dbAccountNumber2), &lastEventId, 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ReplayEvents() was called: %s", err.Error())
+	}
+
+	if len(events) != 2 || events[0].Id != 4 || events[1].Id != 6 {
+		t.Fatalf("expected 2 events in order of their ids")
+	}
+
+	if events[1].Transfer.Direction != transfer.DirectionIncoming || events[1].Balance == nil || *events[1].Balance != 200 {
+		t.Fatalf("events should be returned as they are seen by requested account")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_ReplayEvents_AccountNotFound(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM public.accounts").
+			WillReturnRows(sqlmock.NewRows([]string{""}).AddRow(0))
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	events, err := service.ReplayEvents(account.AccountNumber(dbAccountNumber1), nil, 10)
+
+	// Assert
+	isValid, msg := valdiateServiceError(stream.ErrKindAccountNotFound, err, "ReplayEvents(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if events != nil {
+		t.Fatalf("in case of any error, ReplayEvents() should return (nil, error) as result")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_EventsStream_ResumedFromLastEventId(t *testing.T) {
+	// Arrange
+	var svc = &streamServiceStub{events: []stream.AccountEvent{
+		{Id: 4, Account: account.AccountNumber(dbAccountNumber2)},
+		{Id: 6, Account: account.AccountNumber(dbAccountNumber2)},
+	}}
+	var broker = stream.NewBroker()
+	var mr = mux.NewRouter()
+	stream.RegisterHandlers(mr, svc, broker, kitlog.NewNopLogger())
+
+	var server = httptest.NewServer(mr)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/accounts/2/events", nil)
+	req.Header.Set("Last-Event-ID", "4")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error occured when stream was requested: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	// Event 6 was already replayed, so live event with the same id should be skipped
+	broker.Publish(completedEvent(6, 100))
+	broker.Publish(completedEvent(7, 100))
+
+	var ids = []string{}
+	var scanner = bufio.NewScanner(resp.Body)
+	for len(ids) < 2 && scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			ids = append(ids, strings.TrimPrefix(scanner.Text(), "id: "))
+		}
+	}
+
+	// Assert
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream should have text/event-stream content type")
+	}
+
+	if strings.Join(ids, ",") != "6,7" {
+		t.Fatalf("expected events 6 and 7 to be sent, got %v", ids)
+	}
+}
+
+func Test_EventsStream_MissedEventsReplayedPageByPage(t *testing.T) {
+	// Arrange
+	var svc = &streamServiceStub{}
+	for id := int64(1); id <= 1200; id++ {
+		svc.events = append(svc.events, stream.AccountEvent{Id: id, Account: account.AccountNumber(dbAccountNumber2)})
+	}
+
+	var mr = mux.NewRouter()
+	stream.RegisterHandlers(mr, svc, stream.NewBroker(), kitlog.NewNopLogger())
+
+	var server = httptest.NewServer(mr)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/accounts/2/events", nil)
+	req.Header.Set("Last-Event-ID", "0")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error occured when stream was requested: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var ids = []string{}
+	var scanner = bufio.NewScanner(resp.Body)
+	for len(ids) < 1200 && scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			ids = append(ids, strings.TrimPrefix(scanner.Text(), "id: "))
+		}
+	}
+
+	// Assert
+	if len(ids) != 1200 || ids[0] != "1" || ids[1199] != "1200" {
+		t.Fatalf("all missed events should be sent in order, got %d events", len(ids))
+	}
+
+	if svc.calls != 3 {
+		t.Fatalf("missed events should be read by 3 pages, got %d calls", svc.calls)
+	}
+}
+
+func Test_EventsStream_EventPublishedOutOfOrderSent(t *testing.T) {
+	// Arrange
+	var svc = &streamServiceStub{}
+	var broker = stream.NewBroker()
+	var mr = mux.NewRouter()
+	stream.RegisterHandlers(mr, svc, broker, kitlog.NewNopLogger())
+
+	var server = httptest.NewServer(mr)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/accounts/2/events", nil)
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error occured when stream was requested: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	// Credit to hot account with lower id is committed later, event 9 is published again by relay
+	broker.Publish(completedEvent(9, 100))
+	broker.Publish(completedEvent(8, 100))
+	broker.Publish(completedEvent(9, 100))
+	broker.Publish(completedEvent(10, 100))
+
+	var ids = []string{}
+	var scanner = bufio.NewScanner(resp.Body)
+	for len(ids) < 3 && scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			ids = append(ids, strings.TrimPrefix(scanner.Text(), "id: "))
+		}
+	}
+
+	// Assert
+	if strings.Join(ids, ",") != "9,8,10" {
+		t.Fatalf("expected events 9, 8 and 10 to be sent once, got %v", ids)
+	}
+}
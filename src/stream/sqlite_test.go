@@ -0,0 +1,108 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/migrations"
+	"test/coins/stream"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Opens in-memory SQLite database and applies migrations to it
+func openSQLitePool(tb testing.TB) db.ConnectionPool {
+	pool, err := db.NewConnectionPool("sqlite://:memory:", 1, time.Second)
+	if err != nil {
+		tb.Fatalf("unable to open SQLite database: %s", err.Error())
+	}
+	tb.Cleanup(pool.Close)
+
+	all, err := migrations.Load()
+	if err != nil {
+		tb.Fatalf("unable to load migrations: %s", err.Error())
+	}
+
+	_, err = migrations.NewMigrator(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}, all).Up()
+	if err != nil {
+		tb.Fatalf("unable to migrate SQLite database: %s", err.Error())
+	}
+
+	return pool
+}
+
+func Test_ReplayEvents_SQLite_AccountEventsReturnedByPages(t *testing.T) {
+	// Arrange
+	var pool = openSQLitePool(t)
+	var service = stream.NewStreamService(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	})
+
+	dbContext, err := db.CreateContext(pool, time.Second*5)
+	if err != nil {
+		t.Fatalf("unable to create db context: %s", err.Error())
+	}
+
+	// events 1, 3 and 4 are events of account 2, event 2 is event of other accounts
+	var transfers = []struct {
+		sequenceNumber int64
+		source         int64
+		dest           int64
+	}{
+		{1, dbAccountNumber1, dbAccountNumber2},
+		{2, 3, 4},
+		{3, dbAccountNumber2, dbAccountNumber1},
+		{4, dbAccountNumber1, dbAccountNumber2},
+	}
+	for _, tr := range transfers {
+		payload, _ := json.Marshal(transfer.TransferCompletedEvent{
+			Id:     transfer.TransferId(uuid.New()),
+			Type:   transfer.TransferTypeTransfer,
+			Source: account.AccountNumber(tr.source),
+			Dest:   account.AccountNumber(tr.dest),
+			Amount: 100,
+		})
+
+		_, err = dbContext.Execute(
+			"INSERT INTO public.outbox_events (event_type, payload, created_at, sequence_number) VALUES ($1, $2, $3, $4)",
+			transfer.EventTransferCompleted, string(payload), time.Now().UTC(), tr.sequenceNumber,
+		)
+		if err != nil {
+			t.Fatalf("unable to write event: %s", err.Error())
+		}
+	}
+
+	err = dbContext.Save()
+	dbContext.Release()
+	if err != nil {
+		t.Fatalf("unable to save events: %s", err.Error())
+	}
+
+	// Act
+	var lastEventId int64 = 0
+	first, err := service.ReplayEvents(account.AccountNumber(dbAccountNumber2), &lastEventId, 2)
+	if err != nil {
+		t.Fatalf("unexpected error occured when ReplayEvents() was called: %s", err.Error())
+	}
+
+	lastEventId = first[len(first)-1].Id
+	second, err := service.ReplayEvents(account.AccountNumber(dbAccountNumber2), &lastEventId, 2)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ReplayEvents() was called: %s", err.Error())
+	}
+
+	if len(first) != 2 || first[0].Id != 1 || first[1].Id != 3 {
+		t.Fatalf("first page should contain events 1 and 3, got %v", first)
+	}
+
+	if len(second) != 1 || second[0].Id != 4 {
+		t.Fatalf("second page should contain event 4, got %v", second)
+	}
+}
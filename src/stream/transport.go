@@ -0,0 +1,222 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test/coins/account"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	servErr "test/coins/errors"
+)
+
+// Max number of events replayed by single query when client resumes stream
+const replayBatchSize = 500
+
+// Interval between keep-alive comments, so proxies don't close idle stream
+const heartbeatInterval = 15 * time.Second
+
+// Number of ids of sent events kept by stream to skip events that were already sent
+const sentEventsCapacity = 1024
+
+// Registers http handlers for stream service
+// mr     - Mux router where handlers should be registered
+// svc    - service to register
+// broker - broker that delivers live events
+// logger - logger
+func RegisterHandlers(mr *mux.Router, svc StreamService, broker *Broker, logger kitlog.Logger) {
+	mr.Handle("/api/v1/accounts/{account}/events", eventsHandler{svc, broker, logger}).Methods("GET")
+}
+
+// Server-Sent Events handler of account events
+type eventsHandler struct {
+	svc StreamService
+
+	broker *Broker
+
+	logger kitlog.Logger
+}
+
+func (h eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var ctx = r.Context()
+
+	accountNum, lastEventId, err := decodeEventsRequest(r)
+	if err != nil {
+		servErr.EncodeError(ctx, err, w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		servErr.EncodeError(ctx, errors.New("streaming is not supported"), w)
+		return
+	}
+
+	// Subscribing before replay, so events committed during replay are not lost
+	sub, unsubscribe := h.broker.Subscribe(accountNum)
+	defer unsubscribe()
+
+	// First page of missed events is read before response is started, so invalid account is reported by error response
+	events, err := h.svc.ReplayEvents(accountNum, lastEventId, replayBatchSize)
+	if err != nil {
+		servErr.EncodeError(ctx, err, w)
+		return
+	}
+
+	// Events of hot account can be committed out of order of their ids, so live events
+	// are skipped only if they were already sent, not if their id is less than id of last sent event
+	var sent = newSentEvents(sentEventsCapacity)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Missed events are sent page by page, so only one page is kept in memory
+	for {
+		for _, event := range events {
+			err = writeEvent(w, event)
+			if err != nil {
+				return
+			}
+			sent.add(event.Id)
+		}
+		flusher.Flush()
+
+		if lastEventId == nil || len(events) < replayBatchSize {
+			break
+		}
+
+		var after = events[len(events)-1].Id
+		events, err = h.svc.ReplayEvents(accountNum, &after, replayBatchSize)
+		if err != nil {
+			// Response is already started, client reconnects with id of last sent event
+			level.Error(h.logger).Log("msg", "failed to replay stream events", "account", uint64(accountNum), "err", err)
+			return
+		}
+	}
+
+	var heartbeat = time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Subscriber was dropped as too slow, client should reconnect with Last-Event-ID
+				level.Warn(h.logger).Log("msg", "stream subscriber dropped", "account", uint64(accountNum))
+				return
+			}
+
+			if sent.contains(event.Id) {
+				// Event was already sent during replay or published again by relay
+				continue
+			}
+
+			err = writeEvent(w, event)
+			if err != nil {
+				return
+			}
+			sent.add(event.Id)
+			flusher.Flush()
+		}
+	}
+}
+
+// Ids of events sent to stream. Only recent ids are kept, the oldest id is removed when capacity is reached
+type sentEvents struct {
+	ids map[int64]struct{}
+
+	// Kept ids in order they were added
+	order []int64
+
+	capacity int
+}
+
+// Creates new set of sent event ids
+//	capacity - max number of kept ids
+func newSentEvents(capacity int) *sentEvents {
+	return &sentEvents{ids: map[int64]struct{}{}, capacity: capacity}
+}
+
+// Adds id of sent event
+//	id - event id
+func (sent *sentEvents) add(id int64) {
+	if sent.contains(id) {
+		return
+	}
+
+	if len(sent.order) >= sent.capacity {
+		delete(sent.ids, sent.order[0])
+		sent.order = sent.order[1:]
+	}
+
+	sent.ids[id] = struct{}{}
+	sent.order = append(sent.order, id)
+}
+
+// Checks if event was sent
+//	id - event id
+func (sent *sentEvents) contains(id int64) bool {
+	_, ok := sent.ids[id]
+	return ok
+}
+
+// Writes event in Server-Sent Events format
+//	w     - response writer
+//	event - account event
+func writeEvent(w http.ResponseWriter, event AccountEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, EventTransfer, data)
+	return err
+}
+
+func decodeEventsRequest(r *http.Request) (account.AccountNumber, *int64, error) {
+	var vars = mux.Vars(r)
+	accountNumber, ok := vars["account"]
+	if !ok {
+		return 0, nil, servErr.ErrInvalidRequest("invalid account number", nil)
+	}
+
+	accNum, err := strconv.ParseUint(accountNumber, 10, 64)
+	if err != nil {
+		return 0, nil, servErr.ErrInvalidRequest("invalid account number", err)
+	}
+
+	// Browsers send Last-Event-ID header on reconnect, query parameter can be used on first connect
+	var lastEventId = r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+
+	if lastEventId == "" {
+		return account.AccountNumber(accNum), nil, nil
+	}
+
+	id, err := strconv.ParseInt(lastEventId, 10, 64)
+	if err != nil || id < 0 {
+		return 0, nil, ErrInvalidLastEventId(lastEventId)
+	}
+
+	return account.AccountNumber(accNum), &id, nil
+}
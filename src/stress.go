@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"test/coins/account"
+	"test/coins/config"
+	"test/coins/db"
+	"test/coins/memory"
+	"test/coins/stress"
+	"test/coins/transfer"
+)
+
+// Runs "stress" command: sends random concurrent transfers and checks that invariants hold.
+// Command flags are followed by configuration flags after "--", for example
+// "coins stress -transfers 10000 -- -database-cs sqlite://stress.db"
+//	args - command arguments
+//	out  - writer for command output
+func runStress(args []string, out io.Writer) error {
+	var opts = stress.DefaultOptions()
+	var fs = flag.NewFlagSet("coins stress", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.IntVar(&opts.Accounts, "accounts", opts.Accounts, "number of accounts transfers are made between")
+	fs.Uint64Var(&opts.InitialBalance, "initial-balance", opts.InitialBalance, "money deposited into every account")
+	fs.IntVar(&opts.Transfers, "transfers", opts.Transfers, "number of transfers to send")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "number of concurrent workers")
+	fs.Uint64Var(&opts.MaxAmount, "max-amount", opts.MaxAmount, "max amount of single transfer")
+	fs.Float64Var(&opts.OppositeRate, "opposite-rate", opts.OppositeRate, "share of transfers sent together with transfer in opposite direction")
+	fs.Float64Var(&opts.DuplicateRate, "duplicate-rate", opts.DuplicateRate, "share of transfers sent twice with the same id")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of random generator")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load("coins stress", fs.Args(), os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	accountSvc, transferSvc, closeBackend, err := createStressServices(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer closeBackend()
+
+	// Interrupted run stops sending transfers, but still checks invariants
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(out, "sending %d transfers between %d accounts by %d workers, seed %d\n", opts.Transfers, opts.Accounts, opts.Workers, opts.Seed)
+	report, err := stress.Run(ctx, accountSvc, transferSvc, opts)
+	fmt.Fprint(out, report.String())
+	if err != nil {
+		return err
+	}
+
+	if !report.OK() {
+		return errors.New("stress run failed")
+	}
+
+	return nil
+}
+
+// Creates account and transfer services of backend selected by configuration.
+// Postgres database should be migrated before run, unless autoMigrate setting is enabled
+//	cfg - configuration
+// Returns services and function that releases backend resources
+func createStressServices(cfg config.Config) (account.AccountService, transfer.TransferService, func(), error) {
+	if cfg.Database.Backend() == config.BackendMemory {
+		var store = memory.NewDemoStore(cfg.Database.TransactionTimeout.Std(), nil)
+		return account.NewAccountService(store), transfer.NewTransferService(store), func() {}, nil
+	}
+
+	cnPool, err := db.NewConnectionPool(cfg.Database.ConnectionString, cfg.Database.PoolSize, cfg.Database.AcquireTimeout.Std())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	if cfg.Database.AutoMigrate || cfg.Database.Backend() == config.BackendSQLite {
+		migrator, err := newMigrator(cnPool, cfg)
+		if err == nil {
+			_, err = migrator.Up()
+		}
+
+		if err != nil {
+			cnPool.Close()
+			return nil, nil, nil, fmt.Errorf("unable to migrate database: %w", err)
+		}
+	}
+
+	var factory = func() (db.DbContext, error) {
+		return db.CreateContext(cnPool, cfg.Database.TransactionTimeout.Std())
+	}
+
+	return account.NewAccountService(account.NewPostgresStorage(factory)), transfer.NewTransferService(newTransferStorage(cfg, factory)), cnPool.Close, nil
+}
@@ -0,0 +1,326 @@
+package stress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"test/coins/account"
+	"test/coins/transfer"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Settings of stress run
+type Options struct {
+	// Number of accounts transfers are made between
+	Accounts int
+
+	// Money deposited into every account before transfers
+	InitialBalance uint64
+
+	// Number of transfers to send
+	Transfers int
+
+	// Number of workers sending transfers concurrently
+	Workers int
+
+	// Max amount of single transfer, amounts are random from 1 to MaxAmount
+	MaxAmount uint64
+
+	// Share of transfers sent together with transfer in opposite direction between the same accounts
+	OppositeRate float64
+
+	// Share of transfers sent twice concurrently with the same id
+	DuplicateRate float64
+
+	// Seed of random generator, runs with the same seed send the same transfers
+	Seed int64
+}
+
+// Returns default stress run settings
+func DefaultOptions() Options {
+	return Options{
+		Accounts:       10,
+		InitialBalance: 1000,
+		Transfers:      5000,
+		Workers:        16,
+		MaxAmount:      300,
+		OppositeRate:   0.2,
+		DuplicateRate:  0.1,
+		Seed:           time.Now().UnixNano(),
+	}
+}
+
+// Result of stress run
+type Report struct {
+	// Number of sent transfer requests, duplicates included
+	Sent int
+
+	// Number of completed transfers
+	Succeeded int
+
+	// Number of transfers rejected as source account did not have enough money
+	NotEnoughMoney int
+
+	// Number of transfers rejected as transfer with the same id was already complete
+	Duplicates int
+
+	// Errors transfers failed with, other than expected rejections. Lock and transaction timeouts are reported here
+	Errors []string
+
+	// Time spent on sending transfers
+	Elapsed time.Duration
+
+	// Violated invariants, empty if all invariants hold
+	Violations []string
+}
+
+// Returns true if all transfers were either completed or rejected and all invariants hold
+func (r Report) OK() bool {
+	return len(r.Errors) == 0 && len(r.Violations) == 0
+}
+
+func (r Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "sent %d transfers in %s: %d succeeded, %d not enough money, %d duplicates, %d errors\n",
+		r.Sent, r.Elapsed.Round(time.Millisecond), r.Succeeded, r.NotEnoughMoney, r.Duplicates, len(r.Errors))
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(&sb, "error: %s\n", err)
+	}
+
+	for _, violation := range r.Violations {
+		fmt.Fprintf(&sb, "violation: %s\n", violation)
+	}
+
+	if r.OK() {
+		sb.WriteString("all invariants hold\n")
+	}
+
+	return sb.String()
+}
+
+// Transfer to be sent
+type job struct {
+	id     transfer.TransferId
+	source account.AccountNumber
+	dest   account.AccountNumber
+	amount uint64
+}
+
+// Opens accounts, sends random concurrent transfers between them and checks invariants:
+// no balance is negative, total money in accounts is conserved, every completed transfer is recorded once
+// and no transfer failed with unexpected error, such as lock or transaction timeout
+//	ctx       - context, run stops sending transfers when it is cancelled
+//	accounts  - account service
+//	transfers - transfer service
+//	opts      - run settings
+// Returns run report. Error is returned only if accounts could not be opened or checked
+func Run(ctx context.Context, accounts account.AccountService, transfers transfer.TransferService, opts Options) (Report, error) {
+	if opts.Accounts < 2 || opts.Workers < 1 || opts.MaxAmount < 1 {
+		return Report{}, errors.New("at least 2 accounts, 1 worker and max amount of 1 are required")
+	}
+
+	var numbers = []account.AccountNumber{}
+	for i := 0; i < opts.Accounts; i++ {
+		acc, err := accounts.CreateAccount(ctx)
+		if err != nil {
+			return Report{}, fmt.Errorf("unable to open account: %w", err)
+		}
+
+		if opts.InitialBalance > 0 {
+			err = transfers.Deposit(ctx, transfer.TransferId(uuid.New()), acc.Number, opts.InitialBalance)
+			if err != nil {
+				return Report{}, fmt.Errorf("unable to deposit money: %w", err)
+			}
+		}
+
+		numbers = append(numbers, acc.Number)
+	}
+
+	var report = send(ctx, transfers, plan(numbers, opts), opts.Workers)
+	violations, err := check(ctx, accounts, transfers, numbers, opts, report)
+	if err != nil {
+		return report, err
+	}
+
+	report.Violations = violations
+	return report, nil
+}
+
+// Generates groups of transfers, transfers of the same group are sent concurrently
+//	numbers - account numbers
+//	opts    - run settings
+// Returns groups of transfers
+func plan(numbers []account.AccountNumber, opts Options) [][]job {
+	var rnd = rand.New(rand.NewSource(opts.Seed))
+	var groups = [][]job{}
+	for planned := 0; planned < opts.Transfers; {
+		// Dest is picked among other accounts, so it always differs from source
+		var source = rnd.Intn(len(numbers))
+		var dest = (source + 1 + rnd.Intn(len(numbers)-1)) % len(numbers)
+		var first = job{transfer.TransferId(uuid.New()), numbers[source], numbers[dest], 1 + uint64(rnd.Int63n(int64(opts.MaxAmount)))}
+
+		var group = []job{first}
+		switch p := rnd.Float64(); {
+		case p < opts.OppositeRate:
+			group = append(group, job{transfer.TransferId(uuid.New()), first.dest, first.source, 1 + uint64(rnd.Int63n(int64(opts.MaxAmount)))})
+		case p < opts.OppositeRate+opts.DuplicateRate:
+			group = append(group, first)
+		}
+
+		groups = append(groups, group)
+		planned += len(group)
+	}
+
+	return groups
+}
+
+// Sends groups of transfers by concurrent workers
+//	ctx       - context, sending stops when it is cancelled
+//	transfers - transfer service
+//	groups    - groups of transfers
+//	workers   - number of workers
+// Returns report with transfer outcomes
+func send(ctx context.Context, transfers transfer.TransferService, groups [][]job, workers int) Report {
+	var (
+		report Report
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		queue  = make(chan []job)
+	)
+
+	var record = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Sent++
+		switch {
+		case err == nil:
+			report.Succeeded++
+		case errors.Is(err, transfer.ErrNotEnoughMoney):
+			report.NotEnoughMoney++
+		case errors.Is(err, transfer.ErrTransferAlreadyComplete):
+			report.Duplicates++
+		default:
+			report.Errors = append(report.Errors, describe(err))
+		}
+	}
+
+	var start = time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				var groupWg sync.WaitGroup
+				for _, j := range group {
+					groupWg.Add(1)
+					go func(j job) {
+						defer groupWg.Done()
+						record(transfers.TransferMoney(ctx, j.id, j.source, j.dest, j.amount, transfer.TransferDetails{}))
+					}(j)
+				}
+				groupWg.Wait()
+			}
+		}()
+	}
+
+	for _, group := range groups {
+		select {
+		case queue <- group:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	return report
+}
+
+// Returns error description, database errors include wrapped error as service error message hides it
+func describe(err error) string {
+	var serviceErr servErr.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Unwrap() != nil {
+		return fmt.Sprintf("%s: %s", err.Error(), serviceErr.Unwrap().Error())
+	}
+
+	return err.Error()
+}
+
+// Checks invariants after transfers were sent
+//	ctx       - context
+//	accounts  - account service
+//	transfers - transfer service
+//	numbers   - numbers of accounts transfers were made between
+//	opts      - run settings
+//	report    - report with transfer outcomes
+// Returns violated invariants
+func check(ctx context.Context, accounts account.AccountService, transfers transfer.TransferService, numbers []account.AccountNumber, opts Options, report Report) ([]string, error) {
+	var violations = []string{}
+
+	list, err := accounts.ListAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list accounts: %w", err)
+	}
+
+	var balances = map[account.AccountNumber]int64{}
+	for _, acc := range list {
+		balances[acc.Number] = acc.Balance
+	}
+
+	var total int64 = 0
+	for _, number := range numbers {
+		if balances[number] < 0 {
+			violations = append(violations, fmt.Sprintf("account %d has negative balance %d", number, balances[number]))
+		}
+
+		total += balances[number]
+	}
+
+	var expectedTotal = int64(opts.InitialBalance) * int64(len(numbers))
+	if total != expectedTotal {
+		violations = append(violations, fmt.Sprintf("total money is not conserved: expected %d, got %d", expectedTotal, total))
+	}
+
+	// Every transfer is listed once as outgoing transfer of its source account
+	var recorded = map[transfer.TransferId]int{}
+	for _, number := range numbers {
+		history, err := transfers.ListTransfers(ctx, number, transfer.ListTransfersFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list transfers: %w", err)
+		}
+
+		for _, tr := range history {
+			if tr.Direction == transfer.DirectionOutgoing {
+				recorded[tr.Id]++
+			}
+		}
+	}
+
+	var outgoing = 0
+	for id, count := range recorded {
+		outgoing += count
+		if count > 1 {
+			violations = append(violations, fmt.Sprintf("transfer %s is recorded %d times", uuid.UUID(id), count))
+		}
+	}
+
+	if outgoing != report.Succeeded {
+		violations = append(violations, fmt.Sprintf("%d transfers succeeded, but %d are recorded", report.Succeeded, outgoing))
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,78 @@
+package stress_test
+
+import (
+	"context"
+	"strings"
+	"test/coins/account"
+	"test/coins/memory"
+	"test/coins/stress"
+	"test/coins/transfer"
+	"testing"
+	"time"
+)
+
+// Transfer service that reports every transfer as completed, but executes only first ones
+type lostTransfersService struct {
+	transfer.TransferService
+
+	executed int
+}
+
+func (svc *lostTransfersService) TransferMoney(ctx context.Context, id transfer.TransferId, source, dest account.AccountNumber, amount uint64, details transfer.TransferDetails) error {
+	if svc.executed >= 10 {
+		return nil
+	}
+
+	svc.executed++
+	return svc.TransferService.TransferMoney(ctx, id, source, dest, amount, details)
+}
+
+func stressOptions() stress.Options {
+	var opts = stress.DefaultOptions()
+	opts.Transfers = 2000
+	opts.Seed = 1
+	return opts
+}
+
+func Test_Run_MemoryStore_InvariantsHold(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second*5, nil)
+
+	// Act
+	report, err := stress.Run(context.Background(), account.NewAccountService(store), transfer.NewTransferService(store), stressOptions())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Run() was called: %s", err.Error())
+	}
+
+	if !report.OK() {
+		t.Fatalf("expected all invariants to hold, got report:\n%s", report)
+	}
+
+	if report.Sent < 2000 || report.Succeeded == 0 || report.NotEnoughMoney == 0 || report.Duplicates == 0 {
+		t.Fatalf("expected completed, rejected and duplicate transfers, got report:\n%s", report)
+	}
+}
+
+func Test_Run_LostTransfers_ViolationReported(t *testing.T) {
+	// Arrange
+	var store = memory.NewDemoStore(time.Second*5, nil)
+	var opts = stressOptions()
+	opts.Workers = 1
+	opts.OppositeRate = 0
+	opts.DuplicateRate = 0
+	var transferSvc = &lostTransfersService{TransferService: transfer.NewTransferService(store)}
+
+	// Act
+	report, err := stress.Run(context.Background(), account.NewAccountService(store), transferSvc, opts)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Run() was called: %s", err.Error())
+	}
+
+	if report.OK() || len(report.Violations) != 1 || !strings.Contains(report.Violations[0], "are recorded") {
+		t.Fatalf("expected violation about unrecorded transfers, got report:\n%s", report)
+	}
+}
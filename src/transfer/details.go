@@ -0,0 +1,81 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	MaxMemoLength              = 140
+	MaxExternalReferenceLength = 64
+	MaxMetadataKeys            = 20
+	MaxMetadataKeyLength       = 40
+	MaxMetadataValueLength     = 500
+)
+
+// Checks that transfer details do not exceed length limits.
+// Returns ErrInvalidTransferDetails error if details are invalid
+func (details TransferDetails) Validate() error {
+	if utf8.RuneCountInString(details.Memo) > MaxMemoLength {
+		return ErrInvalidTransferDetails(fmt.Sprintf("memo should not be longer than %d characters", MaxMemoLength))
+	}
+
+	if utf8.RuneCountInString(details.ExternalReference) > MaxExternalReferenceLength {
+		return ErrInvalidTransferDetails(fmt.Sprintf("external reference should not be longer than %d characters", MaxExternalReferenceLength))
+	}
+
+	if len(details.Metadata) > MaxMetadataKeys {
+		return ErrInvalidTransferDetails(fmt.Sprintf("metadata should not contain more than %d keys", MaxMetadataKeys))
+	}
+
+	for key, value := range details.Metadata {
+		if key == "" || utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+			return ErrInvalidTransferDetails(fmt.Sprintf("metadata key should not be empty or longer than %d characters", MaxMetadataKeyLength))
+		}
+
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return ErrInvalidTransferDetails(fmt.Sprintf("metadata value of key [%s] should not be longer than %d characters", key, MaxMetadataValueLength))
+		}
+	}
+
+	return nil
+}
+
+// Returns value to be stored in nullable text column. Empty string is stored as NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+// Returns metadata serialized to JSON to be stored in nullable jsonb column. Empty metadata is stored as NULL
+func metadataToJSON(metadata map[string]string) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Parses metadata read from nullable jsonb column
+func metadataFromJSON(data []byte) (map[string]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var metadata map[string]string
+	err := json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
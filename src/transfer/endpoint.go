@@ -9,7 +9,8 @@ import (
 )
 
 type listTransfersRequest struct {
-	AccountNumber uint64
+	AccountNumber     uint64
+	ExternalReference string
 }
 
 type listTransfersResponse struct {
@@ -23,16 +24,20 @@ func makeListTransfersEndpoint(svc TransferService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(listTransfersRequest)
 		accountNumber := account.AccountNumber(req.AccountNumber)
-		transfers, err := svc.ListTransfers(accountNumber)
+		var filter = ListTransfersFilter{ExternalReference: req.ExternalReference}
+		transfers, err := svc.ListTransfers(ctx, accountNumber, filter)
 		return listTransfersResponse{transfers, err}, nil
 	}
 }
 
 type sendPaymentRequest struct {
-	Id     uuid.UUID `json:"id"`
-	Source uint64    `json:"source"`
-	Dest   uint64    `json:"dest"`
-	Amount uint64    `json:"amount"`
+	Id                uuid.UUID         `json:"id"`
+	Source            uint64            `json:"source"`
+	Dest              uint64            `json:"dest"`
+	Amount            uint64            `json:"amount"`
+	Memo              string            `json:"memo"`
+	ExternalReference string            `json:"externalReference"`
+	Metadata          map[string]string `json:"metadata"`
 }
 
 type sendPaymentResponse struct {
@@ -46,7 +51,78 @@ func makeSendPaymentEnpoint(svc TransferService) endpoint.Endpoint {
 		req := request.(sendPaymentRequest)
 		sourceAcc := account.AccountNumber(req.Source)
 		destAcc := account.AccountNumber(req.Dest)
-		err := svc.TransferMoney(TransferId(req.Id), sourceAcc, destAcc, req.Amount)
+		var details = TransferDetails{
+			Memo:              req.Memo,
+			ExternalReference: req.ExternalReference,
+			Metadata:          req.Metadata,
+		}
+		err := svc.TransferMoney(ctx, TransferId(req.Id), sourceAcc, destAcc, req.Amount, details)
 		return sendPaymentResponse{err}, nil
 	}
 }
+
+type fundingRequest struct {
+	Id      uuid.UUID `json:"id"`
+	Account uint64    `json:"account"`
+	Amount  uint64    `json:"amount"`
+}
+
+type fundingResponse struct {
+	Error error `json:"error,omitempty"`
+}
+
+func (r fundingResponse) error() error { return r.Error }
+
+func makeDepositEndpoint(svc TransferService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(fundingRequest)
+		err := svc.Deposit(ctx, TransferId(req.Id), account.AccountNumber(req.Account), req.Amount)
+		return fundingResponse{err}, nil
+	}
+}
+
+func makeWithdrawEndpoint(svc TransferService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(fundingRequest)
+		err := svc.Withdraw(ctx, TransferId(req.Id), account.AccountNumber(req.Account), req.Amount)
+		return fundingResponse{err}, nil
+	}
+}
+
+type setLimitTierRequest struct {
+	Tier string `json:"-"`
+	TransferLimits
+}
+
+type setAccountLimitsRequest struct {
+	AccountNumber uint64 `json:"-"`
+	Tier          string `json:"tier"`
+	TransferLimits
+}
+
+type limitsResponse struct {
+	Limits *TransferLimits `json:"limits,omitempty"`
+	Error  error           `json:"error,omitempty"`
+}
+
+func (r limitsResponse) error() error { return r.Error }
+
+func makeSetLimitTierEndpoint(svc TransferService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setLimitTierRequest)
+		err := svc.SetLimitTier(ctx, req.Tier, req.TransferLimits)
+		if err != nil {
+			return limitsResponse{nil, err}, nil
+		}
+
+		return limitsResponse{&req.TransferLimits, nil}, nil
+	}
+}
+
+func makeSetAccountLimitsEndpoint(svc TransferService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setAccountLimitsRequest)
+		limits, err := svc.SetAccountLimits(ctx, account.AccountNumber(req.AccountNumber), req.Tier, req.TransferLimits)
+		return limitsResponse{limits, err}, nil
+	}
+}
@@ -28,6 +28,10 @@ func (id *TransferId) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+const TransferTypeTransfer = "transfer"
+const TransferTypeDeposit = "deposit"
+const TransferTypeWithdrawal = "withdrawal"
+
 const DirectionIncoming = "incoming"
 const DirectionOutgoing = "outgoing"
 
@@ -35,6 +39,9 @@ type Transfer struct {
 	// Transfer id
 	Id TransferId `json:"id"`
 
+	// Transfer type. Can have values "transfer", "deposit" or "withdrawal"
+	Type string `json:"type"`
+
 	// Current account number
 	Account account.AccountNumber `json:"account"`
 
@@ -52,4 +59,135 @@ type Transfer struct {
 
 	// Account created timestamp
 	CreatedAt time.Time `json:"createdAt"`
+
+	// Transfer note provided by client
+	Memo string `json:"memo,omitempty"`
+
+	// Transfer reference in external system, for example order id
+	ExternalReference string `json:"externalReference,omitempty"`
+
+	// Arbitrary key/value data attached to transfer
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Optional details attached to money transfer
+type TransferDetails struct {
+	// Transfer note, up to MaxMemoLength characters
+	Memo string
+
+	// Transfer reference in external system, up to MaxExternalReferenceLength characters
+	ExternalReference string
+
+	// Arbitrary key/value data, up to MaxMetadataKeys keys
+	Metadata map[string]string
+}
+
+// Filter applied to list of transfers
+type ListTransfersFilter struct {
+	// If not empty, only transfers with this external reference are returned
+	ExternalReference string
+}
+
+const LimitTransferAmount = "transfer amount"
+const LimitDailyAmount = "daily amount"
+const LimitMonthlyAmount = "monthly amount"
+const LimitDailyCount = "daily transfer count"
+
+// Velocity limits applied to outgoing transfers of account.
+// Nil value means that limit is not set
+type TransferLimits struct {
+	// Max amount of single transfer
+	MaxTransferAmount *int64 `json:"maxTransferAmount"`
+
+	// Max total amount of outgoing transfers per day
+	MaxDailyAmount *int64 `json:"maxDailyAmount"`
+
+	// Max total amount of outgoing transfers per month
+	MaxMonthlyAmount *int64 `json:"maxMonthlyAmount"`
+
+	// Max number of outgoing transfers per day
+	MaxDailyCount *int64 `json:"maxDailyCount"`
+}
+
+// Max length of limit tier name
+const MaxLimitTierLength = 32
+
+// Outgoing transfers of account in current limit periods
+type LimitsUsage struct {
+	// Total amount of outgoing transfers today
+	DailyAmount int64
+
+	// Number of outgoing transfers today
+	DailyCount int64
+
+	// Total amount of outgoing transfers this month
+	MonthlyAmount int64
+}
+
+// Type of event that is written to outbox when money transfer is completed
+const EventTransferCompleted = "TransferCompleted"
+
+// Payload of TransferCompleted event
+type TransferCompletedEvent struct {
+	// Transfer id
+	Id TransferId `json:"id"`
+
+	// Transfer type. Can have values "transfer", "deposit" or "withdrawal"
+	Type string `json:"type"`
+
+	// Account from where money was transferred
+	Source account.AccountNumber `json:"source"`
+
+	// Account to where money was transferred
+	Dest account.AccountNumber `json:"dest"`
+
+	// Transfer amount
+	Amount int64 `json:"amount"`
+
+	// Source account balance after transfer
+	SourceBalance int64 `json:"sourceBalance"`
+
+	// Dest account balance after transfer. Not set if dest account is hot, as its balance is not locked
+	// and concurrent credits to it are committed in any order
+	DestBalance *int64 `json:"destBalance,omitempty"`
+
+	// Transfer note
+	Memo string `json:"memo,omitempty"`
+
+	// Transfer reference in external system
+	ExternalReference string `json:"externalReference,omitempty"`
+
+	// Arbitrary key/value data attached to transfer
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Transfer completed timestamp
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Returns completed transfer as it is seen by one of its accounts
+//	accountNum - source or dest account number
+func (event TransferCompletedEvent) TransferFor(accountNum account.AccountNumber) Transfer {
+	var transfer = Transfer{
+		Id:                event.Id,
+		Type:              event.Type,
+		Account:           accountNum,
+		Amount:            event.Amount,
+		CreatedAt:         event.CreatedAt,
+		Memo:              event.Memo,
+		ExternalReference: event.ExternalReference,
+		Metadata:          event.Metadata,
+	}
+
+	if event.Source == accountNum {
+		var val = event.Dest
+		transfer.ToAccount = &val
+		transfer.Direction = DirectionOutgoing
+	}
+	if event.Dest == accountNum {
+		var val = event.Source
+		transfer.FromAccount = &val
+		transfer.Direction = DirectionIncoming
+	}
+
+	return transfer
 }
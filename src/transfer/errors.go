@@ -2,22 +2,37 @@ package transfer
 
 import (
 	"fmt"
+	"net/http"
 	"test/coins/account"
 	servErr "test/coins/errors"
+	"time"
 )
 
 const (
 	ErrKindInvalidAccount int = 10 + iota
 	ErrKindNotEnoughMoney
 	ErrKindTransferAlreadyComplete
+	ErrKindLimitExceeded
+	ErrKindInvalidTransferDetails
+	ErrKindLimitTierNotFound
 )
 
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindInvalidAccount, servErr.ErrorDefinition{Code: "account_not_found", Status: http.StatusNotFound, Title: "Account not found"})
+	servErr.RegisterKind(ErrKindNotEnoughMoney, servErr.ErrorDefinition{Code: "insufficient_funds", Status: http.StatusUnprocessableEntity, Title: "Not enough money"})
+	servErr.RegisterKind(ErrKindTransferAlreadyComplete, servErr.ErrorDefinition{Code: "duplicate_transfer", Status: http.StatusConflict, Title: "Transfer already complete"})
+	servErr.RegisterKind(ErrKindLimitExceeded, servErr.ErrorDefinition{Code: "limit_exceeded", Status: http.StatusUnprocessableEntity, Title: "Transfer limit exceeded"})
+	servErr.RegisterKind(ErrKindInvalidTransferDetails, servErr.ErrorDefinition{Code: "invalid_transfer_details", Status: http.StatusBadRequest, Title: "Invalid transfer details"})
+	servErr.RegisterKind(ErrKindLimitTierNotFound, servErr.ErrorDefinition{Code: "limit_tier_not_found", Status: http.StatusNotFound, Title: "Limit tier not found"})
+}
+
 // Creates new "Invalid account number" error
 // 	accountNum - account number
 // Returns created error
 func ErrInvalidAccount(accountNum account.AccountNumber) error {
 	var msg = fmt.Sprintf("account with number [%d] not found", uint64(accountNum))
-	return servErr.NewServiceError(msg, nil, ErrKindInvalidAccount)
+	return servErr.NewServiceErrorWithDetails(msg, nil, ErrKindInvalidAccount, servErr.Details{"account": uint64(accountNum)})
 }
 
 // Error that is expected when there is not enough money on source account to complete transfer
@@ -27,3 +42,33 @@ var ErrNotEnoughMoney = servErr.NewServiceError(
 // Error that is expected when money transfer with provided id is already complete
 var ErrTransferAlreadyComplete = servErr.NewServiceError(
 	"transfer already complete", nil, ErrKindTransferAlreadyComplete)
+
+// Creates new "Transfer limit exceeded" error
+//	limit    - name of the limit that was hit
+//	resetsAt - time when limit resets, nil if limit is not time-based
+// Returns created error
+func ErrLimitExceeded(limit string, resetsAt *time.Time) error {
+	var msg = fmt.Sprintf("%s limit exceeded", limit)
+	var details = servErr.Details{"limit": limit}
+	if resetsAt != nil {
+		msg += fmt.Sprintf(", limit resets at %s", resetsAt.UTC().Format(time.RFC3339))
+		details["resetsAt"] = resetsAt.UTC()
+	}
+
+	return servErr.NewServiceErrorWithDetails(msg, nil, ErrKindLimitExceeded, details)
+}
+
+// Creates new "Invalid transfer details" error
+//	reason - reason why transfer details are invalid
+// Returns created error
+func ErrInvalidTransferDetails(reason string) error {
+	return servErr.NewServiceError("invalid transfer details: "+reason, nil, ErrKindInvalidTransferDetails)
+}
+
+// Creates new "Limit tier not found" error
+//	tier - name of limit tier
+// Returns created error
+func ErrLimitTierNotFound(tier string) error {
+	var msg = fmt.Sprintf("limit tier [%s] not found", tier)
+	return servErr.NewServiceErrorWithDetails(msg, nil, ErrKindLimitTierNotFound, servErr.Details{"tier": tier})
+}
@@ -0,0 +1,55 @@
+package transfer
+
+import (
+	"test/coins/account"
+	"time"
+)
+
+// Checks if outgoing transfer does not exceed limits configured for source account.
+// Should be called inside unit of work where source account is locked,
+// so concurrent transfers from the same account can't bypass limits
+//	repo   - transfer repository
+//	source - source account number
+//	amount - transfer amount
+//	now    - current time, used to calculate daily and monthly periods
+// Returns ErrLimitExceeded error if any limit was hit
+func checkTransferLimits(repo TransferRepository, source account.AccountNumber, amount uint64, now time.Time) error {
+	limits, err := repo.ReadLimits(source)
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxTransferAmount != nil && int64(amount) > *limits.MaxTransferAmount {
+		return ErrLimitExceeded(LimitTransferAmount, nil)
+	}
+
+	if limits.MaxDailyAmount == nil && limits.MaxMonthlyAmount == nil && limits.MaxDailyCount == nil {
+		return nil
+	}
+
+	// Limit periods are calendar days and months in UTC
+	now = now.UTC()
+	var dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var dayEnd = dayStart.AddDate(0, 0, 1)
+	var monthEnd = monthStart.AddDate(0, 1, 0)
+
+	usage, err := repo.ReadUsage(source, dayStart, monthStart)
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxDailyCount != nil && usage.DailyCount+1 > *limits.MaxDailyCount {
+		return ErrLimitExceeded(LimitDailyCount, &dayEnd)
+	}
+
+	if limits.MaxDailyAmount != nil && usage.DailyAmount+int64(amount) > *limits.MaxDailyAmount {
+		return ErrLimitExceeded(LimitDailyAmount, &dayEnd)
+	}
+
+	if limits.MaxMonthlyAmount != nil && usage.MonthlyAmount+int64(amount) > *limits.MaxMonthlyAmount {
+		return ErrLimitExceeded(LimitMonthlyAmount, &monthEnd)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package transfer
+
+import (
+	"encoding/json"
+	"test/coins/account"
+	"test/coins/db"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Transfer executed by single statement. Hot accounts and accounts with pending credits are not locked,
+// as part of their balance is kept in pending credits.
+// Transfer is inserted only if both accounts are locked, have expected types, and source has enough money
+// and no transfer limits. Accounts are updated and event is written only if transfer was inserted,
+// so statement changes nothing otherwise. Final select returns accounts and flags, used to find out
+// why transfer was not inserted, accounts that were not locked are returned as hot
+const executeTransferSql = "WITH locked AS (" +
+	"SELECT account_number, balance, credit_limit, account_type, limit_tier FROM public.accounts " +
+	"WHERE account_number IN ($3, $4) AND NOT hot " +
+	"AND NOT EXISTS (SELECT 1 FROM public.pending_credits p WHERE p.account_number = accounts.account_number) " +
+	"ORDER BY account_number FOR UPDATE), " +
+	"limited AS (" +
+	"SELECT account_number FROM locked WHERE account_number = $3 AND limit_tier IS NOT NULL " +
+	"UNION ALL SELECT account_number FROM public.account_limits WHERE account_number = $3), " +
+	"checked AS (" +
+	"SELECT s.account_number FROM locked s, locked d " +
+	"WHERE s.account_number = $3 AND s.account_type = $9 AND d.account_number = $4 AND d.account_type = $10 " +
+	"AND (s.account_type = 'settlement' OR (s.balance >= $2 - s.credit_limit AND NOT EXISTS (SELECT 1 FROM limited)))), " +
+	"inserted AS (" +
+	"INSERT INTO public.transfers (transfer_id, amount, source_account, dest_account, transfer_type, memo, external_reference, metadata, created_at) " +
+	"SELECT $1::uuid, $2, $3, $4, $5, $6, $7, $8::jsonb, $13::timestamp FROM checked " +
+	"ON CONFLICT (transfer_id) DO NOTHING RETURNING transfer_id), " +
+	"debit AS (" +
+	"UPDATE public.accounts SET balance = balance - $2 WHERE account_number = $3 AND EXISTS (SELECT 1 FROM inserted) RETURNING balance), " +
+	"credit AS (" +
+	"UPDATE public.accounts SET balance = balance + $2 WHERE account_number = $4 AND EXISTS (SELECT 1 FROM inserted) RETURNING balance), " +
+	"event AS (" +
+	"INSERT INTO public.outbox_events (event_type, payload, created_at) " +
+	"SELECT $11, $12::jsonb || jsonb_build_object('sourceBalance', debit.balance, 'destBalance', credit.balance), $13::timestamp FROM debit, credit) " +
+	"SELECT a.account_number, a.balance, a.credit_limit, a.account_type, a.hot, " +
+	"EXISTS (SELECT 1 FROM limited), EXISTS (SELECT 1 FROM inserted), " +
+	"EXISTS (SELECT 1 FROM public.transfers WHERE transfer_id = $1::uuid) " +
+	"FROM (SELECT account_number, balance, credit_limit, account_type, false AS hot FROM locked " +
+	"UNION ALL SELECT account_number, balance, credit_limit, account_type, true FROM public.accounts " +
+	"WHERE account_number IN ($3, $4) AND account_number NOT IN (SELECT account_number FROM locked)) a"
+
+// Postgres storage of money transfers, that creates repositories executing transfers by single statement
+type executorStorage struct {
+	postgresStorage
+}
+
+// Creates new Postgres storage of money transfers, that executes transfers by single statement.
+// Statement uses data-modifying CTE, so storage can be used only with Postgres
+//	dbContextFactory - factory function used to create new db context
+func NewSingleStatementStorage(dbContextFactory func() (db.DbContext, error)) Storage {
+	return executorStorage{postgresStorage{dbContextFactory}}
+}
+
+func (storage executorStorage) Transfers(uow db.UnitOfWork) TransferRepository {
+	return executorRepository{postgresRepository{uow.(db.DbContext)}}
+}
+
+// Postgres repository of money transfers, that executes transfer by single statement
+type executorRepository struct {
+	postgresRepository
+}
+
+func (repo executorRepository) Execute(event TransferCompletedEvent, sourceType, destType string) (bool, error) {
+	metadata, err := metadataToJSON(event.Metadata)
+	if err != nil {
+		return false, ErrInvalidTransferDetails(err.Error())
+	}
+
+	// balances are added to payload by statement
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		locked                        []account.Account
+		limited, inserted, duplicated bool
+	)
+
+	err = repo.dbContext.Query(
+		executeTransferSql,
+		sqlParams{
+			uuid.UUID(event.Id), event.Amount, int64(uint64(event.Source)), int64(uint64(event.Dest)), event.Type,
+			nullableString(event.Memo), nullableString(event.ExternalReference), metadata,
+			sourceType, destType, EventTransferCompleted, string(payload), event.CreatedAt.UTC(),
+		},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					acc    account.Account
+					number int64
+				)
+
+				err := rows.Scan(&number, &acc.Balance, &acc.CreditLimit, &acc.Type, &acc.Hot, &limited, &inserted, &duplicated)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				acc.Number = account.AccountNumber(number)
+				locked = append(locked, acc)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	if inserted {
+		return true, nil
+	}
+
+	// Errors are returned in the same order as by step by step execution
+	var source = findAccount(locked, event.Source, sourceType)
+	if source == nil {
+		return false, ErrInvalidAccount(event.Source)
+	}
+
+	var dest = findAccount(locked, event.Dest, destType)
+	if dest == nil {
+		return false, ErrInvalidAccount(event.Dest)
+	}
+
+	// Hot accounts, pending credits and transfer limits are handled step by step
+	if source.Hot || dest.Hot || (sourceType != account.AccountTypeSettlement && limited) {
+		return false, nil
+	}
+
+	// Transfer passed all checks, but was not inserted because of unique transfer id
+	if duplicated || sourceType == account.AccountTypeSettlement || source.AvailableFunds() >= event.Amount {
+		return false, ErrTransferAlreadyComplete
+	}
+
+	return false, ErrNotEnoughMoney
+}
@@ -0,0 +1,275 @@
+package transfer_test
+
+import (
+	"context"
+	"errors"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/transfer"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+func setupSingleStatementService(setupMock func(mock sqlmock.Sqlmock)) transfer.TransferService {
+	return transfer.NewTransferService(transfer.NewSingleStatementStorage(func() (db.DbContext, error) {
+		return db.CreateMockDbContext(setupMock)
+	}))
+}
+
+// Returns empty result of single statement transfer
+func newExecuteRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot", "limited", "inserted", "duplicated"})
+}
+
+func Test_TransferMoney_SingleStatement_SuccessOnValidDataProvided(t *testing.T) {
+	// Arrange
+	var (
+		transferUuid        = uuid.New()
+		transferId          = transfer.TransferId(transferUuid)
+		sourceAcc           = account.AccountNumber(dbAccountNumber1)
+		descAcc             = account.AccountNumber(dbAccountNumber2)
+		amount       uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, false, true, true).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false, false, true, true)
+		mock.ExpectQuery("WITH locked AS \\(.+\\) SELECT a.account_number").
+			WithArgs(
+				transferUuid, int64(amount), dbAccountNumber1, dbAccountNumber2, transfer.TransferTypeTransfer, nil, nil, nil,
+				account.AccountTypeCustomer, account.AccountTypeCustomer, transfer.EventTransferCompleted, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			).
+			WillReturnRows(rows)
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error returned when called for TransferMoney(...): %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SingleStatement_CheckForEnoughMoney(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 1250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, false, false, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false, false, false, false)
+		mock.ExpectQuery("WITH locked AS").WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindNotEnoughMoney, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SingleStatement_DoesNotAllowToCreateTwoTransfersWithSameId(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 1250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		// duplicate is reported even if source has not enough money, as by step by step execution
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, false, false, true).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false, false, false, true)
+		mock.ExpectQuery("WITH locked AS").WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindTransferAlreadyComplete, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SingleStatement_CheckForValidDestAccount(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, false, false, false)
+		mock.ExpectQuery("WITH locked AS").WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindInvalidAccount, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	var expectedErrorMsg = transfer.ErrInvalidAccount(descAcc).Error()
+	if err.Error() != expectedErrorMsg {
+		t.Fatalf("unexpected error message. Expected message: %s, actual: %s", expectedErrorMsg, err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SingleStatement_LimitsCheckedStepByStep(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 500
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, true, false, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false, true, false, false)
+		mock.ExpectQuery("WITH locked AS").WillReturnRows(rows)
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(300, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WithArgs(dbAccountNumber1).WillReturnRows(limitsRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindLimitExceeded, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SingleStatement_RowsErrorReturned(t *testing.T) {
+	// Arrange
+	var (
+		transferId         = transfer.TransferId(uuid.New())
+		sourceAcc          = account.AccountNumber(dbAccountNumber1)
+		descAcc            = account.AccountNumber(dbAccountNumber2)
+		amount      uint64 = 250
+		expectedErr        = errors.New("connection reset")
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupSingleStatementService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		// iteration stops after source account, so dest account looks missing
+		var rows = newExecuteRows().
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false, false, false, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false, false, false, false).
+			RowError(1, expectedErr)
+		mock.ExpectQuery("WITH locked AS").WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(servErr.ErrorKindDB, expectedErr, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
@@ -0,0 +1,301 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+var errQueryReturnedNoData = errors.New("no data returned from database request")
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+// Postgres storage of money transfers
+type postgresStorage struct {
+	dbContextFactory func() (db.DbContext, error)
+}
+
+// Creates new Postgres storage of money transfers
+//	dbContextFactory - factory function used to create new db context
+func NewPostgresStorage(dbContextFactory func() (db.DbContext, error)) Storage {
+	return postgresStorage{dbContextFactory}
+}
+
+func (storage postgresStorage) Begin(ctx context.Context) (db.UnitOfWork, error) {
+	return db.JoinOrBegin(ctx, func() (db.UnitOfWork, error) {
+		return storage.dbContextFactory()
+	})
+}
+
+func (storage postgresStorage) Accounts(uow db.UnitOfWork) account.AccountRepository {
+	return account.NewPostgresRepository(uow.(db.DbContext))
+}
+
+func (storage postgresStorage) Transfers(uow db.UnitOfWork) TransferRepository {
+	return NewPostgresRepository(uow.(db.DbContext))
+}
+
+// Postgres repository of money transfers
+type postgresRepository struct {
+	dbContext db.DbContext
+}
+
+// Creates new Postgres repository of money transfers
+//	dbContext - db context repository works in
+func NewPostgresRepository(dbContext db.DbContext) TransferRepository {
+	return postgresRepository{dbContext}
+}
+
+func (repo postgresRepository) Exists(transferId TransferId) (bool, error) {
+	var id = uuid.UUID(transferId)
+	var result = false
+	var err = repo.dbContext.Query(
+		"SELECT COUNT(*) FROM public.transfers WHERE transfer_id = $1",
+		sqlParams{id},
+		func(value db.QueryResultRows) error {
+			if !value.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			var val int64
+			err := value.Scan(&val)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			result = val > 0
+			return nil
+		})
+
+	return result, err
+}
+
+func (repo postgresRepository) ReadLimits(accountNum account.AccountNumber) (TransferLimits, error) {
+	var limits = TransferLimits{}
+	var err = repo.dbContext.Query(
+		"SELECT COALESCE(al.max_transfer_amount, tl.max_transfer_amount), "+
+			"COALESCE(al.max_daily_amount, tl.max_daily_amount), "+
+			"COALESCE(al.max_monthly_amount, tl.max_monthly_amount), "+
+			"COALESCE(al.max_daily_count, tl.max_daily_count) "+
+			"FROM public.accounts a "+
+			"LEFT JOIN public.limit_tiers tl ON tl.tier = a.limit_tier "+
+			"LEFT JOIN public.account_limits al ON al.account_number = a.account_number "+
+			"WHERE a.account_number = $1",
+		sqlParams{int64(uint64(accountNum))},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return nil
+			}
+
+			err := rows.Scan(&limits.MaxTransferAmount, &limits.MaxDailyAmount, &limits.MaxMonthlyAmount, &limits.MaxDailyCount)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	return limits, err
+}
+
+func (repo postgresRepository) ReadUsage(accountNum account.AccountNumber, dayStart, monthStart time.Time) (LimitsUsage, error) {
+	var usage = LimitsUsage{}
+	var err = repo.dbContext.Query(
+		"SELECT COALESCE(SUM(amount) FILTER (WHERE created_at >= $2), 0), COUNT(*) FILTER (WHERE created_at >= $2), COALESCE(SUM(amount), 0) "+
+			"FROM public.transfers WHERE source_account = $1 AND created_at >= $3",
+		sqlParams{int64(uint64(accountNum)), dayStart, monthStart},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&usage.DailyAmount, &usage.DailyCount, &usage.MonthlyAmount)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	return usage, err
+}
+
+func (repo postgresRepository) LimitTierExists(tier string) (bool, error) {
+	var result = false
+	var err = repo.dbContext.Query(
+		"SELECT COUNT(*) FROM public.limit_tiers WHERE tier = $1",
+		sqlParams{tier},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			var count int64
+			err := rows.Scan(&count)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			result = count > 0
+			return nil
+		})
+
+	return result, err
+}
+
+func (repo postgresRepository) SaveLimitTier(tier string, limits TransferLimits) error {
+	_, err := repo.dbContext.Execute(
+		"INSERT INTO public.limit_tiers (tier, max_transfer_amount, max_daily_amount, max_monthly_amount, max_daily_count) "+
+			"VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (tier) DO UPDATE SET max_transfer_amount = EXCLUDED.max_transfer_amount, "+
+			"max_daily_amount = EXCLUDED.max_daily_amount, max_monthly_amount = EXCLUDED.max_monthly_amount, "+
+			"max_daily_count = EXCLUDED.max_daily_count",
+		tier, limits.MaxTransferAmount, limits.MaxDailyAmount, limits.MaxMonthlyAmount, limits.MaxDailyCount,
+	)
+
+	return err
+}
+
+func (repo postgresRepository) SaveAccountLimits(accountNum account.AccountNumber, tier string, limits TransferLimits) error {
+	_, err := repo.dbContext.Execute(
+		"UPDATE public.accounts SET limit_tier = $2 WHERE account_number = $1",
+		int64(uint64(accountNum)), nullableString(tier),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.dbContext.Execute(
+		"INSERT INTO public.account_limits (account_number, max_transfer_amount, max_daily_amount, max_monthly_amount, max_daily_count) "+
+			"VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (account_number) DO UPDATE SET max_transfer_amount = EXCLUDED.max_transfer_amount, "+
+			"max_daily_amount = EXCLUDED.max_daily_amount, max_monthly_amount = EXCLUDED.max_monthly_amount, "+
+			"max_daily_count = EXCLUDED.max_daily_count",
+		int64(uint64(accountNum)), limits.MaxTransferAmount, limits.MaxDailyAmount, limits.MaxMonthlyAmount, limits.MaxDailyCount,
+	)
+
+	return err
+}
+
+func (repo postgresRepository) Insert(transferId TransferId, sourceNumber, destNumber account.AccountNumber, amount uint64, transferType string, details TransferDetails, createdAt time.Time) error {
+	metadata, err := metadataToJSON(details.Metadata)
+	if err != nil {
+		return ErrInvalidTransferDetails(err.Error())
+	}
+
+	rowsAffected, err := repo.dbContext.Execute(
+		"INSERT INTO public.transfers (transfer_id, amount, source_account, dest_account, transfer_type, memo, external_reference, metadata, created_at)"+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		uuid.UUID(transferId), amount, sourceNumber, destNumber, transferType,
+		nullableString(details.Memo), nullableString(details.ExternalReference), metadata, createdAt.UTC(),
+	)
+
+	// Transfer id is unique, so transfer with the same id inserted by concurrent transaction is rejected
+	if errors.Is(err, servErr.ErrUniqueViolation(nil)) {
+		return ErrTransferAlreadyComplete
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return ErrTransferAlreadyComplete
+	}
+
+	return nil
+}
+
+func (repo postgresRepository) List(accountNumber account.AccountNumber, filter ListTransfersFilter) ([]Transfer, error) {
+	var sql = "SELECT transfer_id, amount, source_account, dest_account, created_at, transfer_type, memo, external_reference, metadata " +
+		"FROM public.transfers WHERE (source_account = $1 or dest_account = $1)"
+	var params = sqlParams{int64(uint64(accountNumber))}
+	if filter.ExternalReference != "" {
+		sql += " AND external_reference = $2"
+		params = append(params, filter.ExternalReference)
+	}
+	sql += " ORDER BY created_at DESC"
+
+	var result = []Transfer{}
+	err := repo.dbContext.Query(
+		sql,
+		params,
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					id           uuid.UUID
+					amount       int64
+					createdAt    time.Time
+					sourceAcc    int64
+					destAcc      int64
+					transferType string
+					memo         *string
+					reference    *string
+					metadataJSON []byte
+				)
+
+				err := rows.Scan(&id, &amount, &sourceAcc, &destAcc, &createdAt, &transferType, &memo, &reference, &metadataJSON)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				metadata, err := metadataFromJSON(metadataJSON)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				var transfer = Transfer{
+					Id:        TransferId(id),
+					Type:      transferType,
+					Account:   accountNumber,
+					Amount:    amount,
+					CreatedAt: createdAt,
+					Metadata:  metadata,
+				}
+
+				if memo != nil {
+					transfer.Memo = *memo
+				}
+
+				if reference != nil {
+					transfer.ExternalReference = *reference
+				}
+
+				if account.AccountNumber(sourceAcc) == accountNumber {
+					var val = account.AccountNumber(destAcc)
+					transfer.ToAccount = &val
+					transfer.Direction = DirectionOutgoing
+				}
+				if account.AccountNumber(destAcc) == accountNumber {
+					var val = account.AccountNumber(sourceAcc)
+					transfer.FromAccount = &val
+					transfer.Direction = DirectionIncoming
+				}
+
+				result = append(result, transfer)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (repo postgresRepository) WriteEvent(eventType string, payload interface{}) error {
+	return outbox.Write(repo.dbContext, eventType, payload)
+}
@@ -0,0 +1,87 @@
+package transfer
+
+import (
+	"context"
+	"test/coins/account"
+	"test/coins/db"
+	"time"
+)
+
+// Repository of money transfers. Repository works inside single unit of work
+type TransferRepository interface {
+	// Checks if transfer with id already exists
+	//	id - transfer id
+	Exists(id TransferId) (bool, error)
+
+	// Adds transfer to history. Returns ErrTransferAlreadyComplete if transfer with the same id exists
+	//	id           - transfer id
+	//	source       - source account number
+	//	dest         - dest account number
+	//	amount       - transfer amount
+	//	transferType - transfer type
+	//	details      - optional transfer details
+	//	createdAt    - transfer time in UTC, the same time zone as limit periods are compared in
+	Insert(id TransferId, source, dest account.AccountNumber, amount uint64, transferType string, details TransferDetails, createdAt time.Time) error
+
+	// Returns transfers of account, latest first
+	//	accountNum - account number
+	//	filter     - filter applied to list of transfers
+	List(accountNum account.AccountNumber, filter ListTransfersFilter) ([]Transfer, error)
+
+	// Returns transfer limits of account. Limits set for account itself take precedence over limits of account tier
+	//	accountNum - account number
+	ReadLimits(accountNum account.AccountNumber) (TransferLimits, error)
+
+	// Returns outgoing transfers usage of account
+	//	accountNum - account number
+	//	dayStart   - start of current day
+	//	monthStart - start of current month
+	ReadUsage(accountNum account.AccountNumber, dayStart, monthStart time.Time) (LimitsUsage, error)
+
+	// Checks if limit tier exists
+	//	tier - tier name
+	LimitTierExists(tier string) (bool, error)
+
+	// Creates limit tier or replaces limits of existing tier
+	//	tier   - tier name
+	//	limits - tier limits
+	SaveLimitTier(tier string, limits TransferLimits) error
+
+	// Sets limit tier of account and limits that override limits of tier
+	//	accountNum - account number
+	//	tier       - tier name, empty - account has no tier
+	//	limits     - account limits, nil limit means that tier limit is used
+	SaveAccountLimits(accountNum account.AccountNumber, tier string, limits TransferLimits) error
+
+	// Writes event that is published only if unit of work is saved
+	//	eventType - event type
+	//	payload   - event payload
+	WriteEvent(eventType string, payload interface{}) error
+}
+
+// Transfer repository that can execute whole transfer by single database request.
+// It is optional, transfers are executed step by step if repository does not implement it
+type TransferExecutor interface {
+	// Debits source account, credits dest account, adds transfer to history and writes TransferCompleted event.
+	// Returns the same errors as step by step execution
+	//	event      - TransferCompleted event, defines transfer. Account balances are set by executor
+	//	sourceType - expected type of source account
+	//	destType   - expected type of dest account
+	// Returns false if transfer needs checks executor can't do (hot accounts, transfer limits) and should be executed step by step
+	Execute(event TransferCompletedEvent, sourceType, destType string) (bool, error)
+}
+
+// Storage backend of money transfers
+type Storage interface {
+	// Begins unit of work. If context carries unit of work, nested unit of work is begun in it
+	//	ctx - context
+	Begin(ctx context.Context) (db.UnitOfWork, error)
+
+	// Returns account repository that works inside unit of work
+	//	uow - unit of work created by Begin
+	Accounts(uow db.UnitOfWork) account.AccountRepository
+
+	// Returns transfer repository that works inside unit of work
+	//	uow - unit of work created by Begin
+	Transfers(uow db.UnitOfWork) TransferRepository
+}
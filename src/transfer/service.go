@@ -1,295 +1,407 @@
 package transfer
 
 import (
+	"context"
 	"errors"
 	"test/coins/account"
 	"test/coins/db"
 	"time"
 
-	"github.com/google/uuid"
-
 	servErr "test/coins/errors"
 )
 
-var errQueryReturnedNoData = errors.New("no data returned from database request")
-
-// Type alias for sql parameters array
-type sqlParams = []interface{}
+var errNoSettlementAccount = errors.New("external settlement account is not found")
 
 // Transfer service. Incapsulates operations with money transfers
 type TransferService interface {
 	// Returns list of transfers for specific account
+	//	ctx        - context, service joins unit of work carried by it
 	//	accountNum - account number
+	//	filter     - filter applied to list of transfers
 	// Returns list of transfers for specified account
-	ListTransfers(accountNum account.AccountNumber) ([]Transfer, error)
+	ListTransfers(ctx context.Context, accountNum account.AccountNumber, filter ListTransfersFilter) ([]Transfer, error)
 
 	// Transfers money between accounts
+	//	ctx - context, service joins unit of work carried by it
 	//	id - unique transfer id
 	// 	source - source account number
 	// 	dest   - dest account number
 	//	amount - amount to trangfer
-	TransferMoney(id TransferId, source, dest account.AccountNumber, amount uint64) error
+	//	details - optional memo, external reference and metadata of transfer
+	TransferMoney(ctx context.Context, id TransferId, source, dest account.AccountNumber, amount uint64, details TransferDetails) error
+
+	// Deposits money from external settlement account to customer account
+	//	ctx        - context, service joins unit of work carried by it
+	//	id         - unique transfer id
+	//	accountNum - account number
+	//	amount     - amount to deposit
+	Deposit(ctx context.Context, id TransferId, accountNum account.AccountNumber, amount uint64) error
+
+	// Withdraws money from customer account to external settlement account
+	//	ctx        - context, service joins unit of work carried by it
+	//	id         - unique transfer id
+	//	accountNum - account number
+	//	amount     - amount to withdraw
+	Withdraw(ctx context.Context, id TransferId, accountNum account.AccountNumber, amount uint64) error
+
+	// Creates limit tier or replaces limits of existing tier. Limits of tier apply to all accounts of tier
+	//	ctx    - context, service joins unit of work carried by it
+	//	tier   - tier name
+	//	limits - tier limits, nil limit means there is no limit
+	SetLimitTier(ctx context.Context, tier string, limits TransferLimits) error
+
+	// Sets limit tier of account and limits that override limits of tier
+	//	ctx        - context, service joins unit of work carried by it
+	//	accountNum - account number
+	//	tier       - tier name, empty - account has no tier
+	//	limits     - account limits, nil limit means that tier limit is used
+	// Returns effective limits of account. Returns ErrLimitTierNotFound if tier does not exist
+	SetAccountLimits(ctx context.Context, accountNum account.AccountNumber, tier string, limits TransferLimits) (*TransferLimits, error)
 }
 
 // Transfer service implementation
 type transferService struct {
-	dbContextFactory func() (db.DbContext, error)
+	storage Storage
+
+	// Returns current time. Used to calculate transfer limit periods
+	now func() time.Time
 }
 
 // Creates new transfer service
-//	dbContextFactory - factory function used to create new db context
-func NewTransferService(dbContextFactory func() (db.DbContext, error)) TransferService {
-	return transferService{dbContextFactory}
+//	storage - storage backend of money transfers
+func NewTransferService(storage Storage) TransferService {
+	return transferService{storage, time.Now}
 }
 
-func (svc transferService) ListTransfers(accountNumber account.AccountNumber) ([]Transfer, error) {
-	dbContext, err := svc.dbContextFactory()
+func (svc transferService) ListTransfers(ctx context.Context, accountNumber account.AccountNumber, filter ListTransfersFilter) ([]Transfer, error) {
+	uow, err := svc.storage.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	defer dbContext.Release()
-
-	var accountNum = int64(uint64(accountNumber))
-	var count int64 = -1
-	err = dbContext.Query(
-		"SELECT COUNT(*) FROM public.accounts WHERE account_number = $1",
-		sqlParams{accountNum},
-		func(rows db.QueryResultRows) error {
-			if !rows.Next() {
-				return servErr.ErrDatabaseError(errQueryReturnedNoData)
-			}
-
-			rows.Scan(&count)
-			return nil
-		},
-	)
+	defer uow.Release()
 
+	acc, err := svc.storage.Accounts(uow).Get(accountNumber)
 	if err != nil {
 		return nil, err
 	}
 
-	if count == 0 {
+	if acc == nil {
 		return nil, ErrInvalidAccount(accountNumber)
 	}
 
-	var result = []Transfer{}
-	err = dbContext.Query(
-		"SELECT transfer_id, amount, source_account, dest_account, created_at FROM public.transfers "+
-			"WHERE source_account = $1 or dest_account = $1 ORDER BY created_at DESC",
-		sqlParams{accountNum},
-		func(rows db.QueryResultRows) error {
-			for rows.Next() {
-				var (
-					id        uuid.UUID
-					amount    int64
-					createdAt time.Time
-					sourceAcc int64
-					destAcc   int64
-				)
-
-				err = rows.Scan(&id, &amount, &sourceAcc, &destAcc, &createdAt)
-				if err != nil {
-					return servErr.ErrDatabaseError(err)
-				}
-
-				var direction string
-				var fromAccount *account.AccountNumber = nil
-				var toAccount *account.AccountNumber = nil
-
-				if account.AccountNumber(sourceAcc) == accountNumber {
-					var val = account.AccountNumber(destAcc)
-					toAccount = &val
-					direction = DirectionOutgoing
-				}
-				if account.AccountNumber(destAcc) == accountNumber {
-					var val = account.AccountNumber(sourceAcc)
-					fromAccount = &val
-					direction = DirectionIncoming
-				}
-
-				result = append(result, Transfer{
-					Id:          TransferId(id),
-					Account:     accountNumber,
-					Amount:      amount,
-					CreatedAt:   createdAt,
-					Direction:   direction,
-					FromAccount: fromAccount,
-					ToAccount:   toAccount,
-				})
-			}
+	return svc.storage.Transfers(uow).List(accountNumber, filter)
+}
 
-			return nil
-		},
-	)
+func (svc transferService) TransferMoney(ctx context.Context, id TransferId, source, dest account.AccountNumber, amount uint64, details TransferDetails) error {
+	err := details.Validate()
+	if err != nil {
+		return err
+	}
 
+	uow, err := svc.storage.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return result, nil
-}
+	defer uow.Release()
 
-func (svc transferService) TransferMoney(id TransferId, source, dest account.AccountNumber, amount uint64) error {
-	dbContext, err := svc.dbContextFactory()
+	err = svc.executeTransfer(uow, id, source, dest, amount, TransferTypeTransfer, details)
 	if err != nil {
 		return err
 	}
 
-	defer dbContext.Release()
+	return uow.Save()
+}
 
-	// Reading existing accounts
-	// Rows for accounts would be blocked until transaction is finished
-	sourceAccount, destAccount, err := readPaymentAccounts(dbContext, source, dest)
+func (svc transferService) Deposit(ctx context.Context, id TransferId, accountNum account.AccountNumber, amount uint64) error {
+	uow, err := svc.storage.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	if sourceAccount == nil {
-		return ErrInvalidAccount(source)
+	defer uow.Release()
+
+	settlementNum, err := settlementAccountNumber(svc.storage.Accounts(uow))
+	if err != nil {
+		return err
 	}
 
-	if destAccount == nil {
-		return ErrInvalidAccount(dest)
+	err = svc.executeTransfer(uow, id, settlementNum, accountNum, amount, TransferTypeDeposit, TransferDetails{})
+	if err != nil {
+		return err
 	}
 
-	// Checking if money thransfer with the same ID already exists (to avoid revolut-like fuckup)
-	isDuplicate, err := checkForTransferDuplicate(dbContext, id)
+	return uow.Save()
+}
+
+func (svc transferService) Withdraw(ctx context.Context, id TransferId, accountNum account.AccountNumber, amount uint64) error {
+	uow, err := svc.storage.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	if isDuplicate {
-		return ErrTransferAlreadyComplete
+	defer uow.Release()
+
+	settlementNum, err := settlementAccountNumber(svc.storage.Accounts(uow))
+	if err != nil {
+		return err
 	}
 
-	// checking for balance
-	if sourceAccount.Balance < int64(amount) {
-		return ErrNotEnoughMoney
+	err = svc.executeTransfer(uow, id, accountNum, settlementNum, amount, TransferTypeWithdrawal, TransferDetails{})
+	if err != nil {
+		return err
 	}
 
-	// updating balance
-	err = updateAccountBalancesForTransfer(dbContext, source, dest, amount)
+	return uow.Save()
+}
+
+func (svc transferService) SetLimitTier(ctx context.Context, tier string, limits TransferLimits) error {
+	uow, err := svc.storage.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	// adding payment history records for both accounts
-	err = addPaymentHistory(dbContext, uuid.UUID(id), source, dest, amount)
+	defer uow.Release()
+
+	err = svc.storage.Transfers(uow).SaveLimitTier(tier, limits)
 	if err != nil {
 		return err
 	}
 
-	return dbContext.Save()
+	return uow.Save()
 }
 
-func readPaymentAccounts(dbContext db.DbContext, sourceNumber, destNumber account.AccountNumber) (sourceAccount, destAccount *account.Account, err error) {
-	sourceAccount = nil
-	destAccount = nil
-
-	err = dbContext.Query(
-		"SELECT account_number, balance FROM public.accounts WHERE account_number = $1 or account_number = $2 FOR UPDATE",
-		sqlParams{sourceNumber, destNumber},
-		func(rows db.QueryResultRows) error {
-			for rows.Next() {
-				var (
-					accNum  int64
-					balance int64
-				)
-
-				err := rows.Scan(&accNum, &balance)
-				if err != nil {
-					return servErr.ErrDatabaseError(err)
-				}
-
-				var acc = account.Account{Number: account.AccountNumber(uint64(accNum)), Balance: balance}
-				if acc.Number == sourceNumber {
-					sourceAccount = &acc
-				}
-
-				if acc.Number == destNumber {
-					destAccount = &acc
-				}
-			}
+func (svc transferService) SetAccountLimits(ctx context.Context, accountNum account.AccountNumber, tier string, limits TransferLimits) (*TransferLimits, error) {
+	uow, err := svc.storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-			return nil
-		},
-	)
+	defer uow.Release()
 
+	acc, err := svc.storage.Accounts(uow).Get(accountNum)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return sourceAccount, destAccount, nil
-}
+	if acc == nil {
+		return nil, ErrInvalidAccount(accountNum)
+	}
 
-func checkForTransferDuplicate(dbContext db.DbContext, transferId TransferId) (bool, error) {
-	var id = uuid.UUID(transferId)
-	var result = false
-	var err = dbContext.Query(
-		"SELECT COUNT(*) FROM public.transfers WHERE transfer_id = $1",
-		sqlParams{id},
-		func(value db.QueryResultRows) error {
-			if !value.Next() {
-				return servErr.ErrDatabaseError(errQueryReturnedNoData)
-			}
+	var transfers = svc.storage.Transfers(uow)
+	if tier != "" {
+		exists, err := transfers.LimitTierExists(tier)
+		if err != nil {
+			return nil, err
+		}
 
-			var val int64
-			err := value.Scan(&val)
-			if err != nil {
-				return servErr.ErrDatabaseError(err)
-			}
+		if !exists {
+			return nil, ErrLimitTierNotFound(tier)
+		}
+	}
 
-			result = val > 0
-			return nil
-		})
+	err = transfers.SaveAccountLimits(accountNum, tier, limits)
+	if err != nil {
+		return nil, err
+	}
 
-	return result, err
+	effective, err := transfers.ReadLimits(accountNum)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uow.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return &effective, nil
 }
 
-func updateAccountBalancesForTransfer(dbContext db.DbContext, sourceNumber, destNumber account.AccountNumber, amount uint64) error {
-	rowsAffected, err := dbContext.Execute(
-		"UPDATE public.accounts SET balance = balance - $1 WHERE account_number = $2",
-		int64(amount), int64(uint64(sourceNumber)),
-	)
+// Moves money between accounts inside unit of work. Does not save unit of work
+//	uow          - unit of work
+//	id           - unique transfer id
+//	source       - source account number
+//	dest         - dest account number
+//	amount       - amount to transfer
+//	transferType - transfer type, defines what kind of accounts can be used as source and dest
+//	details      - optional transfer details
+func (svc transferService) executeTransfer(uow db.UnitOfWork, id TransferId, source, dest account.AccountNumber, amount uint64, transferType string, details TransferDetails) error {
+	var accounts = svc.storage.Accounts(uow)
+	var transfers = svc.storage.Transfers(uow)
+
+	// Settlement account is source for deposits and dest for withdrawals.
+	// All other transfers are allowed only between customer accounts
+	var sourceType = account.AccountTypeCustomer
+	var destType = account.AccountTypeCustomer
+	switch transferType {
+	case TransferTypeDeposit:
+		sourceType = account.AccountTypeSettlement
+	case TransferTypeWithdrawal:
+		destType = account.AccountTypeSettlement
+	}
+
+	var event = TransferCompletedEvent{
+		Id:                id,
+		Type:              transferType,
+		Source:            source,
+		Dest:              dest,
+		Amount:            int64(amount),
+		Memo:              details.Memo,
+		ExternalReference: details.ExternalReference,
+		Metadata:          details.Metadata,
+		CreatedAt:         svc.now().UTC(),
+	}
+
+	// Executing transfer by single request if repository can do it
+	if executor, ok := transfers.(TransferExecutor); ok && source != dest {
+		executed, err := executor.Execute(event, sourceType, destType)
+		if err != nil || executed {
+			return err
+		}
+	}
 
+	// Hot dest account is not locked, it is credited by pending credit,
+	// so concurrent transfers to it don't wait for each other
+	hotDest, err := findHotAccount(accounts, dest, destType)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrInvalidAccount(sourceNumber)
+	var creditPending = hotDest != nil && dest != source
+	var lockNums = []account.AccountNumber{source, dest}
+	if creditPending {
+		lockNums = lockNums[:1]
 	}
 
-	rowsAffected, err = dbContext.Execute(
-		"UPDATE public.accounts SET balance = balance + $1 WHERE account_number = $2",
-		int64(amount), destNumber,
-	)
-
+	// Reading existing accounts
+	// Accounts would be locked until unit of work is finished
+	locked, err := accounts.LockForUpdate(lockNums...)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrInvalidAccount(destNumber)
+	var sourceAccount = findAccount(locked, source, sourceType)
+	if sourceAccount == nil {
+		return ErrInvalidAccount(source)
 	}
 
-	return nil
-}
+	var destAccount = findAccount(locked, dest, destType)
+	if creditPending {
+		destAccount = hotDest
+	}
+
+	if destAccount == nil {
+		return ErrInvalidAccount(dest)
+	}
 
-func addPaymentHistory(dbContext db.DbContext, transferId uuid.UUID, sourceNumber, destNumber account.AccountNumber, amount uint64) error {
-	rowsAffected, err := dbContext.Execute(
-		"INSERT INTO public.transfers (transfer_id, amount, source_account, dest_account)"+
-			"VALUES ($1, $2, $3, $4)",
-		transferId, amount, sourceNumber, destNumber,
-	)
+	// Checking if money thransfer with the same ID already exists (to avoid revolut-like fuckup).
+	// Transfer with the same ID executed concurrently is not visible yet, it is rejected by unique transfer ID on insert
+	isDuplicate, err := transfers.Exists(id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected != 1 {
+	if isDuplicate {
 		return ErrTransferAlreadyComplete
 	}
 
+	// Settlement account represents money outside of the system, so its balance is not checked
+	if sourceType != account.AccountTypeSettlement {
+		// debits of hot account are checked strictly, against balance with all pending credits applied
+		if sourceAccount.Hot {
+			sourceAccount, err = account.ApplyPendingCredits(accounts, source)
+			if err != nil {
+				return err
+			}
+		}
+
+		// checking for balance, including credit line of source account
+		if sourceAccount.AvailableFunds() < int64(amount) {
+			return ErrNotEnoughMoney
+		}
+
+		// checking for velocity limits of source account
+		err = checkTransferLimits(transfers, source, amount, event.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// updating balance
+	err = accounts.AdjustBalance(source, -int64(amount))
+	if err != nil {
+		return err
+	}
+
+	if creditPending {
+		err = accounts.AddPendingCredit(dest, int64(amount))
+	} else {
+		err = accounts.AdjustBalance(dest, int64(amount))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// adding payment history records for both accounts
+	err = transfers.Insert(id, source, dest, amount, transferType, details, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// event is written in the same unit of work, so it is published only if transfer is committed.
+	// Balance of hot dest account is read without lock, so it is not reported
+	event.SourceBalance = sourceAccount.Balance - int64(amount)
+	if !creditPending {
+		var destBalance = destAccount.Balance + int64(amount)
+		event.DestBalance = &destBalance
+	}
+
+	return transfers.WriteEvent(EventTransferCompleted, event)
+}
+
+// Returns number of external settlement account
+//	accounts - account repository
+func settlementAccountNumber(accounts account.AccountRepository) (account.AccountNumber, error) {
+	settlement, err := accounts.List(account.ListAccountsFilter{Type: account.AccountTypeSettlement})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(settlement) == 0 {
+		return 0, servErr.ErrDatabaseError(errNoSettlementAccount)
+	}
+
+	return settlement[0].Number, nil
+}
+
+// Reads hot account without locking it
+//	accounts    - account repository
+//	accountNum  - account number
+//	accountType - expected account type
+// Returns account, nil if it does not exist, has different type or is not hot
+func findHotAccount(accounts account.AccountRepository, accountNum account.AccountNumber, accountType string) (*account.Account, error) {
+	acc, err := accounts.Get(accountNum)
+	if err != nil || acc == nil || !acc.Hot || acc.Type != accountType {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// Returns account with number and type from list of accounts
+//	accounts    - list of accounts
+//	accountNum  - account number
+//	accountType - expected account type
+// Returns account, nil if it is not in list or has different type
+func findAccount(accounts []account.Account, accountNum account.AccountNumber, accountType string) *account.Account {
+	for i := range accounts {
+		if accounts[i].Number == accountNum && accounts[i].Type == accountType {
+			return &accounts[i]
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,87 @@
+package transfer_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"test/coins/account"
+	"test/coins/memory"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const benchAccounts = 16
+
+// Creates transfer service backed by in-memory store with accounts that never run out of money
+// Returns service and numbers of opened accounts
+func setupBenchService() (transfer.TransferService, []account.AccountNumber) {
+	var store = memory.NewStore(time.Second*5, nil)
+	var numbers = []account.AccountNumber{}
+	for i := 0; i < benchAccounts; i++ {
+		numbers = append(numbers, store.AddAccount(account.AccountTypeCustomer, 0, math.MaxInt32))
+	}
+
+	return transfer.NewTransferService(store), numbers
+}
+
+func Benchmark_TransferMoney_UniformAccounts(b *testing.B) {
+	var service, numbers = setupBenchService()
+	var seed int64 = 0
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var rnd = rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			var source = rnd.Intn(len(numbers))
+			var dest = (source + 1 + rnd.Intn(len(numbers)-1)) % len(numbers)
+			err := service.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), numbers[source], numbers[dest], 1, transfer.TransferDetails{})
+			if err != nil {
+				b.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+			}
+		}
+	})
+}
+
+func Benchmark_TransferMoney_HotAccount(b *testing.B) {
+	var service, numbers = setupBenchService()
+	var seed int64 = 0
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var rnd = rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			// Every transfer either credits or debits the first account
+			var source, dest = numbers[0], numbers[1+rnd.Intn(len(numbers)-1)]
+			if rnd.Intn(2) == 0 {
+				source, dest = dest, source
+			}
+
+			err := service.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), source, dest, 1, transfer.TransferDetails{})
+			if err != nil {
+				b.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+			}
+		}
+	})
+}
+
+func Benchmark_ListTransfers(b *testing.B) {
+	var service, numbers = setupBenchService()
+	for i := 0; i < 1000; i++ {
+		err := service.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), numbers[i%2], numbers[1-i%2], 1, transfer.TransferDetails{})
+		if err != nil {
+			b.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := service.ListTransfers(context.Background(), numbers[0], transfer.ListTransfersFilter{})
+		if err != nil {
+			b.Fatalf("unexpected error occured when ListTransfers() was called: %s", err.Error())
+		}
+	}
+}
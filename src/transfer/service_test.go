@@ -1,28 +1,35 @@
 package transfer_test
 
 import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"test/coins/account"
 	"test/coins/db"
 	"test/coins/transfer"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx"
 
 	servErr "test/coins/errors"
 )
 
 const (
-	dbAccountNumber1 int64 = 1
-	dbAccountNumber2 int64 = 2
+	dbAccountNumber1          int64 = 1
+	dbAccountNumber2          int64 = 2
+	dbSettlementAccountNumber int64 = 100
 )
 
 func setupService(setupMock func(mock sqlmock.Sqlmock)) transfer.TransferService {
-	return transfer.NewTransferService(func() (db.DbContext, error) {
+	return transfer.NewTransferService(transfer.NewPostgresStorage(func() (db.DbContext, error) {
 		return db.CreateMockDbContext(setupMock)
-	})
+	}))
 }
 
 func valdiateServiceError(expectedKind int, expectedInnerErr error, actual error, method string) (bool, string) {
@@ -46,6 +53,53 @@ func valdiateServiceError(expectedKind int, expectedInnerErr error, actual error
 	return true, ""
 }
 
+// Expects read of dest account that checks if it is hot, dest account is not hot
+//	mock - sql mock
+func expectRegularDest(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT account_number, .+ FROM public.accounts WHERE account_number = \\$1").
+		WillReturnRows(sqlmock.NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}))
+}
+
+// Matches TransferCompleted event payload written to outbox by account balances after transfer
+type transferCompletedPayload struct {
+	sourceBalance int64
+
+	// Expected dest balance, nil if it should not be reported
+	destBalance *int64
+}
+
+// Returns pointer to balance
+//	balance - account balance
+func balanceOf(balance int64) *int64 {
+	return &balance
+}
+
+func (m transferCompletedPayload) Match(v driver.Value) bool {
+	data, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	var event transfer.TransferCompletedEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return false
+	}
+
+	if m.destBalance == nil || event.DestBalance == nil {
+		return event.SourceBalance == m.sourceBalance && event.DestBalance == m.destBalance
+	}
+
+	return event.SourceBalance == m.sourceBalance && *event.DestBalance == *m.destBalance
+}
+
+// Matches time passed to database in UTC
+type utcTime struct{}
+
+func (m utcTime) Match(v driver.Value) bool {
+	value, ok := v.(time.Time)
+	return ok && value.Location() == time.UTC
+}
+
 func Test_ListTransfers_SqlErrorHandled(t *testing.T) {
 	// Arrange
 	var expectedErr = errors.New("database related error")
@@ -54,16 +108,18 @@ func Test_ListTransfers_SqlErrorHandled(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		var accCountRows = sqlmock.NewRows([]string{""}).AddRow(1)
-		mock.ExpectQuery("SELECT COUNT").WillReturnRows(accCountRows)
+		var accountRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number").WillReturnRows(accountRows)
 
-		mock.ExpectQuery("SELECT transfer_id, amount, source_account, dest_account, created_at FROM public.transfers").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT transfer_id, amount, source_account, dest_account, created_at, transfer_type, memo, external_reference, metadata").WillReturnError(expectedErr)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	transfers, err := service.ListTransfers(1)
+	transfers, err := service.ListTransfers(context.Background(), 1, transfer.ListTransfersFilter{})
 
 	// Assert
 	isValid, msg := valdiateServiceError(servErr.ErrorKindDB, expectedErr, err, "ListTransfers()")
@@ -88,14 +144,14 @@ func Test_ListTransfer_InvalidAccount(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		var accCountRows = sqlmock.NewRows([]string{""}).AddRow(0)
-		mock.ExpectQuery("SELECT COUNT").WillReturnRows(accCountRows)
+		var accountRows = sqlmock.NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"})
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number").WillReturnRows(accountRows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	transfers, err := service.ListTransfers(1)
+	transfers, err := service.ListTransfers(context.Background(), 1, transfer.ListTransfersFilter{})
 
 	// Assert
 	if err == nil {
@@ -128,17 +184,19 @@ func Test_ListTransfers_NoTransfers(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		var accCountRows = sqlmock.NewRows([]string{""}).AddRow(1)
-		mock.ExpectQuery("SELECT COUNT").WillReturnRows(accCountRows)
+		var accountRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number").WillReturnRows(accountRows)
 
-		var rows = sqlmock.NewRows([]string{"transfer_id", "amount", "source_account", "dest_account", "created_at"})
-		mock.ExpectQuery("SELECT transfer_id, amount, source_account, dest_account, created_at FROM public.transfers").WillReturnRows(rows)
+		var rows = sqlmock.NewRows([]string{"transfer_id", "amount", "source_account", "dest_account", "created_at", "transfer_type", "memo", "external_reference", "metadata"})
+		mock.ExpectQuery("SELECT transfer_id, amount, source_account, dest_account, created_at, transfer_type, memo, external_reference, metadata").WillReturnRows(rows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	transfers, err := service.ListTransfers(1)
+	transfers, err := service.ListTransfers(context.Background(), 1, transfer.ListTransfersFilter{})
 
 	// Assert
 	if err != nil {
@@ -170,13 +228,13 @@ func Test_TransferMoney_SqlErrorHandled(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnError(expectedErr)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	err := service.TransferMoney(transferId, sourceAcc, descAcc, amount)
+	err := service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 	isValid, msg := valdiateServiceError(servErr.ErrorKindDB, expectedErr, err, "SendMoney()")
@@ -204,14 +262,16 @@ func Test_TransferMoney_CheckForValidSourceAccount(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
-		var accountsListRows = sqlmock.NewRows([]string{"account_number", "balance"})
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(accountsListRows)
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"})
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	var err = service.TransferMoney(transferId, sourceAcc, destAcc, amount)
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 	isValid, msg := valdiateServiceError(transfer.ErrKindInvalidAccount, nil, err, "TransferMoney(...)")
@@ -244,16 +304,18 @@ func Test_TransferMoney_CheckForValidDestAccount(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
+		expectRegularDest(mock)
+
 		var accountsListRows = sqlmock.
-			NewRows([]string{"account_number", "balance"}).
-			AddRow(dbAccountNumber1, 1000)
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(accountsListRows)
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
 
 		mock.ExpectRollback()
 	})
 
 	// Act
-	var err = service.TransferMoney(transferId, sourceAcc, destAcc, amount)
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 	isValid, msg := valdiateServiceError(transfer.ErrKindInvalidAccount, nil, err, "TransferMoney(...)")
@@ -286,11 +348,13 @@ func Test_TransferMoney_DoesNotAllowToCreateTwoTransfersWithSameId(t *testing.T)
 		dbMock = mock
 		mock.ExpectBegin()
 
+		expectRegularDest(mock)
+
 		var accountsListRows = sqlmock.
-			NewRows([]string{"account_number", "balance"}).
-			AddRow(dbAccountNumber1, 1000).
-			AddRow(dbAccountNumber2, 2000)
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(accountsListRows)
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
 
 		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(2)
 		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
@@ -299,7 +363,61 @@ func Test_TransferMoney_DoesNotAllowToCreateTwoTransfersWithSameId(t *testing.T)
 	})
 
 	// Act
-	var err = service.TransferMoney(transferId, sourceAcc, descAcc, amount)
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindTransferAlreadyComplete, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_DuplicateInsertedConcurrently_ErrTransferAlreadyComplete(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		// transfer with the same id is not committed yet, so it is not found
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(nil, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WillReturnRows(limitsRows)
+
+		var updateCountResult = sqlmock.NewResult(0, 1)
+		mock.ExpectExec("UPDATE public.accounts SET balance = balance").WillReturnResult(updateCountResult)
+		mock.ExpectExec("UPDATE public.accounts SET balance = balance").WillReturnResult(updateCountResult)
+
+		mock.ExpectExec("INSERT INTO public.transfers").
+			WillReturnError(pgx.PgError{Code: "23505", ConstraintName: "idx_transfers_transfer_id"})
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 	isValid, msg := valdiateServiceError(transfer.ErrKindTransferAlreadyComplete, nil, err, "TransferMoney(...)")
@@ -327,11 +445,13 @@ func Test_TransferMoney_CheckForEnoughMoney(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
+		expectRegularDest(mock)
+
 		var accountsListRows = sqlmock.
-			NewRows([]string{"account_number", "balance"}).
-			AddRow(dbAccountNumber1, 1000).
-			AddRow(dbAccountNumber2, 2000)
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(accountsListRows)
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
 
 		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
 		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
@@ -340,7 +460,7 @@ func Test_TransferMoney_CheckForEnoughMoney(t *testing.T) {
 	})
 
 	// Act
-	var err = service.TransferMoney(transferId, sourceAcc, descAcc, amount)
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 
@@ -370,19 +490,314 @@ func Test_TransferMoney_SuccessOnValidDataProvided(t *testing.T) {
 		dbMock = mock
 		mock.ExpectBegin()
 
+		expectRegularDest(mock)
+
 		var accountsListRows = sqlmock.
-			NewRows([]string{"account_number", "balance"}).
-			AddRow(dbAccountNumber1, 1000).
-			AddRow(dbAccountNumber2, 2000)
-		mock.ExpectQuery("SELECT account_number, balance FROM public.accounts").WillReturnRows(accountsListRows)
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
 
 		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
 		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
 
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(nil, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WillReturnRows(limitsRows)
+
 		var updateCountResult = sqlmock.NewResult(0, 1)
 		mock.ExpectExec(
 			"UPDATE public.accounts SET balance = balance",
-		).WithArgs(int64(amount), dbAccountNumber1).WillReturnResult(updateCountResult)
+		).WithArgs(-int64(amount), dbAccountNumber1).WillReturnResult(updateCountResult)
+
+		mock.ExpectExec(
+			"UPDATE public.accounts SET balance = balance",
+		).WithArgs(int64(amount), dbAccountNumber2).WillReturnResult(updateCountResult)
+
+		var insertCountResult = sqlmock.NewResult(0, 1)
+		mock.ExpectExec(
+			"INSERT INTO public.transfers",
+		).WithArgs(transferUuid, int64(amount), dbAccountNumber1, dbAccountNumber2, transfer.TransferTypeTransfer, nil, nil, nil, utcTime{}).WillReturnResult(insertCountResult)
+
+		mock.ExpectExec("INSERT INTO public.outbox_events").
+			WithArgs(transfer.EventTransferCompleted, transferCompletedPayload{sourceBalance: 1000 - int64(amount), destBalance: balanceOf(2000 + int64(amount))}, utcTime{}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error returned when called for TransferMoney(...): %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_HotDestAccountCreditedByPendingCredit(t *testing.T) {
+	// Arrange
+	var (
+		transferUuid        = uuid.New()
+		transferId          = transfer.TransferId(transferUuid)
+		sourceAcc           = account.AccountNumber(dbAccountNumber1)
+		destAcc             = account.AccountNumber(dbAccountNumber2)
+		amount       uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var destRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, true)
+		mock.ExpectQuery("SELECT account_number, .+ FROM public.accounts WHERE account_number = \\$1").WithArgs(dbAccountNumber2).WillReturnRows(destRows)
+
+		// Only source account is locked
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number IN").
+			WithArgs(dbAccountNumber1).
+			WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(nil, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WillReturnRows(limitsRows)
+
+		mock.ExpectExec(
+			"UPDATE public.accounts SET balance = balance",
+		).WithArgs(-int64(amount), dbAccountNumber1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec(
+			"INSERT INTO public.pending_credits",
+		).WithArgs(dbAccountNumber2, int64(amount)).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(
+			"INSERT INTO public.transfers",
+		).WithArgs(transferUuid, int64(amount), dbAccountNumber1, dbAccountNumber2, transfer.TransferTypeTransfer, nil, nil, nil, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec("INSERT INTO public.outbox_events").
+			WithArgs(transfer.EventTransferCompleted, transferCompletedPayload{sourceBalance: 1000 - int64(amount)}, utcTime{}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error returned when called for TransferMoney(...): %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_HotSourceAccountCheckedAfterPendingCreditsApplied(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		destAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		// Balance read while waiting for lock counts credit that was rolled up meanwhile
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 300, 0, account.AccountTypeCustomer, true).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		mock.ExpectQuery("DELETE FROM public.pending_credits").
+			WithArgs(dbAccountNumber1).
+			WillReturnRows(sqlmock.NewRows([]string{"amount"}))
+
+		var sourceRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 200, 0, account.AccountTypeCustomer, true)
+		mock.ExpectQuery("SELECT account_number, .+ FROM public.accounts WHERE account_number = \\$1").WithArgs(dbAccountNumber1).WillReturnRows(sourceRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindNotEnoughMoney, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_CheckForTransferAmountLimit(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 500
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(300, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WithArgs(dbAccountNumber1).WillReturnRows(limitsRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindLimitExceeded, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	var expectedErrorMsg = transfer.ErrLimitExceeded(transfer.LimitTransferAmount, nil).Error()
+	if err.Error() != expectedErrorMsg {
+		t.Fatalf("unexpected error message. Expected message: %s, actual: %s", expectedErrorMsg, err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_CheckForDailyAmountLimit(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		descAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(nil, 500, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WillReturnRows(limitsRows)
+
+		var usageRows = sqlmock.NewRows([]string{"", "", ""}).AddRow(300, 2, 300)
+		mock.ExpectQuery("FROM public.transfers WHERE source_account").
+			WithArgs(dbAccountNumber1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(usageRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindLimitExceeded, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if !strings.HasPrefix(err.Error(), transfer.LimitDailyAmount) || !strings.Contains(err.Error(), "resets at") {
+		t.Fatalf("error message should contain limit name and reset time, actual: %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_AllowsToUseCreditLine(t *testing.T) {
+	// Arrange
+	var (
+		transferUuid        = uuid.New()
+		transferId          = transfer.TransferId(transferUuid)
+		sourceAcc           = account.AccountNumber(dbAccountNumber1)
+		descAcc             = account.AccountNumber(dbAccountNumber2)
+		amount       uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 100, 500, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var limitsRows = sqlmock.NewRows([]string{"", "", "", ""}).AddRow(nil, nil, nil, nil)
+		mock.ExpectQuery("LEFT JOIN public.limit_tiers").WillReturnRows(limitsRows)
+
+		var updateCountResult = sqlmock.NewResult(0, 1)
+		mock.ExpectExec(
+			"UPDATE public.accounts SET balance = balance",
+		).WithArgs(-int64(amount), dbAccountNumber1).WillReturnResult(updateCountResult)
 
 		mock.ExpectExec(
 			"UPDATE public.accounts SET balance = balance",
@@ -391,13 +806,17 @@ func Test_TransferMoney_SuccessOnValidDataProvided(t *testing.T) {
 		var insertCountResult = sqlmock.NewResult(0, 1)
 		mock.ExpectExec(
 			"INSERT INTO public.transfers",
-		).WithArgs(transferUuid, int64(amount), dbAccountNumber1, dbAccountNumber2).WillReturnResult(insertCountResult)
+		).WithArgs(transferUuid, int64(amount), dbAccountNumber1, dbAccountNumber2, transfer.TransferTypeTransfer, nil, nil, nil, sqlmock.AnyArg()).WillReturnResult(insertCountResult)
+
+		mock.ExpectExec("INSERT INTO public.outbox_events").
+			WithArgs(transfer.EventTransferCompleted, sqlmock.AnyArg(), utcTime{}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectCommit()
 	})
 
 	// Act
-	var err = service.TransferMoney(transferId, sourceAcc, descAcc, amount)
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, descAcc, amount, transfer.TransferDetails{})
 
 	// Assert
 	if err != nil {
@@ -409,3 +828,319 @@ func Test_TransferMoney_SuccessOnValidDataProvided(t *testing.T) {
 		t.Fatalf("db methods call expectations were not met: %s", err.Error())
 	}
 }
+
+func Test_Deposit_BookedFromSettlementAccount(t *testing.T) {
+	// Arrange
+	var (
+		transferUuid        = uuid.New()
+		transferId          = transfer.TransferId(transferUuid)
+		destAcc             = account.AccountNumber(dbAccountNumber1)
+		amount       uint64 = 5000
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var settlementRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbSettlementAccountNumber, 0, 0, account.AccountTypeSettlement, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_type").
+			WithArgs(account.AccountTypeSettlement).
+			WillReturnRows(settlementRows)
+
+		expectRegularDest(mock)
+
+		// Settlement account balance is not checked, so it is allowed to go below zero
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbSettlementAccountNumber, 0, 0, account.AccountTypeSettlement, false).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		var updateCountResult = sqlmock.NewResult(0, 1)
+		mock.ExpectExec(
+			"UPDATE public.accounts SET balance = balance",
+		).WithArgs(-int64(amount), dbSettlementAccountNumber).WillReturnResult(updateCountResult)
+
+		mock.ExpectExec(
+			"UPDATE public.accounts SET balance = balance",
+		).WithArgs(int64(amount), dbAccountNumber1).WillReturnResult(updateCountResult)
+
+		var insertCountResult = sqlmock.NewResult(0, 1)
+		mock.ExpectExec(
+			"INSERT INTO public.transfers",
+		).WithArgs(transferUuid, int64(amount), dbSettlementAccountNumber, dbAccountNumber1, transfer.TransferTypeDeposit, nil, nil, nil, sqlmock.AnyArg()).WillReturnResult(insertCountResult)
+
+		mock.ExpectExec("INSERT INTO public.outbox_events").
+			WithArgs(transfer.EventTransferCompleted, sqlmock.AnyArg(), utcTime{}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	var err = service.Deposit(context.Background(), transferId, destAcc, amount)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error returned when called for Deposit(...): %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_Withdraw_CheckForEnoughMoney(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		amount     uint64 = 1250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var settlementRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbSettlementAccountNumber, 0, 0, account.AccountTypeSettlement, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_type").WillReturnRows(settlementRows)
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false).
+			AddRow(dbSettlementAccountNumber, 0, 0, account.AccountTypeSettlement, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.Withdraw(context.Background(), transferId, sourceAcc, amount)
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindNotEnoughMoney, nil, err, "Withdraw(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_SettlementAccountCanNotBeUsed(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbSettlementAccountNumber)
+		destAcc           = account.AccountNumber(dbAccountNumber1)
+		amount     uint64 = 250
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbSettlementAccountNumber, 0, 0, account.AccountTypeSettlement, false).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, transfer.TransferDetails{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindInvalidAccount, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_MemoLengthIsLimited(t *testing.T) {
+	// Arrange
+	var (
+		transferId        = transfer.TransferId(uuid.New())
+		sourceAcc         = account.AccountNumber(dbAccountNumber1)
+		destAcc           = account.AccountNumber(dbAccountNumber2)
+		amount     uint64 = 250
+		details           = transfer.TransferDetails{Memo: strings.Repeat("a", transfer.MaxMemoLength+1)}
+	)
+
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		t.Fatalf("db context should not be created for invalid transfer details")
+	})
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, sourceAcc, destAcc, amount, details)
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindInvalidTransferDetails, nil, err, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+}
+
+func Test_ListTransfers_FilteredByExternalReference(t *testing.T) {
+	// Arrange
+	var (
+		transferUuid = uuid.New()
+		reference    = "order-1234"
+		memo         = "rent March"
+	)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var accountRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number").WillReturnRows(accountRows)
+
+		var rows = sqlmock.
+			NewRows([]string{"transfer_id", "amount", "source_account", "dest_account", "created_at", "transfer_type", "memo", "external_reference", "metadata"}).
+			AddRow(transferUuid, 150, dbAccountNumber1, dbAccountNumber2, time.Now(), transfer.TransferTypeTransfer, memo, reference, []byte(`{"orderId":"1234"}`))
+		mock.ExpectQuery("SELECT transfer_id, .* AND external_reference = \\$2").
+			WithArgs(dbAccountNumber1, reference).
+			WillReturnRows(rows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	transfers, err := service.ListTransfers(context.Background(), 1, transfer.ListTransfersFilter{ExternalReference: reference})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when ListTransfers() was called: %s", err.Error())
+	}
+
+	if len(transfers) != 1 {
+		t.Fatalf("expected list of 1 money transfer")
+	}
+
+	var tr = transfers[0]
+	if tr.Memo != memo || tr.ExternalReference != reference || tr.Metadata["orderId"] != "1234" {
+		t.Fatalf("transfer details were not read from database")
+	}
+
+	if tr.Direction != transfer.DirectionOutgoing || tr.ToAccount == nil || *tr.ToAccount != account.AccountNumber(dbAccountNumber2) {
+		t.Fatalf("invalid transfer direction")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_TransferMoney_FailedTransferRolledBackToSavepoint(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	dbContext, err := db.CreateMockDbContext(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectExec("SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		expectRegularDest(mock)
+
+		var accountsListRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 100, 0, account.AccountTypeCustomer, false).
+			AddRow(dbAccountNumber2, 2000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts").WillReturnRows(accountsListRows)
+
+		var duplicateCheckRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(duplicateCheckRows)
+
+		// Only changes of transfer are rolled back, transaction of caller stays active
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT uow_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	})
+	if err != nil {
+		t.Fatalf("unable to create mock db context: %s", err.Error())
+	}
+
+	// Service should not begin its own transaction
+	var service = transfer.NewTransferService(transfer.NewPostgresStorage(nil))
+	var ctx = db.WithUnitOfWork(context.Background(), dbContext)
+
+	// Act
+	err = service.TransferMoney(ctx, transfer.TransferId(uuid.New()), account.AccountNumber(dbAccountNumber1), account.AccountNumber(dbAccountNumber2), 250, transfer.TransferDetails{})
+
+	// Assert
+	if err != transfer.ErrNotEnoughMoney {
+		t.Fatalf("expected ErrNotEnoughMoney, got %v", err)
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SetAccountLimits_UnknownTier_ErrLimitTierNotFound(t *testing.T) {
+	// Arrange
+	var dbMock sqlmock.Sqlmock = nil
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		var accountRows = sqlmock.
+			NewRows([]string{"account_number", "balance", "credit_limit", "account_type", "hot"}).
+			AddRow(dbAccountNumber1, 1000, 0, account.AccountTypeCustomer, false)
+		mock.ExpectQuery("SELECT account_number, balance .+, credit_limit, account_type, hot FROM public.accounts WHERE account_number").WillReturnRows(accountRows)
+
+		var tierRows = sqlmock.NewRows([]string{""}).AddRow(0)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM public.limit_tiers WHERE tier").WithArgs("premium").WillReturnRows(tierRows)
+
+		mock.ExpectRollback()
+	})
+
+	// Act
+	limits, err := service.SetAccountLimits(context.Background(), account.AccountNumber(dbAccountNumber1), "premium", transfer.TransferLimits{})
+
+	// Assert
+	isValid, msg := valdiateServiceError(transfer.ErrKindLimitTierNotFound, nil, err, "SetAccountLimits(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if limits != nil {
+		t.Fatalf("in case of any error, SetAccountLimits() should return (nil, error) as result")
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
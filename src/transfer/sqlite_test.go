@@ -0,0 +1,203 @@
+package transfer_test
+
+import (
+	"context"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/migrations"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Opens in-memory SQLite database and applies migrations to it
+func openSQLitePool(tb testing.TB) db.ConnectionPool {
+	pool, err := db.NewConnectionPool("sqlite://:memory:", 1, time.Second)
+	if err != nil {
+		tb.Fatalf("unable to open SQLite database: %s", err.Error())
+	}
+	tb.Cleanup(pool.Close)
+
+	all, err := migrations.Load()
+	if err != nil {
+		tb.Fatalf("unable to load migrations: %s", err.Error())
+	}
+
+	_, err = migrations.NewMigrator(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}, all).Up()
+	if err != nil {
+		tb.Fatalf("unable to migrate SQLite database: %s", err.Error())
+	}
+
+	return pool
+}
+
+func Test_TransferMoney_SQLiteStorage_TransferIsListedForBothAccounts(t *testing.T) {
+	// Arrange
+	var pool = openSQLitePool(t)
+
+	var service = transfer.NewTransferService(transfer.NewPostgresStorage(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}))
+	var (
+		transferId = transfer.TransferId(uuid.New())
+		source     = account.AccountNumber(1)
+		dest       = account.AccountNumber(2)
+		details    = transfer.TransferDetails{Memo: "rent", Metadata: map[string]string{"invoice": "42"}}
+	)
+
+	// Act
+	var err = service.TransferMoney(context.Background(), transferId, source, dest, 700, details)
+	if err != nil {
+		t.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+	}
+
+	duplicateErr := service.TransferMoney(context.Background(), transferId, source, dest, 700, details)
+
+	// Assert
+	if duplicateErr != transfer.ErrTransferAlreadyComplete {
+		t.Fatalf("transfer with the same id should be rejected, got: %v", duplicateErr)
+	}
+
+	for _, acc := range []account.AccountNumber{source, dest} {
+		transfers, err := service.ListTransfers(context.Background(), acc, transfer.ListTransfersFilter{})
+		if err != nil {
+			t.Fatalf("unexpected error occured when ListTransfers() was called: %s", err.Error())
+		}
+
+		if len(transfers) != 1 || transfers[0].Id != transferId || transfers[0].Amount != 700 {
+			t.Fatalf("expected single transfer of account %d, got %+v", acc, transfers)
+		}
+
+		if transfers[0].Memo != details.Memo || transfers[0].Metadata["invoice"] != "42" {
+			t.Fatalf("transfer details are not stored, got %+v", transfers[0])
+		}
+	}
+}
+
+func Benchmark_TransferMoney_SQLiteStorage(b *testing.B) {
+	var pool = openSQLitePool(b)
+
+	var service = transfer.NewTransferService(transfer.NewPostgresStorage(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}))
+
+	// Demo accounts send money back and forth, so balances never run out
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var source, dest = account.AccountNumber(1 + i%2), account.AccountNumber(2 - i%2)
+		err := service.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), source, dest, 1, transfer.TransferDetails{})
+		if err != nil {
+			b.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+		}
+	}
+}
+
+func Test_TransferMoney_SQLiteStorage_HotAccountCreditsRolledUp(t *testing.T) {
+	// Arrange
+	var pool = openSQLitePool(t)
+
+	var factory = func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}
+	var accounts = account.NewAccountService(account.NewPostgresStorage(factory))
+	var transfers = transfer.NewTransferService(transfer.NewPostgresStorage(factory))
+	var source, dest = account.AccountNumber(2), account.AccountNumber(1)
+	_, err := accounts.SetHot(context.Background(), dest, true)
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetHot() was called: %s", err.Error())
+	}
+
+	// Act
+	for i := 0; i < 3; i++ {
+		err = transfers.TransferMoney(context.Background(), transfer.TransferId(uuid.New()), source, dest, 100, transfer.TransferDetails{})
+		if err != nil {
+			t.Fatalf("unexpected error occured when TransferMoney() was called: %s", err.Error())
+		}
+	}
+
+	pending, err := accounts.ListAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error occured when ListAccounts() was called: %s", err.Error())
+	}
+
+	rolledUp, err := accounts.RollUpPendingCredits(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("unexpected error occured when RollUpPendingCredits() was called: %s", err.Error())
+	}
+
+	applied, _ := accounts.ListAccounts(context.Background())
+
+	// Assert
+	if rolledUp != 1 {
+		t.Fatalf("expected 1 rolled up account, got %d", rolledUp)
+	}
+
+	for _, list := range [][]account.Account{pending, applied} {
+		if list[0].Number != dest || list[0].Balance != 10300 || !list[0].Hot || list[1].Balance != 249700 {
+			t.Fatalf("expected balances to include credits before and after roll-up, got %+v", list)
+		}
+	}
+}
+
+func Test_SetAccountLimits_SQLiteStorage_TierAndAccountLimitsApplied(t *testing.T) {
+	// Arrange
+	var pool = openSQLitePool(t)
+	var service = transfer.NewTransferService(transfer.NewPostgresStorage(func() (db.DbContext, error) {
+		return db.CreateContext(pool, time.Second*5)
+	}))
+	var (
+		ctx                    = context.Background()
+		source                 = account.AccountNumber(1)
+		dest                   = account.AccountNumber(2)
+		tierMaxAmount    int64 = 1000
+		tierMaxCount     int64 = 1
+		accountMaxAmount int64 = 500
+		updatedMaxCount  int64 = 2
+	)
+
+	err := service.SetLimitTier(ctx, "basic", transfer.TransferLimits{MaxTransferAmount: &tierMaxAmount, MaxDailyCount: &tierMaxCount})
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetLimitTier() was called: %s", err.Error())
+	}
+
+	// Act
+	limits, err := service.SetAccountLimits(ctx, source, "basic", transfer.TransferLimits{MaxTransferAmount: &accountMaxAmount})
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetAccountLimits() was called: %s", err.Error())
+	}
+
+	tooLargeErr := service.TransferMoney(ctx, transfer.TransferId(uuid.New()), source, dest, 600, transfer.TransferDetails{})
+	firstErr := service.TransferMoney(ctx, transfer.TransferId(uuid.New()), source, dest, 100, transfer.TransferDetails{})
+	secondErr := service.TransferMoney(ctx, transfer.TransferId(uuid.New()), source, dest, 100, transfer.TransferDetails{})
+
+	// Tier is replaced, so its new limit applies to accounts of tier
+	err = service.SetLimitTier(ctx, "basic", transfer.TransferLimits{MaxTransferAmount: &tierMaxAmount, MaxDailyCount: &updatedMaxCount})
+	if err != nil {
+		t.Fatalf("unexpected error occured when SetLimitTier() was called: %s", err.Error())
+	}
+
+	thirdErr := service.TransferMoney(ctx, transfer.TransferId(uuid.New()), source, dest, 100, transfer.TransferDetails{})
+
+	// Assert
+	if limits.MaxTransferAmount == nil || *limits.MaxTransferAmount != accountMaxAmount ||
+		limits.MaxDailyCount == nil || *limits.MaxDailyCount != tierMaxCount || limits.MaxDailyAmount != nil {
+		t.Fatalf("account limits should override tier limits, got %+v", limits)
+	}
+
+	if tooLargeErr == nil || tooLargeErr.Error() != transfer.ErrLimitExceeded(transfer.LimitTransferAmount, nil).Error() {
+		t.Fatalf("transfer amount limit of account expected, got %v", tooLargeErr)
+	}
+
+	if firstErr != nil || thirdErr != nil {
+		t.Fatalf("transfers within limits expected to succeed, got %v and %v", firstErr, thirdErr)
+	}
+
+	isValid, msg := valdiateServiceError(transfer.ErrKindLimitExceeded, nil, secondErr, "TransferMoney(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+}
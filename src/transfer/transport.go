@@ -3,10 +3,8 @@ package transfer
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 
@@ -24,7 +22,7 @@ import (
 func RegisterHandlers(mr *mux.Router, svc TransferService, logger kitlog.Logger) {
 	var opts = []kithttp.ServerOption{
 		kithttp.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
-		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerErrorEncoder(servErr.EncodeError),
 	}
 	var sendPaymentHandler = kithttp.NewServer(
 		makeSendPaymentEnpoint(svc),
@@ -45,6 +43,52 @@ func RegisterHandlers(mr *mux.Router, svc TransferService, logger kitlog.Logger)
 	mr.Handle("/api/v1/accounts/{account}/transfers", listTransfersHandler).Methods("GET")
 }
 
+// Registers http handlers of admin API of transfer service, router should be served only to authorized clients
+// mr     - Mux router where handlers should be registered
+// svc    - service to register
+// logger - logger
+func RegisterAdminHandlers(mr *mux.Router, svc TransferService, logger kitlog.Logger) {
+	var opts = []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
+		kithttp.ServerErrorEncoder(servErr.EncodeError),
+	}
+	var depositHandler = kithttp.NewServer(
+		makeDepositEndpoint(svc),
+		decodeFundingRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/admin/deposits", depositHandler).Methods("POST")
+
+	var withdrawHandler = kithttp.NewServer(
+		makeWithdrawEndpoint(svc),
+		decodeFundingRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/admin/withdrawals", withdrawHandler).Methods("POST")
+
+	var setLimitTierHandler = kithttp.NewServer(
+		makeSetLimitTierEndpoint(svc),
+		decodeSetLimitTierRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/admin/limit-tiers/{tier}", setLimitTierHandler).Methods("PUT")
+
+	var setAccountLimitsHandler = kithttp.NewServer(
+		makeSetAccountLimitsEndpoint(svc),
+		decodeSetAccountLimitsRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/admin/accounts/{account}/limits", setAccountLimitsHandler).Methods("PUT")
+}
+
 func RegisterListTranfersHandler(accountHander *mux.Router, svc TransferService, logger kitlog.Logger) http.Handler {
 	//var r = mux.NewRouter()
 
@@ -55,19 +99,67 @@ func decodeListTransfersRequest(_ context.Context, r *http.Request) (interface{}
 	var vars = mux.Vars(r)
 	accountNumber, ok := vars["account"]
 	if !ok {
-		return nil, errors.New("bad route")
+		return nil, servErr.ErrInvalidRequest("invalid account number", nil)
 	}
 
 	accNum, err := strconv.ParseUint(accountNumber, 10, 64)
 	if err != nil {
-		return nil, errors.New("bad route")
+		return nil, servErr.ErrInvalidRequest("invalid account number", err)
 	}
-	return listTransfersRequest{accNum}, nil
+	var reference = r.URL.Query().Get("externalReference")
+	return listTransfersRequest{accNum, reference}, nil
 }
 
 func decodeSendPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var body sendPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	if err := DecodeStrictJSON(r, &body); err != nil {
+		return nil, err
+	}
+
+	if err := body.validate(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func decodeFundingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body fundingRequest
+	if err := DecodeStrictJSON(r, &body); err != nil {
+		return nil, err
+	}
+
+	if err := body.validate(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func decodeSetLimitTierRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body setLimitTierRequest
+	if err := DecodeStrictJSON(r, &body); err != nil {
+		return nil, err
+	}
+
+	body.Tier = mux.Vars(r)["tier"]
+	if err := body.validate(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func decodeSetAccountLimitsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	accNum, err := strconv.ParseUint(mux.Vars(r)["account"], 10, 64)
+	if err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid account number", err)
+	}
+
+	var body setAccountLimitsRequest
+	if err := DecodeStrictJSON(r, &body); err != nil {
+		return nil, err
+	}
+
+	body.AccountNumber = accNum
+	if err := body.validate(); err != nil {
 		return nil, err
 	}
 	return body, nil
@@ -79,33 +171,9 @@ type errorer interface {
 
 func encodeResponse(ctx context.Context, wr http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(errorer); ok && e.error() != nil {
-		encodeError(ctx, e.error(), wr)
+		servErr.EncodeError(ctx, e.error(), wr)
 		return nil
 	}
 	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(wr).Encode(response)
 }
-
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	switch svcErr := err.(type) {
-	case servErr.ServiceError:
-		if svcErr.Kind() == servErr.ErrorKindDB {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-	default:
-		if strings.HasPrefix("json:", err.Error()) {
-			// dirty hack, did not found way to determine if specific error is json serialization error
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
-}
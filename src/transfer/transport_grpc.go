@@ -0,0 +1,154 @@
+package transfer
+
+import (
+	"context"
+	"test/coins/pb"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	kittransport "github.com/go-kit/kit/transport"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// gRPC server of transfer service
+type grpcServer struct {
+	pb.UnimplementedTransferServiceServer
+
+	listTransfers kitgrpc.Handler
+
+	transferMoney kitgrpc.Handler
+}
+
+// Creates gRPC server for transfer service
+// svc    - service to expose
+// logger - logger
+func NewGRPCServer(svc TransferService, logger kitlog.Logger) pb.TransferServiceServer {
+	var opts = []kitgrpc.ServerOption{
+		kitgrpc.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
+	}
+
+	return &grpcServer{
+		listTransfers: kitgrpc.NewServer(
+			makeListTransfersEndpoint(svc),
+			decodeGRPCListTransfersRequest,
+			encodeGRPCListTransfersResponse,
+			opts...,
+		),
+		transferMoney: kitgrpc.NewServer(
+			makeSendPaymentEnpoint(svc),
+			decodeGRPCTransferMoneyRequest,
+			encodeGRPCTransferMoneyResponse,
+			opts...,
+		),
+	}
+}
+
+func (s *grpcServer) ListTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+	_, resp, err := s.listTransfers.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ListTransfersResponse), nil
+}
+
+func (s *grpcServer) TransferMoney(ctx context.Context, req *pb.TransferMoneyRequest) (*pb.TransferMoneyResponse, error) {
+	_, resp, err := s.transferMoney.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.TransferMoneyResponse), nil
+}
+
+func decodeGRPCListTransfersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	var req = request.(*pb.ListTransfersRequest)
+	return listTransfersRequest{req.Account, req.ExternalReference}, nil
+}
+
+func encodeGRPCListTransfersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	var resp = response.(listTransfersResponse)
+	if resp.Error != nil {
+		return nil, encodeGRPCError(resp.Error)
+	}
+
+	var transfers = make([]*pb.Transfer, len(resp.Tranfers))
+	for i, transfer := range resp.Tranfers {
+		transfers[i] = &pb.Transfer{
+			Id:                uuid.UUID(transfer.Id).String(),
+			Type:              transfer.Type,
+			Account:           uint64(transfer.Account),
+			Amount:            transfer.Amount,
+			Direction:         transfer.Direction,
+			CreatedAt:         timestamppb.New(transfer.CreatedAt),
+			Memo:              transfer.Memo,
+			ExternalReference: transfer.ExternalReference,
+			Metadata:          transfer.Metadata,
+		}
+
+		if transfer.FromAccount != nil {
+			var val = uint64(*transfer.FromAccount)
+			transfers[i].FromAccount = &val
+		}
+
+		if transfer.ToAccount != nil {
+			var val = uint64(*transfer.ToAccount)
+			transfers[i].ToAccount = &val
+		}
+	}
+	return &pb.ListTransfersResponse{Transfers: transfers}, nil
+}
+
+func decodeGRPCTransferMoneyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	var req = request.(*pb.TransferMoneyRequest)
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "id should be valid GUID")
+	}
+
+	var body = sendPaymentRequest{
+		Id:                id,
+		Source:            req.Source,
+		Dest:              req.Dest,
+		Amount:            req.Amount,
+		Memo:              req.Memo,
+		ExternalReference: req.ExternalReference,
+		Metadata:          req.Metadata,
+	}
+
+	if err := body.validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return body, nil
+}
+
+func encodeGRPCTransferMoneyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	var resp = response.(sendPaymentResponse)
+	if resp.Error != nil {
+		return nil, encodeGRPCError(resp.Error)
+	}
+	return &pb.TransferMoneyResponse{}, nil
+}
+
+// Converts service error to gRPC status error
+func encodeGRPCError(err error) error {
+	var code = codes.Internal
+	if svcErr, ok := err.(servErr.ServiceError); ok {
+		switch svcErr.Kind() {
+		case ErrKindInvalidAccount:
+			code = codes.NotFound
+		case ErrKindNotEnoughMoney, ErrKindLimitExceeded:
+			code = codes.FailedPrecondition
+		case ErrKindTransferAlreadyComplete:
+			code = codes.AlreadyExists
+		case ErrKindInvalidTransferDetails:
+			code = codes.InvalidArgument
+		}
+	}
+
+	return status.Error(code, err.Error())
+}
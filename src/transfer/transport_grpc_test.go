@@ -0,0 +1,97 @@
+package transfer_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"test/coins/account"
+	"test/coins/pb"
+	"test/coins/transfer"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// Transfer service stub that fails every transfer with predefined error
+type failingTransferService struct {
+	transfer.TransferService
+
+	err error
+}
+
+func (svc failingTransferService) TransferMoney(ctx context.Context, id transfer.TransferId, source, dest account.AccountNumber, amount uint64, details transfer.TransferDetails) error {
+	return svc.err
+}
+
+// Starts in-memory gRPC server and returns client connected to it
+func setupGRPCClient(t *testing.T, svc transfer.TransferService) pb.TransferServiceClient {
+	var listener = bufconn.Listen(1024 * 1024)
+	var server = grpc.NewServer()
+	pb.RegisterTransferServiceServer(server, transfer.NewGRPCServer(svc, kitlog.NewNopLogger()))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to connect to grpc server: %s", err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewTransferServiceClient(conn)
+}
+
+func Test_GRPCTransferMoney_ErrorKindsMappedToStatusCodes(t *testing.T) {
+	var cases = []struct {
+		err  error
+		code codes.Code
+	}{
+		{transfer.ErrInvalidAccount(account.AccountNumber(dbAccountNumber1)), codes.NotFound},
+		{transfer.ErrNotEnoughMoney, codes.FailedPrecondition},
+		{transfer.ErrLimitExceeded(transfer.LimitDailyAmount, nil), codes.FailedPrecondition},
+		{transfer.ErrTransferAlreadyComplete, codes.AlreadyExists},
+		{transfer.ErrInvalidTransferDetails("memo is too long"), codes.InvalidArgument},
+		{servErr.ErrDatabaseError(errors.New("connection refused")), codes.Internal},
+	}
+
+	for _, c := range cases {
+		// Arrange
+		var client = setupGRPCClient(t, failingTransferService{err: c.err})
+
+		// Act
+		_, err := client.TransferMoney(context.Background(), &pb.TransferMoneyRequest{
+			Id:     uuid.NewString(),
+			Source: uint64(dbAccountNumber1),
+			Dest:   uint64(dbAccountNumber2),
+			Amount: 100,
+		})
+
+		// Assert
+		if status.Code(err) != c.code {
+			t.Fatalf("expected status code %s for error [%v], got %s", c.code, c.err, status.Code(err))
+		}
+	}
+}
+
+func Test_GRPCTransferMoney_InvalidId(t *testing.T) {
+	// Arrange
+	var client = setupGRPCClient(t, failingTransferService{})
+
+	// Act
+	_, err := client.TransferMoney(context.Background(), &pb.TransferMoneyRequest{Id: "not-a-guid", Source: 1, Dest: 2, Amount: 100})
+
+	// Assert
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected status code InvalidArgument, got %s", status.Code(err))
+	}
+}
@@ -0,0 +1,223 @@
+package transfer_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test/coins/account"
+	"test/coins/transfer"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// Sends request to transfer money and returns response status and problem
+func postTransfer(t *testing.T, body string) (int, servErr.Problem) {
+	var mr = mux.NewRouter()
+	transfer.RegisterHandlers(mr, failingTransferService{}, kitlog.NewNopLogger())
+
+	var recorder = httptest.NewRecorder()
+	mr.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(body)))
+
+	var problem servErr.Problem
+	if recorder.Code != http.StatusOK {
+		err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+		if err != nil {
+			t.Fatalf("unable to parse problem: %s", err.Error())
+		}
+	}
+
+	return recorder.Code, problem
+}
+
+func (svc failingTransferService) SetLimitTier(ctx context.Context, tier string, limits transfer.TransferLimits) error {
+	return svc.err
+}
+
+// Sends admin request that sets limits and returns response status and body
+func putLimits(t *testing.T, path string, body string) (int, []byte) {
+	var mr = mux.NewRouter()
+	transfer.RegisterAdminHandlers(mr, failingTransferService{}, kitlog.NewNopLogger())
+
+	var recorder = httptest.NewRecorder()
+	mr.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+// Returns names of invalid fields from validation problem
+func invalidFields(problem servErr.Problem) []string {
+	var result = []string{}
+	fields, _ := problem.Details["fields"].([]interface{})
+	for _, field := range fields {
+		result = append(result, field.(map[string]interface{})["field"].(string))
+	}
+
+	return result
+}
+
+func Test_EncodeError_ProblemWithStatusAndCode(t *testing.T) {
+	var resetsAt = time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	var cases = []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{transfer.ErrInvalidAccount(account.AccountNumber(dbAccountNumber1)), http.StatusNotFound, "account_not_found"},
+		{transfer.ErrTransferAlreadyComplete, http.StatusConflict, "duplicate_transfer"},
+		{transfer.ErrNotEnoughMoney, http.StatusUnprocessableEntity, "insufficient_funds"},
+		{transfer.ErrLimitExceeded(transfer.LimitDailyAmount, &resetsAt), http.StatusUnprocessableEntity, "limit_exceeded"},
+		{transfer.ErrLimitTierNotFound("premium"), http.StatusNotFound, "limit_tier_not_found"},
+		{servErr.ErrInvalidRequest("invalid account number", nil), http.StatusBadRequest, "invalid_request"},
+		{json.Unmarshal([]byte("{"), &struct{}{}), http.StatusBadRequest, "invalid_request"},
+		{servErr.ErrDatabaseError(errors.New("connection refused")), http.StatusInternalServerError, "database_error"},
+		{errors.New("unexpected error"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, c := range cases {
+		// Arrange
+		var recorder = httptest.NewRecorder()
+
+		// Act
+		servErr.EncodeError(context.Background(), c.err, recorder)
+
+		// Assert
+		var problem servErr.Problem
+		err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+		if err != nil {
+			t.Fatalf("unable to parse problem: %s", err.Error())
+		}
+
+		if recorder.Code != c.status || problem.Status != c.status || problem.Code != c.code {
+			t.Fatalf("expected status %d and code %s for error [%v], got %d and %s", c.status, c.code, c.err, recorder.Code, problem.Code)
+		}
+
+		if recorder.Header().Get("Content-Type") != servErr.ProblemContentType {
+			t.Fatalf("expected %s content type", servErr.ProblemContentType)
+		}
+	}
+}
+
+func Test_EncodeError_LimitDetailsReturned(t *testing.T) {
+	// Arrange
+	var resetsAt = time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	var recorder = httptest.NewRecorder()
+
+	// Act
+	servErr.EncodeError(context.Background(), transfer.ErrLimitExceeded(transfer.LimitDailyAmount, &resetsAt), recorder)
+
+	// Assert
+	var problem servErr.Problem
+	json.Unmarshal(recorder.Body.Bytes(), &problem)
+	if problem.Details["limit"] != transfer.LimitDailyAmount || problem.Details["resetsAt"] != "2026-10-20T00:00:00Z" {
+		t.Fatalf("limit name and reset time should be returned in problem details, got %v", problem.Details)
+	}
+}
+
+func Test_PostTransfer_ValidRequestAccepted(t *testing.T) {
+	// Act
+	status, _ := postTransfer(t, `{"id":"dc4214f0-6c39-4663-b43b-2ddcf72cee4e","source":1,"dest":2,"amount":100,"memo":"rent"}`)
+
+	// Assert
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+}
+
+func Test_PostTransfer_FieldErrorsReturned(t *testing.T) {
+	// Act
+	status, problem := postTransfer(t, `{"id":"00000000-0000-0000-0000-000000000000","source":1,"dest":1,"amount":0}`)
+
+	// Assert
+	if status != http.StatusUnprocessableEntity || problem.Code != "validation_failed" {
+		t.Fatalf("expected status 422 with validation_failed code, got %d and %s", status, problem.Code)
+	}
+
+	if strings.Join(invalidFields(problem), ",") != "id,dest,amount" {
+		t.Fatalf("expected id, dest and amount field errors, got %v", invalidFields(problem))
+	}
+}
+
+func Test_PostTransfer_AmountAboveMaxInt64Rejected(t *testing.T) {
+	// Act
+	status, problem := postTransfer(t, `{"id":"dc4214f0-6c39-4663-b43b-2ddcf72cee4e","source":1,"dest":2,"amount":9223372036854775808}`)
+
+	// Assert
+	if status != http.StatusUnprocessableEntity || strings.Join(invalidFields(problem), ",") != "amount" {
+		t.Fatalf("expected amount field error, got %d and %v", status, invalidFields(problem))
+	}
+}
+
+func Test_PostTransfer_UnknownFieldRejected(t *testing.T) {
+	// Act
+	status, problem := postTransfer(t, `{"id":"dc4214f0-6c39-4663-b43b-2ddcf72cee4e","source":1,"dest":2,"amount":100,"ammount":100}`)
+
+	// Assert
+	if status != http.StatusBadRequest || problem.Code != "invalid_request" {
+		t.Fatalf("expected status 400 with invalid_request code, got %d and %s", status, problem.Code)
+	}
+}
+
+func Test_PostTransfer_LargeBodyRejected(t *testing.T) {
+	// Act
+	var memo = strings.Repeat("a", int(transfer.MaxRequestBodySize))
+	status, problem := postTransfer(t, `{"id":"dc4214f0-6c39-4663-b43b-2ddcf72cee4e","source":1,"dest":2,"amount":100,"memo":"`+memo+`"}`)
+
+	// Assert
+	if status != http.StatusRequestEntityTooLarge || problem.Code != "request_too_large" {
+		t.Fatalf("expected status 413 with request_too_large code, got %d and %s", status, problem.Code)
+	}
+}
+
+func Test_PutLimitTier_ValidRequest_LimitsReturned(t *testing.T) {
+	// Act
+	status, body := putLimits(t, "/api/v1/admin/limit-tiers/basic", `{"maxTransferAmount":1000,"maxDailyCount":null}`)
+
+	// Assert
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	var expected = `{"limits":{"maxTransferAmount":1000,"maxDailyAmount":null,"maxMonthlyAmount":null,"maxDailyCount":null}}`
+	if strings.TrimSpace(string(body)) != expected {
+		t.Fatalf("expected response %s, got %s", expected, string(body))
+	}
+}
+
+func Test_PutLimits_InvalidLimits_FieldErrorsReturned(t *testing.T) {
+	var cases = []struct {
+		path   string
+		body   string
+		fields string
+	}{
+		{"/api/v1/admin/limit-tiers/basic", `{"maxTransferAmount":-1,"maxDailyCount":-5}`, "maxTransferAmount,maxDailyCount"},
+		{"/api/v1/admin/limit-tiers/" + strings.Repeat("t", transfer.MaxLimitTierLength+1), `{}`, "tier"},
+		{"/api/v1/admin/accounts/1/limits", `{"tier":"basic","maxMonthlyAmount":-100}`, "maxMonthlyAmount"},
+	}
+
+	for _, c := range cases {
+		// Act
+		status, body := putLimits(t, c.path, c.body)
+
+		// Assert
+		var problem servErr.Problem
+		if err := json.Unmarshal(body, &problem); err != nil {
+			t.Fatalf("unable to parse problem: %s", err.Error())
+		}
+
+		if status != http.StatusUnprocessableEntity || problem.Code != "validation_failed" {
+			t.Fatalf("expected status 422 with validation_failed code for %s, got %d and %s", c.body, status, problem.Code)
+		}
+
+		if strings.Join(invalidFields(problem), ",") != c.fields {
+			t.Fatalf("expected %s field errors, got %v", c.fields, invalidFields(problem))
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Max amount of single transfer. Balances are stored as bigint, so amount should fit into int64
+const MaxAmount uint64 = math.MaxInt64
+
+// Max size of transfer request body in bytes
+const MaxRequestBodySize int64 = 16 * 1024
+
+// Decodes JSON request body. Body should not be larger than MaxRequestBodySize,
+// contain unknown fields or anything after JSON object
+//	r    - http request
+//	body - pointer to value body is decoded to
+func DecodeStrictJSON(r *http.Request, body interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxRequestBodySize+1))
+	if err != nil {
+		return servErr.ErrInvalidRequest("unable to read request body", err)
+	}
+
+	if int64(len(data)) > MaxRequestBodySize {
+		return servErr.ErrRequestTooLarge(MaxRequestBodySize)
+	}
+
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(body)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return servErr.ErrValidation([]servErr.FieldError{{Field: typeErr.Field, Message: "has invalid type or value"}})
+	}
+
+	if err != nil {
+		return servErr.ErrInvalidRequest("invalid request body", err)
+	}
+
+	if decoder.More() {
+		return servErr.ErrInvalidRequest("invalid request body", errors.New("unexpected data after JSON object"))
+	}
+
+	return nil
+}
+
+// Validates money transfer request
+// Returns validation error with all invalid fields, nil if request is valid
+func (req sendPaymentRequest) validate() error {
+	var fields = []servErr.FieldError{}
+	fields = validateId(fields, req.Id)
+
+	if req.Source == 0 {
+		fields = append(fields, servErr.FieldError{Field: "source", Message: "is required"})
+	}
+
+	if req.Dest == 0 {
+		fields = append(fields, servErr.FieldError{Field: "dest", Message: "is required"})
+	} else if req.Source == req.Dest {
+		fields = append(fields, servErr.FieldError{Field: "dest", Message: "should differ from source"})
+	}
+
+	fields = validateAmount(fields, req.Amount)
+	if len(fields) > 0 {
+		return servErr.ErrValidation(fields)
+	}
+
+	return nil
+}
+
+// Validates deposit or withdrawal request
+// Returns validation error with all invalid fields, nil if request is valid
+func (req fundingRequest) validate() error {
+	var fields = []servErr.FieldError{}
+	fields = validateId(fields, req.Id)
+
+	if req.Account == 0 {
+		fields = append(fields, servErr.FieldError{Field: "account", Message: "is required"})
+	}
+
+	fields = validateAmount(fields, req.Amount)
+	if len(fields) > 0 {
+		return servErr.ErrValidation(fields)
+	}
+
+	return nil
+}
+
+// Validates limit tier request
+// Returns validation error with all invalid fields, nil if request is valid
+func (req setLimitTierRequest) validate() error {
+	var fields = []servErr.FieldError{}
+	fields = validateTier(fields, req.Tier, true)
+	fields = validateLimits(fields, req.TransferLimits)
+	if len(fields) > 0 {
+		return servErr.ErrValidation(fields)
+	}
+
+	return nil
+}
+
+// Validates account limits request
+// Returns validation error with all invalid fields, nil if request is valid
+func (req setAccountLimitsRequest) validate() error {
+	var fields = []servErr.FieldError{}
+	fields = validateTier(fields, req.Tier, false)
+	fields = validateLimits(fields, req.TransferLimits)
+	if len(fields) > 0 {
+		return servErr.ErrValidation(fields)
+	}
+
+	return nil
+}
+
+// Appends error of tier field, if any
+//	fields   - errors of other fields
+//	tier     - tier name
+//	required - tier name should not be empty
+func validateTier(fields []servErr.FieldError, tier string, required bool) []servErr.FieldError {
+	if required && tier == "" {
+		return append(fields, servErr.FieldError{Field: "tier", Message: "is required"})
+	}
+
+	if len(tier) > MaxLimitTierLength {
+		return append(fields, servErr.FieldError{Field: "tier", Message: fmt.Sprintf("should not be longer than %d characters", MaxLimitTierLength)})
+	}
+
+	return fields
+}
+
+// Appends errors of limit fields, limits should not be negative
+func validateLimits(fields []servErr.FieldError, limits TransferLimits) []servErr.FieldError {
+	var values = []struct {
+		field string
+		value *int64
+	}{
+		{"maxTransferAmount", limits.MaxTransferAmount},
+		{"maxDailyAmount", limits.MaxDailyAmount},
+		{"maxMonthlyAmount", limits.MaxMonthlyAmount},
+		{"maxDailyCount", limits.MaxDailyCount},
+	}
+
+	for _, v := range values {
+		if v.value != nil && *v.value < 0 {
+			fields = append(fields, servErr.FieldError{Field: v.field, Message: "should not be negative"})
+		}
+	}
+
+	return fields
+}
+
+// Appends error of transfer id field, if any
+func validateId(fields []servErr.FieldError, id uuid.UUID) []servErr.FieldError {
+	if id == uuid.Nil {
+		return append(fields, servErr.FieldError{Field: "id", Message: "is required and should not be nil GUID"})
+	}
+
+	return fields
+}
+
+// Appends error of amount field, if any
+func validateAmount(fields []servErr.FieldError, amount uint64) []servErr.FieldError {
+	if amount == 0 {
+		return append(fields, servErr.FieldError{Field: "amount", Message: "should be greater than 0"})
+	}
+
+	if amount > MaxAmount {
+		return append(fields, servErr.FieldError{Field: "amount", Message: "should not be greater than 9223372036854775807"})
+	}
+
+	return fields
+}
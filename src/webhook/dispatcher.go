@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"test/coins/transfer"
+	"time"
+)
+
+// Dispatcher receives events from outbox relay and creates deliveries
+// for subscriptions of accounts affected by event. It implements outbox.Publisher
+type Dispatcher struct {
+	dbContextFactory func() (db.DbContext, error)
+}
+
+// Creates new webhook dispatcher
+//	dbContextFactory - factory function used to create new db context
+func NewDispatcher(dbContextFactory func() (db.DbContext, error)) *Dispatcher {
+	return &Dispatcher{dbContextFactory}
+}
+
+func (dispatcher *Dispatcher) Publish(event outbox.Event) error {
+	if event.Type != transfer.EventTransferCompleted {
+		return nil
+	}
+
+	var completed transfer.TransferCompletedEvent
+	err := json.Unmarshal(event.Payload, &completed)
+	if err != nil {
+		return err
+	}
+
+	dbContext, err := dispatcher.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	err = enqueueDeliveries(dbContext, event.Id, EventTransferSent, completed.Source, completed)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueDeliveries(dbContext, event.Id, EventTransferReceived, completed.Dest, completed)
+	if err != nil {
+		return err
+	}
+
+	return dbContext.Save()
+}
+
+// Creates deliveries of event for all active subscriptions of account to event type.
+// Relay delivers events at least once, so duplicate deliveries are ignored.
+// Deliveries are due immediately, time is passed in UTC as it is compared by sender
+//	dbContext  - db context
+//	eventId    - outbox event id
+//	eventType  - webhook event type
+//	accountNum - account number
+//	completed  - completed transfer
+func enqueueDeliveries(dbContext db.DbContext, eventId int64, eventType string, accountNum account.AccountNumber, completed transfer.TransferCompletedEvent) error {
+	payload, err := json.Marshal(EventPayload{
+		EventId:  eventId,
+		Type:     eventType,
+		Account:  accountNum,
+		Transfer: completed,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = dbContext.Execute(
+		"INSERT INTO public.webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at) "+
+			"SELECT subscription_id, $1, $2, $3, $4, $7 FROM public.webhook_subscriptions "+
+			"WHERE account_number = $5 AND active AND ',' || event_types || ',' LIKE $6 "+
+			"ON CONFLICT (subscription_id, event_id, event_type) DO NOTHING",
+		eventId, eventType, string(payload), DeliveryPending, int64(uint64(accountNum)), "%,"+eventType+",%", time.Now().UTC(),
+	)
+
+	return err
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"test/coins/account"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type subscribeRequest struct {
+	AccountNumber uint64   `json:"-"`
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret"`
+	EventTypes    []string `json:"eventTypes"`
+}
+
+type subscriptionResponse struct {
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Error        error         `json:"error,omitempty"`
+}
+
+func (r subscriptionResponse) error() error { return r.Error }
+
+func makeSubscribeEndpoint(svc WebhookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(subscribeRequest)
+		subscription, err := svc.Subscribe(account.AccountNumber(req.AccountNumber), req.URL, req.Secret, req.EventTypes)
+		return subscriptionResponse{subscription, err}, nil
+	}
+}
+
+type listSubscriptionsRequest struct {
+	AccountNumber uint64
+}
+
+type listSubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+	Error         error          `json:"error,omitempty"`
+}
+
+func (r listSubscriptionsResponse) error() error { return r.Error }
+
+func makeListSubscriptionsEndpoint(svc WebhookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listSubscriptionsRequest)
+		subscriptions, err := svc.ListSubscriptions(account.AccountNumber(req.AccountNumber))
+		return listSubscriptionsResponse{subscriptions, err}, nil
+	}
+}
+
+type subscriptionIdRequest struct {
+	Id SubscriptionId
+}
+
+type unsubscribeResponse struct {
+	Error error `json:"error,omitempty"`
+}
+
+func (r unsubscribeResponse) error() error { return r.Error }
+
+func makeUnsubscribeEndpoint(svc WebhookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(subscriptionIdRequest)
+		err := svc.Unsubscribe(req.Id)
+		return unsubscribeResponse{err}, nil
+	}
+}
+
+type listDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	Error      error      `json:"error,omitempty"`
+}
+
+func (r listDeliveriesResponse) error() error { return r.Error }
+
+func makeListDeliveriesEndpoint(svc WebhookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(subscriptionIdRequest)
+		deliveries, err := svc.ListDeliveries(req.Id)
+		return listDeliveriesResponse{deliveries, err}, nil
+	}
+}
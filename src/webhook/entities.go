@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"encoding/json"
+	"test/coins/account"
+	"test/coins/transfer"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionId uuid.UUID
+
+// Returns string representation of subscription id
+func (id SubscriptionId) String() string {
+	return uuid.UUID(id).String()
+}
+
+// Needed to support proper serialization to JSON
+func (id SubscriptionId) MarshalJSON() ([]byte, error) {
+	var guid = uuid.UUID(id)
+	var str = guid.String()
+
+	return []byte("\"" + str + "\""), nil
+}
+
+// Needed to support proper deserialization from JSON
+func (id *SubscriptionId) UnmarshalJSON(data []byte) error {
+	guid, err := uuid.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	*id = SubscriptionId(guid)
+	return nil
+}
+
+// Event that is sent when account sends money (outgoing transfer or withdrawal)
+const EventTransferSent = "transfer.sent"
+
+// Event that is sent when account receives money (incoming transfer or deposit)
+const EventTransferReceived = "transfer.received"
+
+const DeliveryPending = "pending"
+const DeliverySucceeded = "succeeded"
+const DeliveryDead = "dead"
+
+type Subscription struct {
+	// Subscription id
+	Id SubscriptionId `json:"id"`
+
+	// Account number which events are sent
+	Account account.AccountNumber `json:"account"`
+
+	// URL where events are sent
+	URL string `json:"url"`
+
+	// Types of events that are sent
+	EventTypes []string `json:"eventTypes"`
+
+	// Subscription created timestamp
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Attempt to send event to subscription URL
+type Delivery struct {
+	// Delivery id, sent in X-Webhook-Id header
+	Id int64 `json:"id"`
+
+	// Subscription id
+	SubscriptionId SubscriptionId `json:"subscriptionId"`
+
+	// Event type
+	EventType string `json:"eventType"`
+
+	// Request body sent to subscription URL
+	Payload json.RawMessage `json:"payload"`
+
+	// Delivery status. Can have values "pending", "succeeded" or "dead"
+	Status string `json:"status"`
+
+	// Number of made attempts
+	Attempts int `json:"attempts"`
+
+	// Time of next attempt, if delivery is pending
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+
+	// HTTP status code returned by last attempt, 0 if request failed
+	LastStatusCode int `json:"lastStatusCode,omitempty"`
+
+	// Error of last attempt
+	LastError string `json:"lastError,omitempty"`
+
+	// Delivery created timestamp
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Request body sent to subscription URL
+type EventPayload struct {
+	// Id of outbox event that caused delivery
+	EventId int64 `json:"eventId"`
+
+	// Event type
+	Type string `json:"type"`
+
+	// Account number
+	Account account.AccountNumber `json:"account"`
+
+	// Completed transfer
+	Transfer transfer.TransferCompletedEvent `json:"transfer"`
+}
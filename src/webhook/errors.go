@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	servErr "test/coins/errors"
+)
+
+const (
+	ErrKindInvalidSubscription int = 40 + iota
+	ErrKindSubscriptionNotFound
+)
+
+// Registers error kinds of package in error catalog
+func init() {
+	servErr.RegisterKind(ErrKindInvalidSubscription, servErr.ErrorDefinition{Code: "invalid_subscription", Status: http.StatusBadRequest, Title: "Invalid webhook subscription"})
+	servErr.RegisterKind(ErrKindSubscriptionNotFound, servErr.ErrorDefinition{Code: "subscription_not_found", Status: http.StatusNotFound, Title: "Webhook subscription not found"})
+}
+
+// Creates new "Invalid subscription" error
+//	reason - reason why subscription is invalid
+// Returns created error
+func ErrInvalidSubscription(reason string) error {
+	return servErr.NewServiceError("invalid webhook subscription: "+reason, nil, ErrKindInvalidSubscription)
+}
+
+// Creates new "Subscription not found" error
+//	id - subscription id
+// Returns created error
+func ErrSubscriptionNotFound(id SubscriptionId) error {
+	var msg = fmt.Sprintf("webhook subscription with id [%s] not found", id.String())
+	return servErr.NewServiceError(msg, nil, ErrKindSubscriptionNotFound)
+}
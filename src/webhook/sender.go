@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"test/coins/db"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	servErr "test/coins/errors"
+)
+
+// Time claimed delivery is not picked up by other senders, if http client has no timeout
+const defaultRequestLease = time.Minute
+
+// Policy of delivery retries. Delay before attempt N is BaseDelay * 2^(N-2), but not greater than MaxDelay
+type RetryPolicy struct {
+	// Max number of attempts, after that delivery is moved to dead state
+	MaxAttempts int
+
+	// Delay before second attempt
+	BaseDelay time.Duration
+
+	// Max delay between attempts
+	MaxDelay time.Duration
+}
+
+// Retry policy with 8 attempts over ~40 minutes
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    30 * time.Minute,
+}
+
+// Returns delay before next attempt
+//	attempts - number of made attempts
+func (policy RetryPolicy) delay(attempts int) time.Duration {
+	var delay = policy.BaseDelay
+	for i := 1; i < attempts && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	return delay
+}
+
+// Sender sends pending deliveries to subscription URLs
+type Sender struct {
+	dbContextFactory func() (db.DbContext, error)
+
+	client *http.Client
+
+	retryPolicy RetryPolicy
+
+	interval time.Duration
+
+	batchSize int
+
+	logger kitlog.Logger
+}
+
+// Creates new webhook sender
+//	dbContextFactory - factory function used to create new db context
+//	client           - http client used to send requests, should have timeout set
+//	retryPolicy      - policy of delivery retries
+//	interval         - interval between checks for pending deliveries
+//	batchSize        - max number of deliveries sent in single transaction
+//	logger           - logger
+func NewSender(dbContextFactory func() (db.DbContext, error), client *http.Client, retryPolicy RetryPolicy, interval time.Duration, batchSize int, logger kitlog.Logger) *Sender {
+	return &Sender{dbContextFactory, client, retryPolicy, interval, batchSize, logger}
+}
+
+// Runs sender until context is cancelled
+//	ctx - context used to stop sender
+func (sender *Sender) Run(ctx context.Context) {
+	var ticker = time.NewTicker(sender.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				sent, err := sender.SendBatch(ctx)
+				if err != nil {
+					level.Error(sender.logger).Log("msg", "failed to send webhook deliveries", "err", err)
+					break
+				}
+
+				if sent < sender.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Delivery prepared to be sent
+type pendingDelivery struct {
+	id        int64
+	eventType string
+	payload   []byte
+	attempts  int
+	url       string
+	secret    string
+}
+
+// Sends single batch of due pending deliveries
+//	ctx - context of http requests
+// Returns number of deliveries that were attempted
+func (sender *Sender) SendBatch(ctx context.Context) (int, error) {
+	deliveries, err := sender.claimDeliveries()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		statusCode, sendErr := sender.send(ctx, delivery)
+
+		err = sender.recordAttempt(delivery, statusCode, sendErr)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// Selects due pending deliveries and postpones their next attempt, so other senders don't pick them up
+// while requests are sent. If sender stops before attempt is recorded, delivery is sent again after lease expires.
+// Requests are sent outside of transaction, because they can take longer than transaction timeout
+// Returns claimed deliveries
+func (sender *Sender) claimDeliveries() ([]pendingDelivery, error) {
+	dbContext, err := sender.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var now = time.Now().UTC()
+	var deliveries = []pendingDelivery{}
+	err = dbContext.Query(
+		"SELECT d.id, d.event_type, d.payload, d.attempts, s.url, s.secret FROM public.webhook_deliveries d "+
+			"JOIN public.webhook_subscriptions s ON s.subscription_id = d.subscription_id "+
+			"WHERE d.status = $1 AND d.next_attempt_at <= $2 AND s.active "+
+			"ORDER BY d.id LIMIT $3 FOR UPDATE OF d SKIP LOCKED",
+		sqlParams{DeliveryPending, now, sender.batchSize},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var delivery pendingDelivery
+				err := rows.Scan(&delivery.id, &delivery.eventType, &delivery.payload, &delivery.attempts, &delivery.url, &delivery.secret)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				deliveries = append(deliveries, delivery)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	var ids = make([]interface{}, len(deliveries))
+	var placeholders = make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.id
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+	}
+
+	// Deliveries are sent one by one, so lease should be enough to send all of them
+	var requestTimeout = sender.client.Timeout
+	if requestTimeout == 0 {
+		requestTimeout = defaultRequestLease
+	}
+	var leaseUntil = now.Add(time.Duration(len(deliveries))*requestTimeout + defaultRequestLease)
+
+	_, err = dbContext.Execute(
+		"UPDATE public.webhook_deliveries SET next_attempt_at = $1 WHERE id IN ("+strings.Join(placeholders, ", ")+")",
+		append([]interface{}{leaseUntil}, ids...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbContext.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Records result of delivery attempt
+//	delivery   - sent delivery
+//	statusCode - response status code, 0 if request failed
+//	sendErr    - error of attempt, nil if delivery succeeded
+func (sender *Sender) recordAttempt(delivery pendingDelivery, statusCode int, sendErr error) error {
+	var status = DeliverySucceeded
+	var now = time.Now().UTC()
+	var nextAttemptAt = now
+	var lastError interface{} = nil
+	var attempts = delivery.attempts + 1
+	if sendErr != nil {
+		lastError = sendErr.Error()
+		if attempts >= sender.retryPolicy.MaxAttempts {
+			status = DeliveryDead
+		} else {
+			status = DeliveryPending
+			nextAttemptAt = nextAttemptAt.Add(sender.retryPolicy.delay(attempts))
+		}
+	}
+
+	dbContext, err := sender.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	_, err = dbContext.Execute(
+		"UPDATE public.webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, "+
+			"last_status_code = $4, last_error = $5, updated_at = $6 WHERE id = $7",
+		status, attempts, nextAttemptAt, statusCode, lastError, now, delivery.id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return dbContext.Save()
+}
+
+// Sends signed delivery request
+//	ctx      - request context
+//	delivery - delivery to send
+// Returns response status code (0 if request failed) and error if delivery was not accepted
+func (sender *Sender) send(ctx context.Context, delivery pendingDelivery) (int, error) {
+	var timestamp = time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(HeaderDeliveryId, strconv.FormatInt(delivery.id, 10))
+	req.Header.Set(HeaderEvent, delivery.eventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.secret, timestamp, delivery.payload))
+
+	resp, err := sender.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Draining body, so connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"test/coins/account"
+	"test/coins/db"
+	"time"
+
+	"github.com/google/uuid"
+
+	servErr "test/coins/errors"
+)
+
+// Type alias for sql parameters array
+type sqlParams = []interface{}
+
+// Min length of subscription secret
+const MinSecretLength = 16
+
+const deliveryColumns = "id, subscription_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at"
+
+var errQueryReturnedNoData = errors.New("no data returned from database request")
+
+// Webhook service. Incapsulates operations with webhook subscriptions
+type WebhookService interface {
+	// Registers URL where account events are sent
+	//	accountNum - account number
+	//	url        - URL where events are sent, should be http or https URL
+	//	secret     - secret used to sign requests
+	//	eventTypes - types of events to send
+	// Returns created subscription
+	Subscribe(accountNum account.AccountNumber, url string, secret string, eventTypes []string) (*Subscription, error)
+
+	// Returns list of active subscriptions of account
+	//	accountNum - account number
+	ListSubscriptions(accountNum account.AccountNumber) ([]Subscription, error)
+
+	// Deactivates subscription. Pending deliveries of subscription are not sent
+	//	id - subscription id
+	Unsubscribe(id SubscriptionId) error
+
+	// Returns delivery log of subscription, latest deliveries first
+	//	id - subscription id
+	ListDeliveries(id SubscriptionId) ([]Delivery, error)
+}
+
+// Webhook service implementation
+type webhookService struct {
+	dbContextFactory func() (db.DbContext, error)
+}
+
+// Creates new webhook service
+//	dbContextFactory - factory function used to create new db context
+func NewWebhookService(dbContextFactory func() (db.DbContext, error)) WebhookService {
+	return webhookService{dbContextFactory}
+}
+
+func (svc webhookService) Subscribe(accountNum account.AccountNumber, subscriptionUrl string, secret string, eventTypes []string) (*Subscription, error) {
+	parsedUrl, err := url.Parse(subscriptionUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return nil, ErrInvalidSubscription("url should be valid http or https URL")
+	}
+
+	if len(secret) < MinSecretLength {
+		return nil, ErrInvalidSubscription("secret should be at least 16 characters long")
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidSubscription("at least one event type is required")
+	}
+
+	for _, eventType := range eventTypes {
+		if eventType != EventTransferSent && eventType != EventTransferReceived {
+			return nil, ErrInvalidSubscription("unknown event type [" + eventType + "]")
+		}
+	}
+
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var count int64 = 0
+	err = dbContext.Query(
+		"SELECT COUNT(*) FROM public.accounts WHERE account_number = $1 AND account_type = $2",
+		sqlParams{int64(uint64(accountNum)), account.AccountTypeCustomer},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&count)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, ErrInvalidSubscription("account not found")
+	}
+
+	var subscription = Subscription{
+		Id:         SubscriptionId(uuid.New()),
+		Account:    accountNum,
+		URL:        subscriptionUrl,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	_, err = dbContext.Execute(
+		"INSERT INTO public.webhook_subscriptions (subscription_id, account_number, url, secret, event_types, created_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6)",
+		uuid.UUID(subscription.Id), int64(uint64(accountNum)), subscriptionUrl, secret,
+		strings.Join(eventTypes, ","), subscription.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbContext.Save()
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+func (svc webhookService) ListSubscriptions(accountNum account.AccountNumber) ([]Subscription, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var result = []Subscription{}
+	err = dbContext.Query(
+		"SELECT subscription_id, account_number, url, event_types, created_at FROM public.webhook_subscriptions "+
+			"WHERE account_number = $1 AND active ORDER BY created_at DESC",
+		sqlParams{int64(uint64(accountNum))},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				var (
+					id         uuid.UUID
+					accNum     int64
+					eventTypes string
+					sub        Subscription
+				)
+
+				err := rows.Scan(&id, &accNum, &sub.URL, &eventTypes, &sub.CreatedAt)
+				if err != nil {
+					return servErr.ErrDatabaseError(err)
+				}
+
+				sub.Id = SubscriptionId(id)
+				sub.Account = account.AccountNumber(uint64(accNum))
+				sub.EventTypes = strings.Split(eventTypes, ",")
+				result = append(result, sub)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (svc webhookService) Unsubscribe(id SubscriptionId) error {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return err
+	}
+	defer dbContext.Release()
+
+	rowsAffected, err := dbContext.Execute(
+		"UPDATE public.webhook_subscriptions SET active = false WHERE subscription_id = $1 AND active",
+		uuid.UUID(id),
+	)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSubscriptionNotFound(id)
+	}
+
+	return dbContext.Save()
+}
+
+func (svc webhookService) ListDeliveries(id SubscriptionId) ([]Delivery, error) {
+	dbContext, err := svc.dbContextFactory()
+	if err != nil {
+		return nil, err
+	}
+	defer dbContext.Release()
+
+	var count int64 = 0
+	err = dbContext.Query(
+		"SELECT COUNT(*) FROM public.webhook_subscriptions WHERE subscription_id = $1",
+		sqlParams{uuid.UUID(id)},
+		func(rows db.QueryResultRows) error {
+			if !rows.Next() {
+				return servErr.ErrDatabaseError(errQueryReturnedNoData)
+			}
+
+			err := rows.Scan(&count)
+			if err != nil {
+				return servErr.ErrDatabaseError(err)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, ErrSubscriptionNotFound(id)
+	}
+
+	var result = []Delivery{}
+	err = dbContext.Query(
+		"SELECT "+deliveryColumns+" FROM public.webhook_deliveries WHERE subscription_id = $1 ORDER BY id DESC",
+		sqlParams{uuid.UUID(id)},
+		func(rows db.QueryResultRows) error {
+			for rows.Next() {
+				delivery, err := scanDelivery(rows)
+				if err != nil {
+					return err
+				}
+
+				result = append(result, delivery)
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Reads delivery from current row. Row should contain columns listed in deliveryColumns
+func scanDelivery(rows db.QueryResultRows) (Delivery, error) {
+	var (
+		delivery   Delivery
+		subId      uuid.UUID
+		payload    []byte
+		statusCode *int64
+		lastError  *string
+	)
+
+	err := rows.Scan(&delivery.Id, &subId, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &statusCode, &lastError, &delivery.CreatedAt)
+	if err != nil {
+		return delivery, servErr.ErrDatabaseError(err)
+	}
+
+	delivery.SubscriptionId = SubscriptionId(subId)
+	delivery.Payload = payload
+	if statusCode != nil {
+		delivery.LastStatusCode = int(*statusCode)
+	}
+
+	if lastError != nil {
+		delivery.LastError = *lastError
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,299 @@
+package webhook_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"test/coins/account"
+	"test/coins/db"
+	"test/coins/outbox"
+	"test/coins/transfer"
+	"test/coins/webhook"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+const (
+	dbAccountNumber1 int64 = 1
+	dbAccountNumber2 int64 = 2
+
+	testSecret = "0123456789abcdef"
+)
+
+var pendingColumns = []string{"id", "event_type", "payload", "attempts", "url", "secret"}
+
+var testRetryPolicy = webhook.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Minute,
+	MaxDelay:    time.Hour,
+}
+
+// Matches UTC time that is after now plus delay, with some tolerance for test execution time
+type delayedTime struct {
+	delay time.Duration
+}
+
+func (a delayedTime) Match(v driver.Value) bool {
+	value, ok := v.(time.Time)
+	if !ok || value.Location() != time.UTC {
+		return false
+	}
+
+	var expected = time.Now().UTC().Add(a.delay)
+	return value.After(expected.Add(-time.Minute/2)) && value.Before(expected.Add(time.Minute/2))
+}
+
+func setupService(setupMock func(mock sqlmock.Sqlmock)) webhook.WebhookService {
+	return webhook.NewWebhookService(func() (db.DbContext, error) {
+		return db.CreateMockDbContext(setupMock)
+	})
+}
+
+// Creates sender, each created db context is set up by next setup function
+func setupSender(setupMocks ...func(mock sqlmock.Sqlmock)) *webhook.Sender {
+	var created = 0
+	return webhook.NewSender(func() (db.DbContext, error) {
+		var setupMock = setupMocks[created]
+		created++
+		return db.CreateMockDbContext(setupMock)
+	}, &http.Client{Timeout: time.Second * 5}, testRetryPolicy, time.Second, 10, kitlog.NewNopLogger())
+}
+
+// Returns mock setup for claiming single pending delivery
+func claimDelivery(dbMock *sqlmock.Sqlmock, id int64, payload []byte, attempts int, url string) func(mock sqlmock.Sqlmock) {
+	return func(mock sqlmock.Sqlmock) {
+		*dbMock = mock
+		mock.ExpectBegin()
+
+		var rows = sqlmock.NewRows(pendingColumns).AddRow(id, webhook.EventTransferSent, payload, attempts, url, testSecret)
+		mock.ExpectQuery("SELECT .* FROM public.webhook_deliveries .* FOR UPDATE OF d SKIP LOCKED").WillReturnRows(rows)
+
+		mock.ExpectExec("UPDATE public.webhook_deliveries SET next_attempt_at = \\$1 WHERE id IN \\(\\$2\\)").
+			WithArgs(sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	}
+}
+
+func valdiateServiceError(expectedKind int, actual error, method string) (bool, string) {
+	if actual == nil {
+		return false, fmt.Sprintf("error expected to be returned by method %s", method)
+	}
+
+	err, ok := actual.(servErr.ServiceError)
+	if !ok {
+		return false, "expected error to be of type ServiceError"
+	}
+
+	if err.Kind() != expectedKind {
+		return false, fmt.Sprintf("expected error with kind %d, got %d", expectedKind, err.Kind())
+	}
+
+	return true, ""
+}
+
+func Test_Subscribe_InvalidUrl(t *testing.T) {
+	// Arrange
+	var service = setupService(func(mock sqlmock.Sqlmock) {
+		t.Fatalf("db context should not be created for invalid subscription")
+	})
+
+	// Act
+	result, err := service.Subscribe(account.AccountNumber(dbAccountNumber1), "ftp://example.com/hook", testSecret, []string{webhook.EventTransferSent})
+
+	// Assert
+	isValid, msg := valdiateServiceError(webhook.ErrKindInvalidSubscription, err, "Subscribe(...)")
+	if !isValid {
+		t.Fatalf(msg)
+	}
+
+	if result != nil {
+		t.Fatalf("in case of any error, Subscribe() should return (nil, error) as result")
+	}
+}
+
+func Test_Dispatcher_DeliveriesCreatedForBothAccounts(t *testing.T) {
+	// Arrange
+	var completed = transfer.TransferCompletedEvent{
+		Id:     transfer.TransferId(uuid.New()),
+		Type:   transfer.TransferTypeTransfer,
+		Source: account.AccountNumber(dbAccountNumber1),
+		Dest:   account.AccountNumber(dbAccountNumber2),
+		Amount: 100,
+	}
+	payload, _ := json.Marshal(completed)
+
+	var dbMock sqlmock.Sqlmock = nil
+	var dispatcher = webhook.NewDispatcher(func() (db.DbContext, error) {
+		return db.CreateMockDbContext(func(mock sqlmock.Sqlmock) {
+			dbMock = mock
+			mock.ExpectBegin()
+
+			mock.ExpectExec("INSERT INTO public.webhook_deliveries .* ON CONFLICT").
+				WithArgs(int64(42), webhook.EventTransferSent, sqlmock.AnyArg(), webhook.DeliveryPending, dbAccountNumber1, "%,transfer.sent,%", delayedTime{0}).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			mock.ExpectExec("INSERT INTO public.webhook_deliveries .* ON CONFLICT").
+				WithArgs(int64(42), webhook.EventTransferReceived, sqlmock.AnyArg(), webhook.DeliveryPending, dbAccountNumber2, "%,transfer.received,%", delayedTime{0}).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			mock.ExpectCommit()
+		})
+	})
+
+	// Act
+	err := dispatcher.Publish(outbox.Event{Id: 42, Type: transfer.EventTransferCompleted, Payload: payload})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when Publish() was called: %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SendBatch_SignedDeliverySucceeded(t *testing.T) {
+	// Arrange
+	var body = []byte(`{"eventId":42,"type":"transfer.sent"}`)
+	var verified = false
+	var receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		verified = webhook.VerifySignature(testSecret, timestamp, received, r.Header.Get(webhook.HeaderSignature)) &&
+			r.Header.Get(webhook.HeaderDeliveryId) == "7" &&
+			r.Header.Get(webhook.HeaderEvent) == webhook.EventTransferSent
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	var claimMock, dbMock sqlmock.Sqlmock = nil, nil
+	var sender = setupSender(claimDelivery(&claimMock, 7, body, 0, receiver.URL), func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectExec("UPDATE public.webhook_deliveries").
+			WithArgs(webhook.DeliverySucceeded, 1, sqlmock.AnyArg(), http.StatusNoContent, nil, delayedTime{0}, int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	sent, err := sender.SendBatch(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when SendBatch() was called: %s", err.Error())
+	}
+
+	if sent != 1 {
+		t.Fatalf("expected 1 sent delivery, got %d", sent)
+	}
+
+	if !verified {
+		t.Fatalf("receiver should get request with valid signature and headers")
+	}
+
+	err = claimMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SendBatch_FailedDeliveryRetriedWithBackoff(t *testing.T) {
+	// Arrange
+	var receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	// Second attempt failed, third attempt should be made after doubled delay
+	var claimMock, dbMock sqlmock.Sqlmock = nil, nil
+	var sender = setupSender(claimDelivery(&claimMock, 7, []byte(`{}`), 1, receiver.URL), func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectExec("UPDATE public.webhook_deliveries").
+			WithArgs(webhook.DeliveryPending, 2, delayedTime{2 * time.Minute}, http.StatusInternalServerError, sqlmock.AnyArg(), delayedTime{0}, int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	_, err := sender.SendBatch(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when SendBatch() was called: %s", err.Error())
+	}
+
+	err = claimMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
+
+func Test_SendBatch_DeliveryDeadAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	var receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	var claimMock, dbMock sqlmock.Sqlmock = nil, nil
+	var sender = setupSender(claimDelivery(&claimMock, 7, []byte(`{}`), 2, receiver.URL), func(mock sqlmock.Sqlmock) {
+		dbMock = mock
+		mock.ExpectBegin()
+
+		mock.ExpectExec("UPDATE public.webhook_deliveries").
+			WithArgs(webhook.DeliveryDead, 3, sqlmock.AnyArg(), http.StatusBadGateway, sqlmock.AnyArg(), delayedTime{0}, int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+	})
+
+	// Act
+	_, err := sender.SendBatch(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error occured when SendBatch() was called: %s", err.Error())
+	}
+
+	err = claimMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+
+	err = dbMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatalf("db methods call expectations were not met: %s", err.Error())
+	}
+}
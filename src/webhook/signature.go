@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Header with HMAC-SHA256 signature of request
+const HeaderSignature = "X-Webhook-Signature"
+
+// Header with unix time when request was signed
+const HeaderTimestamp = "X-Webhook-Timestamp"
+
+// Header with delivery id. The same for all attempts of delivery
+const HeaderDeliveryId = "X-Webhook-Id"
+
+// Header with event type
+const HeaderEvent = "X-Webhook-Event"
+
+// Calculates signature of webhook request. Signature is HMAC-SHA256 of "{timestamp}.{body}"
+// with subscription secret as a key, in format "sha256={hex}"
+//	secret    - subscription secret
+//	timestamp - unix time when request is sent
+//	body      - request body
+func Sign(secret string, timestamp int64, body []byte) string {
+	var mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks signature of webhook request. Can be used by receivers
+//	secret    - subscription secret
+//	timestamp - value of X-Webhook-Timestamp header
+//	body      - request body
+//	signature - value of X-Webhook-Signature header
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
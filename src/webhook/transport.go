@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	kittransport "github.com/go-kit/kit/transport"
+	kithttp "github.com/go-kit/kit/transport/http"
+	kitlog "github.com/go-kit/log"
+
+	servErr "test/coins/errors"
+)
+
+// Registers http handlers for webhook service
+// mr     - Mux router where handlers should be registered
+// svc    - service to register
+// logger - logger
+func RegisterHandlers(mr *mux.Router, svc WebhookService, logger kitlog.Logger) {
+	var opts = []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(kittransport.NewLogErrorHandler(logger)),
+		kithttp.ServerErrorEncoder(servErr.EncodeError),
+	}
+
+	var subscribeHandler = kithttp.NewServer(
+		makeSubscribeEndpoint(svc),
+		decodeSubscribeRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/accounts/{account}/webhooks", subscribeHandler).Methods("POST")
+
+	var listSubscriptionsHandler = kithttp.NewServer(
+		makeListSubscriptionsEndpoint(svc),
+		decodeListSubscriptionsRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/accounts/{account}/webhooks", listSubscriptionsHandler).Methods("GET")
+
+	var unsubscribeHandler = kithttp.NewServer(
+		makeUnsubscribeEndpoint(svc),
+		decodeSubscriptionIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/webhooks/{id}", unsubscribeHandler).Methods("DELETE")
+
+	var listDeliveriesHandler = kithttp.NewServer(
+		makeListDeliveriesEndpoint(svc),
+		decodeSubscriptionIdRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	mr.Handle("/api/v1/webhooks/{id}/deliveries", listDeliveriesHandler).Methods("GET")
+}
+
+func decodeAccountNumber(r *http.Request) (uint64, error) {
+	var vars = mux.Vars(r)
+	accountNumber, ok := vars["account"]
+	if !ok {
+		return 0, servErr.ErrInvalidRequest("invalid account number", nil)
+	}
+
+	accNum, err := strconv.ParseUint(accountNumber, 10, 64)
+	if err != nil {
+		return 0, servErr.ErrInvalidRequest("invalid account number", err)
+	}
+	return accNum, nil
+}
+
+func decodeSubscribeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	accNum, err := decodeAccountNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body subscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid request body", err)
+	}
+
+	body.AccountNumber = accNum
+	return body, nil
+}
+
+func decodeListSubscriptionsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	accNum, err := decodeAccountNumber(r)
+	if err != nil {
+		return nil, err
+	}
+	return listSubscriptionsRequest{accNum}, nil
+}
+
+func decodeSubscriptionIdRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var vars = mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, servErr.ErrInvalidRequest("invalid subscription id", nil)
+	}
+
+	guid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, servErr.ErrInvalidRequest("invalid subscription id", err)
+	}
+	return subscriptionIdRequest{SubscriptionId(guid)}, nil
+}
+
+type errorer interface {
+	error() error
+}
+
+func encodeResponse(ctx context.Context, wr http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		servErr.EncodeError(ctx, e.error(), wr)
+		return nil
+	}
+	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(wr).Encode(response)
+}